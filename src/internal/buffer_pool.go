@@ -1,8 +1,12 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // BufferPoolManager 缓冲池管理器
@@ -14,11 +18,41 @@ type BufferPoolManager struct {
 	PoolSize    int
 	PageSize    int
 	mu          sync.Mutex
+
+	// SecondaryCache 非空时，被驱逐的页面以压缩形式放入二级缓存，未命中时先查二级缓存再读磁盘
+	SecondaryCache *PageCache
+
+	// WarmupFile 非空时，ShutDown 会把驻留页面写入该文件，供下次启动时 StartWarmup 预热
+	WarmupFile string
+
+	// frames 下标为 frame id，nil 表示空闲
+	frames []*Page
+	// freeList 空闲的 frame id
+	freeList []int
+	// frameFreed 有 frame 变为可用时关闭并替换，用于唤醒等待 frame 的 FetchPageCtx
+	frameFreed chan struct{}
+
+	// preloading 预热正在从磁盘读取的页面，读取期间页面被写回时标记为 true，见 preloadPage
+	preloading map[int]bool
+
+	// pinTracker 调试模式下记录每次 pin 的调用栈，为 nil 时不记录
+	pinTracker *pinTracker
+
+	// 命中、未命中和驱逐次数，见 Stats
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
 }
 
 // NewManager 创建一个新的 Manager 实例
 func NewBufferPoolManager(diskManager *DiskManager, poolSize, DefaultPageSize, k int) *BufferPoolManager {
 	replacer := NewReplacer(poolSize, k)
+
+	freeList := make([]int, poolSize)
+	for i := range freeList {
+		freeList[i] = i
+	}
+
 	return &BufferPoolManager{
 		DiskManager: diskManager,
 		Replacer:    replacer,
@@ -26,61 +60,109 @@ func NewBufferPoolManager(diskManager *DiskManager, poolSize, DefaultPageSize, k
 		PinnedPages: make(map[int]int),
 		PoolSize:    poolSize,
 		PageSize:    DefaultPageSize,
+		frames:      make([]*Page, poolSize),
+		freeList:    freeList,
+		frameFreed:  make(chan struct{}),
+		preloading:  make(map[int]bool),
 	}
 }
 
 // FetchPage 从缓冲池或磁盘中获取指定页面
+// 所有 frame 都被 pin 住时立即返回 ErrNoEvictableFrame
 func (m *BufferPoolManager) FetchPage(pageID int) (*Page, error) {
-	// 尝试从缓冲池获取page
-	if p, ok := m.PageTable[pageID]; ok {
-		return p, nil
-	}
+	return m.FetchPageWithAccess(pageID, AccessUnknown)
+}
 
+// FetchPageWithAccess 与 FetchPage 相同，并告诉替换策略页面的访问方式
+// 顺序扫描使用 AccessScan，扫过的页面会被优先驱逐，不会挤掉热点页面
+func (m *BufferPoolManager) FetchPageWithAccess(pageID int, accessType AccessType) (*Page, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// 如果池子的大小超过的预定大小，则驱逐一个page
-	if len(m.PageTable) >= m.PoolSize {
-		if err := m.evictPage(); err != nil {
+	return m.fetchPage(pageID, accessType)
+}
+
+// FetchPageCtx 与 FetchPage 相同，但所有 frame 都被 pin 住时会等待
+// 直到有 frame 可以被驱逐，或者 ctx 被取消、超时
+func (m *BufferPoolManager) FetchPageCtx(ctx context.Context, pageID int) (*Page, error) {
+	for {
+		m.mu.Lock()
+		p, err := m.fetchPage(pageID, AccessUnknown)
+		if !errors.Is(err, ErrNoEvictableFrame) {
+			m.mu.Unlock()
+			return p, err
+		}
+		frameFreed := m.frameFreed
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrNoEvictableFrame, ctx.Err())
+		case <-frameFreed:
+		}
+	}
+}
+
+// fetchPage 调用方需持有 m.mu
+func (m *BufferPoolManager) fetchPage(pageID int, accessType AccessType) (*Page, error) {
+	// 尝试从缓冲池获取page
+	if p, ok := m.PageTable[pageID]; ok {
+		if err := m.recordAccess(p.frameID, accessType); err != nil {
 			return nil, err
 		}
+		m.pin(p)
+		m.hits.Add(1)
+		return p, nil
 	}
 
+	// 取一个空闲的 frame，没有则驱逐一个page
+	frameID, err := m.allocFrame()
+	if err != nil {
+		return nil, err
+	}
+	m.misses.Add(1)
+
 	// 创建一个新page加入到缓冲池
 	p := &Page{
-		PageID: pageID,
-		Data:   make([]byte, DefaultPageSize),
+		PageID:  pageID,
+		Data:    make([]byte, DefaultPageSize),
+		frameID: frameID,
 	}
 
-	// 从磁盘中读取数据
-	if err := m.DiskManager.ReadPage(int(pageID), p.Data); err != nil {
-		return nil, err
+	// 先查二级缓存，再从磁盘中读取数据
+	if m.SecondaryCache == nil || !m.SecondaryCache.Get(pageID, p.Data) {
+		if err := m.DiskManager.ReadPage(int(pageID), p.Data); err != nil {
+			m.freeList = append(m.freeList, frameID)
+			return nil, err
+		}
 	}
 
-	// 加入到缓冲池
-	m.PageTable[pageID] = p
-	p.PinCount++
-
 	// 在lru replacer中记录这个page
-	if err := m.Replacer.RecordAccess(int(pageID), 0); err != nil {
+	if err := m.recordAccess(frameID, accessType); err != nil {
+		m.freeList = append(m.freeList, frameID)
 		return nil, err
 	}
 
+	// 加入到缓冲池
+	m.PageTable[pageID] = p
+	m.frames[frameID] = p
+	m.pin(p)
+
 	return p, nil
 }
 
 // UnpinPage 解除固定页面并处理相关的脏页面写回
 // 通常在FetchPage，并结束对页的操作之后，需要UnpinPage
 func (m *BufferPoolManager) UnpinPage(pageID int, isDirty bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// 确认该页面是否存在
 	p, ok := m.PageTable[pageID]
 	if !ok {
 		return fmt.Errorf("page %d not found", pageID)
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	// 页面没有被标记
 	if p.PinCount == 0 {
 		return fmt.Errorf("page %d is already unpinned", pageID)
@@ -88,6 +170,17 @@ func (m *BufferPoolManager) UnpinPage(pageID int, isDirty bool) error {
 
 	// 取消标记
 	p.PinCount--
+	if m.pinTracker != nil {
+		m.pinTracker.unpin(pageID)
+	}
+
+	// 没有人使用这个页了，允许驱逐
+	if p.PinCount == 0 {
+		if err := m.Replacer.SetEvictable(p.frameID, true); err != nil {
+			return err
+		}
+		m.notifyFrameFreed()
+	}
 
 	// 页面是脏页，需要写回磁盘
 	if isDirty {
@@ -119,39 +212,60 @@ func (m *BufferPoolManager) FlushPage(pageID int) error {
 		}
 		// 重置为干净
 		p.IsDirty = false
+		if _, ok := m.preloading[pageID]; ok {
+			m.preloading[pageID] = true
+		}
 	}
 
 	return nil
 }
 
-// NewPage 创建一个新页到缓冲池，并放回id
-func (m *BufferPoolManager) NewPage() (int, error) {
+// NewPage 在磁盘上分配一个新页，放入缓冲池并 pin 住，返回全零的页面
+// 与 FetchPage 一样占用一个 frame，缓冲池满时会驱逐一个页，使用完毕后需要 UnpinPage
+func (m *BufferPoolManager) NewPage() (*Page, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	pageID := int(len(m.PageTable) + 1)
+	frameID, err := m.allocFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	pageID, err := m.DiskManager.AllocatePage()
+	if err != nil {
+		m.freeList = append(m.freeList, frameID)
+		return nil, err
+	}
 
 	p := &Page{
-		PageID: pageID,
-		Data:   make([]byte, DefaultPageSize),
+		PageID:  pageID,
+		Data:    make([]byte, DefaultPageSize),
+		frameID: frameID,
+	}
+
+	if err := m.recordAccess(frameID, AccessUnknown); err != nil {
+		m.freeList = append(m.freeList, frameID)
+		return nil, err
 	}
 
 	m.PageTable[pageID] = p
+	m.frames[frameID] = p
+	m.pin(p)
 
-	return pageID, nil
+	return p, nil
 }
 
 // DeletePage 从缓冲池中删除页
 func (m *BufferPoolManager) DeletePage(pageID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// 确认该页是否存在
 	p, ok := m.PageTable[pageID]
 	if !ok {
 		return fmt.Errorf("page %d not found", pageID)
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	// 如果页面被标记，表示有进程正在使用这个页，不予删除
 	if p.PinCount > 0 {
 		return fmt.Errorf("page %d is already pinned", pageID)
@@ -164,7 +278,21 @@ func (m *BufferPoolManager) DeletePage(pageID int) error {
 		}
 	}
 
+	// 归还 frame
+	if err := m.Replacer.Remove(p.frameID); err != nil {
+		return err
+	}
+	m.frames[p.frameID] = nil
+	m.freeList = append(m.freeList, p.frameID)
+	m.notifyFrameFreed()
+
 	delete(m.PageTable, pageID)
+	if m.pinTracker != nil {
+		m.pinTracker.forget(pageID)
+	}
+	if m.SecondaryCache != nil {
+		m.SecondaryCache.Remove(pageID)
+	}
 
 	return nil
 }
@@ -185,28 +313,159 @@ func (m *BufferPoolManager) FlushAllPages() error {
 	return nil
 }
 
-// evictPage 驱逐一个页
-func (m *BufferPoolManager) evictPage() error {
+// Resize 调整缓冲池的 frame 数量
+// 扩容立即生效；缩容时先驱逐未被 pin 的页面，再把剩余页面挪到保留的 frame 中
+// 被 pin 的页面太多导致无法缩容时返回 ErrNoEvictableFrame，已驱逐的页面不会恢复
+func (m *BufferPoolManager) Resize(newSize int) error {
+	if newSize <= 0 {
+		return ErrInvalidPoolSize
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	evictedFrameID, err := m.Replacer.Evict(-1)
+	if newSize >= m.PoolSize {
+		for frameID := m.PoolSize; frameID < newSize; frameID++ {
+			m.frames = append(m.frames, nil)
+			m.freeList = append(m.freeList, frameID)
+		}
+		if err := m.Replacer.Resize(newSize); err != nil {
+			return err
+		}
+		m.PoolSize = newSize
+		m.notifyFrameFreed()
+		return nil
+	}
+
+	// 驱逐页面，直到剩余页面能放进 newSize 个 frame
+	for m.PoolSize-len(m.freeList) > newSize {
+		frameID, err := m.evictPage()
+		if err != nil {
+			return err
+		}
+		m.freeList = append(m.freeList, frameID)
+	}
+
+	// 只保留编号小于 newSize 的空闲 frame
+	freeList := make([]int, 0, len(m.freeList))
+	for _, frameID := range m.freeList {
+		if frameID < newSize {
+			freeList = append(freeList, frameID)
+		}
+	}
+
+	// 把超出范围的页面挪到保留的空闲 frame 中
+	for frameID := newSize; frameID < m.PoolSize; frameID++ {
+		p := m.frames[frameID]
+		if p == nil {
+			continue
+		}
+
+		target := freeList[len(freeList)-1]
+		freeList = freeList[:len(freeList)-1]
+
+		m.Replacer.move(frameID, target)
+		m.frames[target] = p
+		p.frameID = target
+	}
+
+	m.frames = m.frames[:newSize]
+	m.freeList = freeList
+	if err := m.Replacer.Resize(newSize); err != nil {
+		return err
+	}
+	m.PoolSize = newSize
+
+	return nil
+}
+
+// allocFrame 取一个可用的 frame，调用方需持有 m.mu
+func (m *BufferPoolManager) allocFrame() (int, error) {
+	if n := len(m.freeList); n > 0 {
+		frameID := m.freeList[n-1]
+		m.freeList = m.freeList[:n-1]
+		return frameID, nil
+	}
+
+	return m.evictPage()
+}
+
+// evictPage 驱逐一个页并返回它占用的 frame，调用方需持有 m.mu
+func (m *BufferPoolManager) evictPage() (int, error) {
+	// Evict 的参数只做范围校验，实际驱逐的 frame 由 replacer 决定
+	evictedFrameID, err := m.Replacer.Evict(0)
 	if err != nil {
+		return -1, err
+	}
+
+	p := m.frames[evictedFrameID]
+	if p.IsDirty {
+		if err := m.FlushPage(p.PageID); err != nil {
+			// 写回失败，恢复 replacer 中的记录
+			_ = m.recordAccess(evictedFrameID, AccessUnknown)
+			_ = m.Replacer.SetEvictable(evictedFrameID, true)
+			return -1, err
+		}
+	}
+
+	if m.SecondaryCache != nil {
+		m.SecondaryCache.Put(p.PageID, p.Data)
+	}
+
+	delete(m.PageTable, p.PageID)
+	m.frames[evictedFrameID] = nil
+	m.evictions.Add(1)
+
+	return evictedFrameID, nil
+}
+
+// recordAccess 记录一次访问，并在页面被 pin 住期间禁止驱逐
+func (m *BufferPoolManager) recordAccess(frameID int, accessType AccessType) error {
+	if err := m.Replacer.RecordAccess(frameID, accessType); err != nil {
 		return err
 	}
 
-	for pageID, p := range m.PageTable {
-		if int(p.PageID) == evictedFrameID {
-			if p.IsDirty {
-				if err := m.FlushPage(pageID); err != nil {
-					return err
-				}
-			}
+	return m.Replacer.SetEvictable(frameID, false)
+}
+
+// notifyFrameFreed 唤醒所有等待 frame 的 FetchPageCtx，调用方需持有 m.mu
+func (m *BufferPoolManager) notifyFrameFreed() {
+	close(m.frameFreed)
+	m.frameFreed = make(chan struct{})
+}
+
+// ShutDown 刷新所有脏页，保存预热文件，并检查是否还有未释放的 pin
+// 调试模式下返回的错误中包含每个泄漏 pin 的调用栈
+func (m *BufferPoolManager) ShutDown() error {
+	if err := m.FlushAllPages(); err != nil {
+		return err
+	}
+
+	if m.WarmupFile != "" {
+		if err := m.SaveWarmup(m.WarmupFile); err != nil {
+			return err
+		}
+	}
 
-			delete(m.PageTable, pageID)
-			break
+	if records := m.LeakedPins(); len(records) > 0 {
+		var sb strings.Builder
+		for _, r := range records {
+			fmt.Fprintf(&sb, "\npage %d pinned at:\n%s", r.PageID, r.Stack)
 		}
+		return fmt.Errorf("%w: %d pins outstanding%s", ErrPinLeak, len(records), sb.String())
+	}
+
+	if pageIDs := m.pinnedPageIDs(); len(pageIDs) > 0 {
+		return fmt.Errorf("%w: pages %v are still pinned", ErrPinLeak, pageIDs)
 	}
 
 	return nil
 }
+
+// pin 增加页面的 pin 计数，调试模式下同时记录调用栈
+func (m *BufferPoolManager) pin(p *Page) {
+	p.PinCount++
+	if m.pinTracker != nil {
+		m.pinTracker.pin(p.PageID)
+	}
+}
@@ -1,8 +1,11 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
+	"time"
 )
 
 func setupDiskManager(t *testing.T) *DiskManager {
@@ -75,17 +78,301 @@ func TestBufferPool(t *testing.T) {
 	}
 
 	// 测试 NewPage
-	newPageID, err := bm.NewPage()
+	newPage, err := bm.NewPage()
 	if err != nil {
 		t.Fatalf("NewPage 失败: %v", err)
 	}
-	if newPageID <= 0 {
-		t.Fatalf("NewPage 返回的 ID 无效: %d", newPageID)
+	if newPage.PageID <= 0 {
+		t.Fatalf("NewPage 返回的 ID 无效: %d", newPage.PageID)
+	}
+
+	// 被 pin 住的页不能删除
+	err = bm.DeletePage(newPage.PageID)
+	if err == nil {
+		t.Fatalf("DeletePage 应该失败")
+	}
+
+	err = bm.UnpinPage(newPage.PageID, false)
+	if err != nil {
+		t.Fatalf("UnpinPage 失败: %v", err)
 	}
 
 	// 测试 DeletePage
-	err = bm.DeletePage(newPageID)
+	err = bm.DeletePage(newPage.PageID)
 	if err != nil {
 		t.Fatalf("DeletePage 失败: %v", err)
 	}
 }
+
+func TestBufferPool_Evict(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 2, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	for pageID := 0; pageID < 2; pageID++ {
+		if _, err := bm.FetchPage(pageID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 所有 frame 都被 pin 住
+	if _, err := bm.FetchPage(2); !errors.Is(err, ErrNoEvictableFrame) {
+		t.Fatalf("expected ErrNoEvictableFrame, got %v", err)
+	}
+
+	if err := bm.UnpinPage(0, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bm.FetchPage(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := bm.PageTable[0]; ok {
+		t.Error("page 0 should be evicted")
+	}
+
+	for _, pageID := range []int{1, 2} {
+		if err := bm.UnpinPage(pageID, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBufferPool_FetchPageCtx(t *testing.T) {
+	t.Run("wait for frame", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 1, PageSize, 2)
+		if _, err := bm.FetchPage(0); err != nil {
+			t.Fatal(err)
+		}
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = bm.UnpinPage(0, false)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p, err := bm.FetchPageCtx(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.PageID != 1 {
+			t.Errorf("expected page 1, got %d", p.PageID)
+		}
+		if err := bm.UnpinPage(1, false); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 1, PageSize, 2)
+		if _, err := bm.FetchPage(0); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := bm.FetchPageCtx(ctx, 1)
+		if !errors.Is(err, ErrNoEvictableFrame) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected ErrNoEvictableFrame and DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 1, PageSize, 2)
+		if _, err := bm.FetchPage(0); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := bm.FetchPageCtx(ctx, 1); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestBufferPool_Resize(t *testing.T) {
+	t.Run("invalid size", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 2, PageSize, 2)
+		if err := bm.Resize(0); !errors.Is(err, ErrInvalidPoolSize) {
+			t.Error("expected ErrInvalidPoolSize")
+		}
+	})
+
+	t.Run("grow", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 1, PageSize, 2)
+		defer bm.AssertNoPinLeaks(t)
+
+		if _, err := bm.FetchPage(0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bm.FetchPage(1); !errors.Is(err, ErrNoEvictableFrame) {
+			t.Fatal("expected ErrNoEvictableFrame")
+		}
+
+		if err := bm.Resize(3); err != nil {
+			t.Fatal(err)
+		}
+		for pageID := 1; pageID < 3; pageID++ {
+			if _, err := bm.FetchPage(pageID); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for pageID := 0; pageID < 3; pageID++ {
+			if err := bm.UnpinPage(pageID, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("shrink", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 4, PageSize, 2)
+		defer bm.AssertNoPinLeaks(t)
+
+		for pageID := 0; pageID < 4; pageID++ {
+			if _, err := bm.FetchPage(pageID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// 页面 0 和 1 可以被驱逐，页面 2 和 3 仍被 pin 住
+		for pageID := 0; pageID < 2; pageID++ {
+			if err := bm.UnpinPage(pageID, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := bm.Resize(1); !errors.Is(err, ErrNoEvictableFrame) {
+			t.Fatal("expected ErrNoEvictableFrame")
+		}
+
+		if err := bm.Resize(2); err != nil {
+			t.Fatal(err)
+		}
+		if bm.PoolSize != 2 || len(bm.PageTable) != 2 {
+			t.Fatalf("expected 2 frames and 2 pages, got %d and %d", bm.PoolSize, len(bm.PageTable))
+		}
+
+		// 被挪动的页面在 unpin 后可以正常驱逐
+		for pageID := 2; pageID < 4; pageID++ {
+			if err := bm.UnpinPage(pageID, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for pageID := 4; pageID < 6; pageID++ {
+			if _, err := bm.FetchPage(pageID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for pageID := 4; pageID < 6; pageID++ {
+			if err := bm.UnpinPage(pageID, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestBufferPool_NewPage(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 2, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	// 文件中已有 10 个页，新页从 10 开始分配
+	p1, err := bm.NewPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := bm.NewPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p1.PageID != 10 || p2.PageID != 11 {
+		t.Fatalf("expected pages 10 and 11, got %d and %d", p1.PageID, p2.PageID)
+	}
+	if p1.PinCount != 1 {
+		t.Errorf("expected new page to be pinned, got pin count %d", p1.PinCount)
+	}
+
+	// 新页占用缓冲池容量
+	if _, err := bm.NewPage(); !errors.Is(err, ErrNoEvictableFrame) {
+		t.Fatalf("expected ErrNoEvictableFrame, got %v", err)
+	}
+
+	copy(p1.Data, "page 10")
+	if err := bm.UnpinPage(p1.PageID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := bm.UnpinPage(p2.PageID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// 驱逐后重新读取：写过的页读回数据，没写过的页读回全零
+	p3, err := bm.NewPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p4, err := bm.NewPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*Page{p3, p4} {
+		if err := bm.UnpinPage(p.PageID, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := bm.FetchPage(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p.Data[:7]) != "page 10" {
+		t.Error("page 10 data mismatch")
+	}
+	if err := bm.UnpinPage(10, false); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = bm.FetchPage(13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range p.Data {
+		if b != 0 {
+			t.Fatal("expected page 13 to be zero-filled")
+		}
+	}
+	if err := bm.UnpinPage(13, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有分配过的页仍然读取失败
+	if _, err := bm.FetchPage(100); err == nil {
+		t.Error("expected reading an unallocated page to fail")
+	}
+}
@@ -0,0 +1,1175 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HeaderPageID 数据库头部页，系统表从这里找到
+const HeaderPageID = 0
+
+// 头部页格式：
+//
+//	| 魔数 u32 | 版本 u16 | 保留 2 字节 | sys_tables 第一页 i32 | sys_columns 第一页 i32 | sys_indexes 第一页 i32 |
+//	| sys_constraints 第一页 i32 |
+//
+// 全零的头部页表示新的数据库，NewCatalog 会在其中创建系统表
+const (
+	headerMagic   = 0x48424447 // "GDBH"
+	headerVersion = 3
+
+	headerMagicOffset          = 0
+	headerVersionOffset        = 4
+	headerSysTablesOffset      = 8
+	headerSysColumnsOffset     = 12
+	headerSysIndexesOffset     = 16
+	headerSysConstraintsOffset = 20
+
+	// catalogNameLength 表名、列名和索引名的最大长度
+	catalogNameLength = 255
+)
+
+// 系统表，表、列、索引和约束的定义各占一张，与普通表一样存放在 TableHeap 中
+var (
+	// sysTablesSchema 每张表一行
+	sysTablesSchema = &Schema{Columns: []Column{
+		NewColumn("oid", IntegerType()),
+		NewColumn("name", VarcharType(catalogNameLength)),
+		NewColumn("first_page", IntegerType()),
+	}}
+
+	// sysColumnsSchema 表结构的每个版本的每列一行，type 为 Type.String 的结果，
+	// default 为默认值按列类型的二进制编码，没有默认值时为 NULL
+	sysColumnsSchema = &Schema{Columns: []Column{
+		NewColumn("table_oid", IntegerType()),
+		NewColumn("version", IntegerType()),
+		NewColumn("position", IntegerType()),
+		NewColumn("column_id", IntegerType()),
+		NewColumn("name", VarcharType(catalogNameLength)),
+		NewColumn("type", VarcharType(32)),
+		NewColumn("default", BlobType()),
+		NewColumn("not_null", BooleanType()),
+	}}
+
+	// sysIndexesSchema 每个索引一行，columns 为逗号分隔的列 ID
+	sysIndexesSchema = &Schema{Columns: []Column{
+		NewColumn("oid", IntegerType()),
+		NewColumn("name", VarcharType(catalogNameLength)),
+		NewColumn("table_oid", IntegerType()),
+		NewColumn("columns", VarcharType(1024)),
+		NewColumn("is_unique", BooleanType()),
+		NewColumn("meta_page", IntegerType()),
+	}}
+
+	// sysConstraintsSchema 表结构的每个版本的每个约束一行，kind 为 ConstraintKind，
+	// columns 为逗号分隔的列 ID，check 为 CHECK 约束的表达式，
+	// ref_table 和 ref_columns 为外键引用的表的 OID 和列 ID，on_delete 和 on_update 为 ReferentialAction
+	sysConstraintsSchema = &Schema{Columns: []Column{
+		NewColumn("table_oid", IntegerType()),
+		NewColumn("version", IntegerType()),
+		NewColumn("position", IntegerType()),
+		NewColumn("name", VarcharType(catalogNameLength)),
+		NewColumn("kind", IntegerType()),
+		NewColumn("columns", VarcharType(1024)),
+		NewColumn("check", VarcharType(1024)),
+		NewColumn("ref_table", IntegerType()),
+		NewColumn("ref_columns", VarcharType(1024)),
+		NewColumn("on_delete", IntegerType()),
+		NewColumn("on_update", IntegerType()),
+	}}
+)
+
+// TableInfo 目录中的一张表
+// ALTER TABLE 不修改已有的 TableInfo，而是换上一个新的，已取得的 TableInfo 保持查找时的表结构
+type TableInfo struct {
+	OID    int
+	Name   string
+	Schema *Schema
+	Table  *Table
+
+	indexes []*IndexInfo
+
+	// rid、columnRIDs 和 constraintRIDs 表在系统表中的行，包括所有版本的列和约束，用于删除
+	rid            RID
+	columnRIDs     []RID
+	constraintRIDs []RID
+
+	// maxColumnID 所有版本中最大的列 ID，新加的列不复用已删除的列的 ID
+	maxColumnID int
+}
+
+// IndexInfo 目录中的一个索引
+type IndexInfo struct {
+	OID       int
+	Name      string
+	TableName string
+	Columns   []string
+	Index     *Index
+
+	rid RID
+}
+
+// Catalog 系统目录，记录所有表和索引的定义
+// 定义保存在系统表中，从头部页可以找到系统表，因此重启后可以通过 NewCatalog 恢复
+// 内存中用 Trie 按小写名字查找表和索引，名字不区分大小写
+type Catalog struct {
+	bpm *BufferPoolManager
+
+	// mu 保护 tables、indexes 和 nextOID，并串行化 DDL
+	mu      sync.RWMutex
+	tables  *Trie[*TableInfo]
+	indexes *Trie[*IndexInfo]
+	nextOID int
+
+	// fkMu 涉及外键的表的写操作共用的锁，见 writeScope
+	fkMu sync.Mutex
+
+	sysTables      *Table
+	sysColumns     *Table
+	sysIndexes     *Table
+	sysConstraints *Table
+}
+
+// NewCatalog 打开数据库的系统目录，头部页为空时创建系统表
+func NewCatalog(bpm *BufferPoolManager) (*Catalog, error) {
+	p, err := fetchHeaderPage(bpm)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{
+		bpm:     bpm,
+		tables:  NewTrie[*TableInfo](),
+		indexes: NewTrie[*IndexInfo](),
+		nextOID: 1,
+	}
+
+	var dirty bool
+	switch binary.LittleEndian.Uint32(p.Data[headerMagicOffset:]) {
+	case headerMagic:
+		err = c.open(p)
+	case 0:
+		if slices.ContainsFunc(p.Data, func(b byte) bool { return b != 0 }) {
+			err = fmt.Errorf("%w: page %d", ErrInvalidHeaderPage, HeaderPageID)
+			break
+		}
+		err = c.bootstrap(p)
+		dirty = err == nil
+	default:
+		err = fmt.Errorf("%w: page %d", ErrInvalidHeaderPage, HeaderPageID)
+	}
+
+	if unpinErr := bpm.UnpinPage(HeaderPageID, dirty); unpinErr != nil && err == nil {
+		err = unpinErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// fetchHeaderPage pin 住头部页，数据库文件为空时分配它
+func fetchHeaderPage(bpm *BufferPoolManager) (*Page, error) {
+	numPages, err := bpm.DiskManager.NumPages()
+	if err != nil {
+		return nil, err
+	}
+	if numPages > HeaderPageID {
+		return bpm.FetchPage(HeaderPageID)
+	}
+
+	p, err := bpm.NewPage()
+	if err != nil {
+		return nil, err
+	}
+	if p.PageID != HeaderPageID {
+		bpm.UnpinPage(p.PageID, false)
+		return nil, fmt.Errorf("%w: allocated page %d", ErrInvalidHeaderPage, p.PageID)
+	}
+
+	return p, nil
+}
+
+// bootstrap 创建系统表并写入头部页
+func (c *Catalog) bootstrap(header *Page) error {
+	var err error
+	if c.sysTables, err = NewTable("sys_tables", sysTablesSchema, c.bpm); err != nil {
+		return err
+	}
+	if c.sysColumns, err = NewTable("sys_columns", sysColumnsSchema, c.bpm); err != nil {
+		return err
+	}
+	if c.sysIndexes, err = NewTable("sys_indexes", sysIndexesSchema, c.bpm); err != nil {
+		return err
+	}
+	if c.sysConstraints, err = NewTable("sys_constraints", sysConstraintsSchema, c.bpm); err != nil {
+		return err
+	}
+
+	header.mu.Lock()
+	defer header.mu.Unlock()
+
+	binary.LittleEndian.PutUint32(header.Data[headerMagicOffset:], headerMagic)
+	binary.LittleEndian.PutUint16(header.Data[headerVersionOffset:], headerVersion)
+	binary.LittleEndian.PutUint32(header.Data[headerSysTablesOffset:], uint32(c.sysTables.Heap.FirstPageID()))
+	binary.LittleEndian.PutUint32(header.Data[headerSysColumnsOffset:], uint32(c.sysColumns.Heap.FirstPageID()))
+	binary.LittleEndian.PutUint32(header.Data[headerSysIndexesOffset:], uint32(c.sysIndexes.Heap.FirstPageID()))
+	binary.LittleEndian.PutUint32(header.Data[headerSysConstraintsOffset:], uint32(c.sysConstraints.Heap.FirstPageID()))
+
+	return nil
+}
+
+// open 打开头部页记录的系统表，并读入所有表和索引的定义
+func (c *Catalog) open(header *Page) error {
+	header.mu.RLock()
+	version := binary.LittleEndian.Uint16(header.Data[headerVersionOffset:])
+	sysTables := int(int32(binary.LittleEndian.Uint32(header.Data[headerSysTablesOffset:])))
+	sysColumns := int(int32(binary.LittleEndian.Uint32(header.Data[headerSysColumnsOffset:])))
+	sysIndexes := int(int32(binary.LittleEndian.Uint32(header.Data[headerSysIndexesOffset:])))
+	sysConstraints := int(int32(binary.LittleEndian.Uint32(header.Data[headerSysConstraintsOffset:])))
+	header.mu.RUnlock()
+
+	if version != headerVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidHeaderPage, version)
+	}
+
+	var err error
+	if c.sysTables, err = OpenTable("sys_tables", sysTablesSchema, c.bpm, sysTables); err != nil {
+		return err
+	}
+	if c.sysColumns, err = OpenTable("sys_columns", sysColumnsSchema, c.bpm, sysColumns); err != nil {
+		return err
+	}
+	if c.sysIndexes, err = OpenTable("sys_indexes", sysIndexesSchema, c.bpm, sysIndexes); err != nil {
+		return err
+	}
+	if c.sysConstraints, err = OpenTable("sys_constraints", sysConstraintsSchema, c.bpm, sysConstraints); err != nil {
+		return err
+	}
+
+	return c.load()
+}
+
+// load 从系统表重建内存中的目录
+func (c *Catalog) load() error {
+	type columnRow struct {
+		rid      RID
+		position int
+		column   Column
+	}
+	type versionKey struct {
+		oid     int
+		version int
+	}
+	columns := make(map[versionKey][]columnRow)
+	err := scanTable(c.sysColumns, func(rid RID, row []Value) error {
+		col, err := decodeColumn(row)
+		if err != nil {
+			return err
+		}
+
+		key := versionKey{int(row[0].Int()), int(row[1].Int())}
+		columns[key] = append(columns[key], columnRow{
+			rid:      rid,
+			position: int(row[2].Int()),
+			column:   col,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	type constraintRow struct {
+		rid      RID
+		position int
+		row      []Value
+	}
+	constraints := make(map[versionKey][]constraintRow)
+	err = scanTable(c.sysConstraints, func(rid RID, row []Value) error {
+		key := versionKey{int(row[0].Int()), int(row[1].Int())}
+		constraints[key] = append(constraints[key], constraintRow{rid: rid, position: int(row[2].Int()), row: row})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 外键引用的表可能还没有读入，列名在所有表读入后再解析
+	type reference struct {
+		schema     *Schema
+		constraint int
+		refOID     int
+		refColumns string
+	}
+	var references []reference
+
+	versions := make(map[int][]*Schema)
+	infos := make(map[int]*TableInfo)
+	for key, cols := range columns {
+		slices.SortFunc(cols, func(a, b columnRow) int { return a.position - b.position })
+		schemaCols := make([]Column, len(cols))
+		info := infos[key.oid]
+		if info == nil {
+			info = &TableInfo{OID: key.oid}
+			infos[key.oid] = info
+		}
+		for i, col := range cols {
+			if col.position != i {
+				return fmt.Errorf("%w: table %d version %d is missing column %d", ErrInvalidCatalogData, key.oid, key.version, i)
+			}
+			schemaCols[i] = col.column
+			info.columnRIDs = append(info.columnRIDs, col.rid)
+			info.maxColumnID = max(info.maxColumnID, col.column.ID)
+		}
+
+		schema := &Schema{Columns: schemaCols, Version: key.version}
+		rows := constraints[key]
+		slices.SortFunc(rows, func(a, b constraintRow) int { return a.position - b.position })
+		for _, row := range rows {
+			constraint, err := decodeConstraint(schema, row.row)
+			if err != nil {
+				return fmt.Errorf("%w: table %d: %w", ErrInvalidCatalogData, key.oid, err)
+			}
+			if constraint.Kind == ConstraintForeignKey {
+				references = append(references, reference{schema, len(schema.Constraints), int(row.row[7].Int()), row.row[8].Str()})
+			}
+			schema.Constraints = append(schema.Constraints, constraint)
+			info.constraintRIDs = append(info.constraintRIDs, row.rid)
+		}
+		delete(constraints, key)
+		versions[key.oid] = append(versions[key.oid], schema)
+	}
+
+	for key := range constraints {
+		return fmt.Errorf("%w: constraints of table %d version %d have no columns", ErrInvalidCatalogData, key.oid, key.version)
+	}
+
+	err = scanTable(c.sysTables, func(rid RID, row []Value) error {
+		oid := int(row[0].Int())
+		info, ok := infos[oid]
+		if !ok {
+			return fmt.Errorf("%w: table %s has no columns", ErrInvalidCatalogData, row[1].Str())
+		}
+		info.Name = row[1].Str()
+		info.rid = rid
+
+		// 最新的版本是当前的表结构
+		schemas := versions[oid]
+		slices.SortFunc(schemas, func(a, b *Schema) int { return a.Version - b.Version })
+		info.Schema = schemas[len(schemas)-1]
+
+		var err error
+		if info.Table, err = OpenTable(info.Name, info.Schema, c.bpm, int(row[2].Int())); err != nil {
+			return err
+		}
+		for _, schema := range schemas[:len(schemas)-1] {
+			info.Table.AddSchemaVersion(schema)
+		}
+
+		c.nextOID = max(c.nextOID, info.OID+1)
+		return c.tables.Put(strings.ToLower(info.Name), info)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range references {
+		con := &ref.schema.Constraints[ref.constraint]
+		parent, ok := infos[ref.refOID]
+		if !ok || parent.Table == nil {
+			return fmt.Errorf("%w: foreign key %s references missing table %d", ErrInvalidCatalogData, con.Name, ref.refOID)
+		}
+		keyAttrs, err := parseKeyColumns(parent.Schema, ref.refColumns)
+		if err != nil {
+			return fmt.Errorf("%w: foreign key %s: %w", ErrInvalidCatalogData, con.Name, err)
+		}
+		con.RefTable = parent.Name
+		con.RefColumns = columnNames(parent.Schema, keyAttrs)
+	}
+	for oid, schemas := range versions {
+		for _, schema := range schemas {
+			if err := schema.Validate(); err != nil {
+				return fmt.Errorf("%w: table %d: %w", ErrInvalidCatalogData, oid, err)
+			}
+		}
+	}
+
+	err = scanTable(c.sysIndexes, func(rid RID, row []Value) error {
+		table, ok := infos[int(row[2].Int())]
+		if !ok || table.Table == nil {
+			return fmt.Errorf("%w: index %s on missing table %d", ErrInvalidCatalogData, row[1].Str(), row[2].Int())
+		}
+
+		keyAttrs, err := parseKeyColumns(table.Schema, row[3].Str())
+		if err != nil {
+			return fmt.Errorf("%w: index %s: %w", ErrInvalidCatalogData, row[1].Str(), err)
+		}
+		idx, err := OpenIndex(c.bpm, row[1].Str(), table.Schema, keyAttrs, row[4].Bool(), int(row[5].Int()))
+		if err != nil {
+			return err
+		}
+
+		info := &IndexInfo{
+			OID:       int(row[0].Int()),
+			Name:      idx.Name,
+			TableName: table.Name,
+			Columns:   columnNames(table.Schema, keyAttrs),
+			Index:     idx,
+			rid:       rid,
+		}
+		table.Table.attachIndex(idx)
+		table.indexes = append(table.indexes, info)
+		c.nextOID = max(c.nextOID, info.OID+1)
+		return c.indexes.Put(strings.ToLower(info.Name), info)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		for _, con := range info.Schema.Constraints {
+			if con.Kind != ConstraintForeignKey {
+				continue
+			}
+			if err := c.attachForeignKey(info, con); err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidCatalogData, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CreateTable 创建一张表并记录到系统表中
+// 返回的 TableInfo.Schema 是 schema 的副本，其中的列分配了 ID，没有名字的约束按表名取名
+// 每个 PRIMARY KEY 和 UNIQUE 约束创建一个同名的唯一索引，每个 FOREIGN KEY 约束在外键列上创建一个同名的索引，
+// 与已有的索引重名时返回 ErrIndexExists；外键引用的列上必须已有唯一索引，可以引用这张表自己
+func (c *Catalog) CreateTable(name string, schema *Schema) (*TableInfo, error) {
+	if err := validateCatalogName(name); err != nil {
+		return nil, err
+	}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	schema = schema.withColumnIDs()
+	schema.Constraints = nameConstraints(name, schema.Constraints)
+	for _, con := range schema.Constraints {
+		if err := validateCatalogName(con.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.ToLower(name)
+	if _, ok := c.tables.Get(key); ok {
+		return nil, fmt.Errorf("%w: %s", ErrTableExists, name)
+	}
+	for i, con := range schema.Constraints {
+		switch con.Kind {
+		case ConstraintCheck:
+			continue
+		case ConstraintForeignKey:
+			if err := c.resolveForeignKey(name, schema, &schema.Constraints[i]); err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := c.indexes.Get(strings.ToLower(con.Name)); ok {
+			return nil, fmt.Errorf("%w: %s", ErrIndexExists, con.Name)
+		}
+	}
+
+	table, err := NewTable(name, schema, c.bpm)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &TableInfo{
+		OID:    c.nextOID,
+		Name:   name,
+		Schema: schema,
+		Table:  table,
+	}
+	info.rid, err = c.sysTables.InsertTuple([]Value{
+		NewInteger(int32(info.OID)),
+		NewVarchar(name),
+		NewInteger(int32(table.Heap.FirstPageID())),
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.nextOID++
+
+	if err := c.initTable(info); err != nil {
+		// 撤销已经写入的系统表的行、索引和外键，表和索引占用的页面与 DropTable 一样不会被复用
+		_ = c.removeTable(info)
+		return nil, err
+	}
+	if err := c.tables.Put(key, info); err != nil {
+		_ = c.removeTable(info)
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// initTable 写入新表的结构，创建约束的索引并连接外键，调用方持有 c.mu
+func (c *Catalog) initTable(info *TableInfo) error {
+	if err := c.insertSchema(info, info.Schema); err != nil {
+		return err
+	}
+
+	for _, con := range info.Schema.Constraints {
+		if con.Kind == ConstraintCheck {
+			continue
+		}
+		if _, err := c.createIndex(info, con.Name, con.Columns, con.Kind != ConstraintForeignKey); err != nil {
+			return err
+		}
+	}
+	for _, con := range info.Schema.Constraints {
+		if con.Kind != ConstraintForeignKey {
+			continue
+		}
+		if err := c.attachForeignKey(info, con); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nameConstraints 返回 constraints 的副本，没有名字的约束按 PostgreSQL 的习惯取名，
+// 如 orders_pkey、orders_email_key、orders_user_id_fkey 和 orders_price_check，重名时在末尾加上数字
+func nameConstraints(table string, constraints []Constraint) []Constraint {
+	constraints = slices.Clone(constraints)
+
+	taken := make(map[string]struct{}, len(constraints))
+	for _, con := range constraints {
+		taken[strings.ToLower(con.Name)] = struct{}{}
+	}
+
+	for i, con := range constraints {
+		if con.Name != "" {
+			continue
+		}
+
+		var base string
+		switch con.Kind {
+		case ConstraintPrimaryKey:
+			base = table + "_pkey"
+		case ConstraintUnique:
+			base = table + "_" + strings.Join(con.Columns, "_") + "_key"
+		case ConstraintForeignKey:
+			base = table + "_" + strings.Join(con.Columns, "_") + "_fkey"
+		default:
+			parts := []string{table}
+			for _, col := range con.referencedColumns() {
+				if !slices.ContainsFunc(parts[1:], func(p string) bool { return strings.EqualFold(p, col) }) {
+					parts = append(parts, col)
+				}
+			}
+			base = strings.Join(parts, "_") + "_check"
+		}
+
+		name := base
+		for n := 1; ; n++ {
+			if _, ok := taken[strings.ToLower(name)]; !ok {
+				break
+			}
+			name = base + strconv.Itoa(n)
+		}
+		taken[strings.ToLower(name)] = struct{}{}
+		constraints[i].Name = name
+	}
+
+	return constraints
+}
+
+// resolveForeignKey 检查正在创建的表 table 上的外键约束 con 引用的表和列，
+// RefColumns 为空时填入被引用的表的主键，列名改为被引用的表中的写法
+func (c *Catalog) resolveForeignKey(table string, schema *Schema, con *Constraint) error {
+	refSchema := schema
+	var ref *TableInfo
+	if !strings.EqualFold(con.RefTable, table) {
+		var err error
+		if ref, err = c.getTable(con.RefTable); err != nil {
+			return err
+		}
+		refSchema = ref.Schema
+	}
+
+	if len(con.RefColumns) == 0 {
+		pk := refSchema.PrimaryKey()
+		if pk == nil {
+			return fmt.Errorf("%w: %s references a table without a primary key", ErrInvalidSchema, con)
+		}
+		if len(pk.Columns) != len(con.Columns) {
+			return fmt.Errorf("%w: %s has %d columns referencing %d columns", ErrInvalidSchema, con, len(con.Columns), len(pk.Columns))
+		}
+		con.RefColumns = pk.Columns
+	}
+	con.RefColumns = slices.Clone(con.RefColumns)
+
+	for i, name := range con.RefColumns {
+		j := refSchema.ColumnIndex(name)
+		if j < 0 {
+			return fmt.Errorf("%w: %s references missing column %s", ErrInvalidSchema, con, name)
+		}
+		refCol, col := refSchema.Columns[j], schema.Columns[schema.ColumnIndex(con.Columns[i])]
+		if refCol.Type.ID != col.Type.ID {
+			return fmt.Errorf("%w: %s: column %s %s cannot reference %s %s", ErrInvalidSchema, con, col.Name, col.Type, refCol.Name, refCol.Type)
+		}
+		con.RefColumns[i] = refCol.Name
+	}
+
+	// 被引用的列上需要有唯一索引，引用自己时由自己的 PRIMARY KEY 或 UNIQUE 约束创建
+	var unique bool
+	if ref != nil {
+		unique = ref.uniqueIndex(con.RefColumns) != nil
+	} else {
+		unique = slices.ContainsFunc(schema.Constraints, func(other Constraint) bool {
+			return (other.Kind == ConstraintPrimaryKey || other.Kind == ConstraintUnique) && equalColumns(other.Columns, con.RefColumns)
+		})
+	}
+	if !unique {
+		return fmt.Errorf("%w: %s: no unique index on the referenced columns", ErrInvalidSchema, con)
+	}
+
+	return nil
+}
+
+// attachForeignKey 把 table 上的外键约束 con 连接到被引用的表上，两边的索引都需要已经创建
+func (c *Catalog) attachForeignKey(table *TableInfo, con Constraint) error {
+	ref, err := c.referencedTable(table, con.RefTable)
+	if err != nil {
+		return err
+	}
+
+	childIdx := slices.IndexFunc(table.indexes, func(idx *IndexInfo) bool { return strings.EqualFold(idx.Name, con.Name) })
+	if childIdx < 0 {
+		return fmt.Errorf("%w: foreign key %s has no index", ErrIndexNotFound, con.Name)
+	}
+	parentIdx := ref.uniqueIndex(con.RefColumns)
+	if parentIdx == nil {
+		return fmt.Errorf("%w: foreign key %s: no unique index on %s (%s)", ErrIndexNotFound, con.Name, ref.Name, strings.Join(con.RefColumns, ", "))
+	}
+
+	connectForeignKey(&c.fkMu, &foreignKey{
+		name:        con.Name,
+		child:       table.Table,
+		childIndex:  table.indexes[childIdx].Index,
+		parent:      ref.Table,
+		parentIndex: parentIdx.Index,
+		onDelete:    con.OnDelete,
+		onUpdate:    con.OnUpdate,
+	})
+	return nil
+}
+
+// referencedTable 返回外键引用的表，引用 table 自己时返回 table
+func (c *Catalog) referencedTable(table *TableInfo, name string) (*TableInfo, error) {
+	if strings.EqualFold(name, table.Name) {
+		return table, nil
+	}
+
+	return c.getTable(name)
+}
+
+// uniqueIndex 返回列恰好为 columns 的唯一索引，没有时返回 nil
+func (info *TableInfo) uniqueIndex(columns []string) *IndexInfo {
+	for _, idx := range info.indexes {
+		if idx.Index.Unique && equalColumns(idx.Columns, columns) {
+			return idx
+		}
+	}
+
+	return nil
+}
+
+// insertSchema 把表结构的一个版本写入 sys_columns 和 sys_constraints
+func (c *Catalog) insertSchema(info *TableInfo, schema *Schema) error {
+	for i, col := range schema.Columns {
+		def := NewNull(TypeBlob)
+		if col.Default != nil && !col.Default.IsNull() {
+			data, err := col.Default.AppendBinary(nil, col.Type)
+			if err != nil {
+				return fmt.Errorf("default of column %s: %w", col.Name, err)
+			}
+			def = NewBlob(data)
+		}
+
+		rid, err := c.sysColumns.InsertTuple([]Value{
+			NewInteger(int32(info.OID)),
+			NewInteger(int32(schema.Version)),
+			NewInteger(int32(i)),
+			NewInteger(int32(col.ID)),
+			NewVarchar(col.Name),
+			NewVarchar(col.Type.String()),
+			def,
+			NewBoolean(col.NotNull),
+		})
+		if err != nil {
+			return err
+		}
+		info.columnRIDs = append(info.columnRIDs, rid)
+		info.maxColumnID = max(info.maxColumnID, col.ID)
+	}
+
+	for i, con := range schema.Constraints {
+		columns, check := NewNull(TypeVarchar), NewNull(TypeVarchar)
+		refTable, refColumns := NewNull(TypeInteger), NewNull(TypeVarchar)
+		onDelete, onUpdate := NewNull(TypeInteger), NewNull(TypeInteger)
+		if con.Kind == ConstraintCheck {
+			check = NewVarchar(con.Check)
+		} else {
+			columns = NewVarchar(formatKeyColumns(schema, columnAttrs(schema, con.Columns)))
+		}
+		if con.Kind == ConstraintForeignKey {
+			ref, err := c.referencedTable(info, con.RefTable)
+			if err != nil {
+				return err
+			}
+			refSchema := ref.Schema
+			if ref == info {
+				refSchema = schema
+			}
+			refTable = NewInteger(int32(ref.OID))
+			refColumns = NewVarchar(formatKeyColumns(refSchema, columnAttrs(refSchema, con.RefColumns)))
+			onDelete, onUpdate = NewInteger(int32(con.OnDelete)), NewInteger(int32(con.OnUpdate))
+		}
+
+		rid, err := c.sysConstraints.InsertTuple([]Value{
+			NewInteger(int32(info.OID)),
+			NewInteger(int32(schema.Version)),
+			NewInteger(int32(i)),
+			NewVarchar(con.Name),
+			NewInteger(int32(con.Kind)),
+			columns,
+			check,
+			refTable,
+			refColumns,
+			onDelete,
+			onUpdate,
+		})
+		if err != nil {
+			return err
+		}
+		info.constraintRIDs = append(info.constraintRIDs, rid)
+	}
+
+	return nil
+}
+
+// decodeColumn 解码 sys_columns 中的一行
+func decodeColumn(row []Value) (Column, error) {
+	name := row[4].Str()
+	typ, err := ParseType(row[5].Str())
+	if err != nil {
+		return Column{}, fmt.Errorf("%w: column %s: %w", ErrInvalidCatalogData, name, err)
+	}
+
+	col := NewColumn(name, typ)
+	col.ID = int(row[3].Int())
+	col.NotNull = row[7].Bool()
+	if !row[6].IsNull() {
+		def, err := DecodeValue(typ, row[6].Bytes())
+		if err != nil {
+			return Column{}, fmt.Errorf("%w: default of column %s: %w", ErrInvalidCatalogData, name, err)
+		}
+		col = col.WithDefault(def)
+	}
+
+	return col, nil
+}
+
+// decodeConstraint 解码 sys_constraints 中的一行，schema 为约束所属版本的表结构
+// 外键引用的表和列由 load 在所有表读入后设置
+func decodeConstraint(schema *Schema, row []Value) (Constraint, error) {
+	con := Constraint{Name: row[3].Str(), Kind: ConstraintKind(row[4].Int())}
+	if con.Kind == ConstraintCheck {
+		con.Check = row[6].Str()
+		return con, nil
+	}
+
+	keyAttrs, err := parseKeyColumns(schema, row[5].Str())
+	if err != nil {
+		return Constraint{}, fmt.Errorf("constraint %s: %w", con.Name, err)
+	}
+	con.Columns = columnNames(schema, keyAttrs)
+	if con.Kind == ConstraintForeignKey {
+		con.OnDelete = ReferentialAction(row[9].Int())
+		con.OnUpdate = ReferentialAction(row[10].Int())
+	}
+
+	return con, nil
+}
+
+// GetTable 按名字查找表
+func (c *Catalog) GetTable(name string) (*TableInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.getTable(name)
+}
+
+func (c *Catalog) getTable(name string) (*TableInfo, error) {
+	info, ok := c.tables.Get(strings.ToLower(name))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTableNotFound, name)
+	}
+
+	return info, nil
+}
+
+// DropTable 删除表、表上的索引和约束的定义，不能删除被其他表的外键引用的表
+// 磁盘管理器还不能回收页面，表和索引占用的页面不会被复用
+func (c *Catalog) DropTable(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := c.getTable(name)
+	if err != nil {
+		return err
+	}
+	for _, fk := range info.Table.referencedBy {
+		if fk.child != info.Table {
+			return fmt.Errorf("%w: table %s is referenced by foreign key %s of table %s", ErrInvalidSchema, info.Name, fk.name, fk.child.Name)
+		}
+	}
+
+	if err := c.removeTable(info); err != nil {
+		return err
+	}
+
+	return c.tables.Delete(strings.ToLower(info.Name))
+}
+
+// removeTable 删除表在系统表中的行和表上的索引，并摘下表的外键，不修改 c.tables
+// 用于删除表和撤销创建了一半的表，后者的系统表的行可能只写入了一部分
+func (c *Catalog) removeTable(info *TableInfo) error {
+	for _, idx := range info.indexes {
+		if err := c.sysIndexes.DeleteTuple(idx.rid); err != nil {
+			return err
+		}
+		if err := c.indexes.Delete(strings.ToLower(idx.Name)); err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return err
+		}
+	}
+	info.indexes = nil
+
+	if err := c.deleteSchemaRows(info.columnRIDs, info.constraintRIDs); err != nil {
+		return err
+	}
+	if err := c.sysTables.DeleteTuple(info.rid); err != nil {
+		return err
+	}
+	disconnectForeignKeys(&c.fkMu, info.Table)
+
+	return nil
+}
+
+// deleteSchemaRows 删除 insertSchema 写入 sys_columns 和 sys_constraints 的行
+func (c *Catalog) deleteSchemaRows(columnRIDs, constraintRIDs []RID) error {
+	for _, rid := range columnRIDs {
+		if err := c.sysColumns.DeleteTuple(rid); err != nil {
+			return err
+		}
+	}
+	for _, rid := range constraintRIDs {
+		if err := c.sysConstraints.DeleteTuple(rid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddColumn 在表的末尾加上一列，生成表结构的新版本
+// 已有的行不会被改写，读取时该列取 col 的默认值，没有默认值时为 NULL
+func (c *Catalog) AddColumn(tableName string, col Column) (*TableInfo, error) {
+	if err := validateCatalogName(col.Name); err != nil {
+		return nil, err
+	}
+
+	return c.alterTable(tableName, func(info *TableInfo) (*Schema, error) {
+		col.ID = info.maxColumnID + 1
+		return info.Schema.AddColumn(col)
+	})
+}
+
+// DropColumn 删除表的一列，生成表结构的新版本，不能删除索引使用的列
+func (c *Catalog) DropColumn(tableName, column string) (*TableInfo, error) {
+	return c.alterTable(tableName, func(info *TableInfo) (*Schema, error) {
+		return info.Schema.DropColumn(column)
+	})
+}
+
+// RenameColumn 修改列名，生成表结构的新版本，不能修改被外键引用的列的名字
+func (c *Catalog) RenameColumn(tableName, oldName, newName string) (*TableInfo, error) {
+	if err := validateCatalogName(newName); err != nil {
+		return nil, err
+	}
+
+	return c.alterTable(tableName, func(info *TableInfo) (*Schema, error) {
+		for _, fk := range info.Table.referencedBy {
+			if slices.ContainsFunc(columnNames(info.Schema, fk.parentIndex.KeyAttrs()), func(col string) bool { return strings.EqualFold(col, oldName) }) {
+				return nil, fmt.Errorf("%w: column %s is referenced by foreign key %s", ErrInvalidSchema, oldName, fk.name)
+			}
+		}
+
+		return info.Schema.RenameColumn(oldName, newName)
+	})
+}
+
+// alterTable 用 fn 生成的新版本替换表结构，并把新版本记录到 sys_columns 和 sys_constraints 中
+func (c *Catalog) alterTable(tableName string, fn func(info *TableInfo) (*Schema, error)) (*TableInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := c.getTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := fn(info)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先写入新版本，写入失败或者表拒绝新版本时删除已经写入的行，表和系统表都保持原来的版本
+	next := *info
+	next.Schema = schema
+	next.columnRIDs = slices.Clone(info.columnRIDs)
+	next.constraintRIDs = slices.Clone(info.constraintRIDs)
+	err = c.insertSchema(&next, schema)
+	if err == nil {
+		err = info.Table.Alter(schema)
+	}
+	if err != nil {
+		_ = c.deleteSchemaRows(next.columnRIDs[len(info.columnRIDs):], next.constraintRIDs[len(info.constraintRIDs):])
+		return nil, err
+	}
+
+	// 索引列可能被改名，IndexInfo 同样换成新的；替换已有的键不会失败
+	next.indexes = make([]*IndexInfo, len(info.indexes))
+	for i, idx := range info.indexes {
+		nextIdx := *idx
+		nextIdx.Columns = columnNames(schema, idx.Index.KeyAttrs())
+		next.indexes[i] = &nextIdx
+		if err := replaceTrieValue(c.indexes, strings.ToLower(idx.Name), &nextIdx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := replaceTrieValue(c.tables, strings.ToLower(info.Name), &next); err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+func replaceTrieValue[V any](t *Trie[V], key string, val V) error {
+	if err := t.Delete(key); err != nil {
+		return err
+	}
+
+	return t.Put(key, val)
+}
+
+// ListTables 按名字的字典序返回所有表名
+func (c *Catalog) ListTables() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, c.tables.Len())
+	c.tables.WalkPrefix("", func(_ string, info *TableInfo) bool {
+		names = append(names, info.Name)
+		return true
+	})
+
+	return names
+}
+
+// CreateIndex 在表的 columns 列上创建索引，并用表中已有的行填充
+// unique 为 true 时已有的行违反唯一性则返回 ErrDuplicateKey
+func (c *Catalog) CreateIndex(indexName, tableName string, columns []string, unique bool) (*IndexInfo, error) {
+	if err := validateCatalogName(indexName); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.ToLower(indexName)
+	if _, ok := c.indexes.Get(key); ok {
+		return nil, fmt.Errorf("%w: %s", ErrIndexExists, indexName)
+	}
+	table, err := c.getTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.createIndex(table, indexName, columns, unique)
+}
+
+// createIndex 创建索引并记录到 sys_indexes 中，调用方持有 c.mu
+func (c *Catalog) createIndex(table *TableInfo, indexName string, columns []string, unique bool) (*IndexInfo, error) {
+	keyAttrs := make([]int, len(columns))
+	for i, col := range columns {
+		if keyAttrs[i] = table.Schema.ColumnIndex(col); keyAttrs[i] < 0 {
+			return nil, fmt.Errorf("%w: index %s: no column %s in table %s", ErrInvalidSchema, indexName, col, table.Name)
+		}
+	}
+
+	idx, err := NewIndex(c.bpm, indexName, table.Schema, keyAttrs, unique)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &IndexInfo{
+		OID:       c.nextOID,
+		Name:      indexName,
+		TableName: table.Name,
+		Columns:   columnNames(table.Schema, keyAttrs),
+		Index:     idx,
+	}
+	info.rid, err = c.sysIndexes.InsertTuple([]Value{
+		NewInteger(int32(info.OID)),
+		NewVarchar(indexName),
+		NewInteger(int32(table.OID)),
+		NewVarchar(formatKeyColumns(table.Schema, keyAttrs)),
+		NewBoolean(unique),
+		NewInteger(int32(idx.MetaPageID())),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.indexes.Put(strings.ToLower(indexName), info); err != nil {
+		_ = c.sysIndexes.DeleteTuple(info.rid)
+		return nil, err
+	}
+
+	// 填充失败时删除已经写入的记录，索引的页面与 DropTable 一样不会被复用
+	if err := table.Table.AddIndex(idx); err != nil {
+		_ = c.indexes.Delete(strings.ToLower(indexName))
+		_ = c.sysIndexes.DeleteTuple(info.rid)
+		return nil, err
+	}
+
+	c.nextOID++
+	table.indexes = append(table.indexes, info)
+	return info, nil
+}
+
+// GetIndex 按名字查找索引
+func (c *Catalog) GetIndex(name string) (*IndexInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	info, ok := c.indexes.Get(strings.ToLower(name))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrIndexNotFound, name)
+	}
+
+	return info, nil
+}
+
+// GetIndexes 按创建顺序返回表上的所有索引
+func (c *Catalog) GetIndexes(tableName string) ([]*IndexInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	table, err := c.getTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Clone(table.indexes), nil
+}
+
+// scanTable 对表中的每一行调用 fn
+func scanTable(t *Table, fn func(rid RID, row []Value) error) error {
+	it := t.Iterator()
+	defer it.Close()
+
+	for it.Next() {
+		row, err := t.DecodeTuple(it.Tuple())
+		if err != nil {
+			return err
+		}
+		if err := fn(it.RID(), row); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}
+
+func validateCatalogName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidSchema)
+	}
+	if len(name) > catalogNameLength {
+		return fmt.Errorf("%w: name longer than %d bytes", ErrInvalidSchema, catalogNameLength)
+	}
+
+	return nil
+}
+
+// formatKeyColumns 返回索引列的 ID，用逗号分隔
+func formatKeyColumns(schema *Schema, keyAttrs []int) string {
+	parts := make([]string, len(keyAttrs))
+	for i, attr := range keyAttrs {
+		parts[i] = strconv.Itoa(schema.Columns[attr].ID)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// parseKeyColumns 把 formatKeyColumns 记录的列 ID 转换为列在 schema 中的下标
+func parseKeyColumns(schema *Schema, s string) ([]int, error) {
+	parts := strings.Split(s, ",")
+	keyAttrs := make([]int, len(parts))
+	for i, part := range parts {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		if keyAttrs[i] = schema.ColumnByID(id); keyAttrs[i] < 0 {
+			return nil, fmt.Errorf("no column with id %d", id)
+		}
+	}
+
+	return keyAttrs, nil
+}
+
+// columnAttrs 返回列名在 schema 中的下标，列名需要已经检查过
+func columnAttrs(schema *Schema, names []string) []int {
+	attrs := make([]int, len(names))
+	for i, name := range names {
+		attrs[i] = schema.ColumnIndex(name)
+	}
+
+	return attrs
+}
+
+// equalColumns 两组列名是否按顺序相同，不区分大小写
+func equalColumns(a, b []string) bool {
+	return slices.EqualFunc(a, b, strings.EqualFold)
+}
+
+func columnNames(schema *Schema, keyAttrs []int) []string {
+	names := make([]string, len(keyAttrs))
+	for i, attr := range keyAttrs {
+		names[i] = schema.Columns[attr].Name
+	}
+
+	return names
+}
@@ -0,0 +1,548 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newCatalogSchema(t *testing.T) *Schema {
+	t.Helper()
+
+	schema, err := NewSchema(
+		NewColumn("id", IntegerType()),
+		NewColumn("email", VarcharType(64)),
+		NewColumn("balance", DecimalType(10, 2)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return schema
+}
+
+func TestCatalog(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	c, err := NewCatalog(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := c.CreateTable("users", newCatalogSchema(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateTable("Users", newCatalogSchema(t)); !errors.Is(err, ErrTableExists) {
+		t.Errorf("expected ErrTableExists, got %v", err)
+	}
+	if _, err := c.CreateTable("orders", newCatalogSchema(t)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateTable("", newCatalogSchema(t)); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema, got %v", err)
+	}
+
+	if got := c.ListTables(); !slices.Equal(got, []string{"orders", "users"}) {
+		t.Errorf("unexpected tables %v", got)
+	}
+	if info, err := c.GetTable("USERS"); err != nil || info != users {
+		t.Errorf("expected to find users, got %v, %v", info, err)
+	}
+	if _, err := c.GetTable("missing"); !errors.Is(err, ErrTableNotFound) {
+		t.Errorf("expected ErrTableNotFound, got %v", err)
+	}
+
+	t.Run("index", func(t *testing.T) {
+		rid, err := users.Table.InsertTuple([]Value{NewInteger(1), NewVarchar("a@x.org"), mustDecimal(100, 2)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 创建索引时填充已有的行
+		byEmail, err := c.CreateIndex("users_email", "users", []string{"email"}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := byEmail.Index.ScanKey([]Value{NewVarchar("a@x.org")}); !slices.Equal(got, []RID{rid}) {
+			t.Errorf("expected %v, got %v", []RID{rid}, got)
+		}
+
+		if _, err := users.Table.InsertTuple([]Value{NewInteger(2), NewVarchar("a@x.org"), mustDecimal(0, 2)}); !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("expected ErrDuplicateKey, got %v", err)
+		}
+		rid2, err := users.Table.InsertTuple([]Value{NewInteger(2), NewVarchar("b@x.org"), mustDecimal(0, 2)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := users.Table.UpdateTuple(rid2, []Value{NewInteger(2), NewVarchar("a@x.org"), mustDecimal(0, 2)}); !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("expected ErrDuplicateKey, got %v", err)
+		}
+		if values, _ := users.Table.GetTuple(rid2); values[1].Str() != "b@x.org" {
+			t.Errorf("a failed update should not change the row, got %v", values)
+		}
+
+		// 更新后索引指向新的键
+		if _, err := users.Table.UpdateTuple(rid2, []Value{NewInteger(2), NewVarchar("c@x.org"), mustDecimal(0, 2)}); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := byEmail.Index.ScanKey([]Value{NewVarchar("b@x.org")}); len(got) != 0 {
+			t.Errorf("expected the old key to be gone, got %v", got)
+		}
+		if got, _ := byEmail.Index.ScanKey([]Value{NewVarchar("c@x.org")}); !slices.Equal(got, []RID{rid2}) {
+			t.Errorf("expected %v, got %v", []RID{rid2}, got)
+		}
+
+		if _, err := c.CreateIndex("users_id", "users", []string{"id", "balance"}, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.CreateIndex("USERS_EMAIL", "users", []string{"id"}, false); !errors.Is(err, ErrIndexExists) {
+			t.Errorf("expected ErrIndexExists, got %v", err)
+		}
+		if _, err := c.CreateIndex("users_bad", "users", []string{"nope"}, false); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("expected ErrInvalidSchema, got %v", err)
+		}
+		if _, err := c.CreateIndex("orders_dup", "orders", []string{"id"}, false); err != nil {
+			t.Fatal(err)
+		}
+
+		indexes, err := c.GetIndexes("users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(indexes) != 2 || indexes[0].Name != "users_email" || !slices.Equal(indexes[1].Columns, []string{"id", "balance"}) {
+			t.Errorf("unexpected indexes %v", indexes)
+		}
+
+		if err := users.Table.DeleteTuple(rid); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := byEmail.Index.ScanKey([]Value{NewVarchar("a@x.org")}); len(got) != 0 {
+			t.Errorf("expected the deleted row to leave the index, got %v", got)
+		}
+	})
+
+	t.Run("unique violation on existing rows", func(t *testing.T) {
+		info, err := c.CreateTable("dups", newCatalogSchema(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := info.Table.InsertTuple([]Value{NewInteger(7), NewVarchar("x"), mustDecimal(0, 2)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := c.CreateIndex("dups_id", "dups", []string{"id"}, true); !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("expected ErrDuplicateKey, got %v", err)
+		}
+		if indexes, _ := c.GetIndexes("dups"); len(indexes) != 0 {
+			t.Errorf("expected no indexes, got %v", indexes)
+		}
+		err = scanTable(c.sysIndexes, func(_ RID, row []Value) error {
+			if row[1].Str() == "dups_id" {
+				t.Errorf("unexpected sys_indexes row %v", row)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.CreateIndex("dups_id", "dups", []string{"id"}, false); err != nil {
+			t.Errorf("expected the name to be free again, got %v", err)
+		}
+		if _, err := info.Table.InsertTuple([]Value{NewInteger(7), NewVarchar("x"), mustDecimal(0, 2)}); err != nil {
+			t.Errorf("the failed index should not be maintained: %v", err)
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		if err := c.DropTable("orders"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.GetTable("orders"); !errors.Is(err, ErrTableNotFound) {
+			t.Errorf("expected ErrTableNotFound, got %v", err)
+		}
+		if _, err := c.GetIndex("orders_dup"); !errors.Is(err, ErrIndexNotFound) {
+			t.Errorf("expected ErrIndexNotFound, got %v", err)
+		}
+		if err := c.DropTable("orders"); !errors.Is(err, ErrTableNotFound) {
+			t.Errorf("expected ErrTableNotFound, got %v", err)
+		}
+
+		// 名字可以重新使用
+		if _, err := c.CreateTable("orders", newCatalogSchema(t)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestCatalog_Restart(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	c, err := NewCatalog(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := c.CreateTable("users", newCatalogSchema(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateIndex("users_email", "users", []string{"email"}, true); err != nil {
+		t.Fatal(err)
+	}
+	rid, err := users.Table.InsertTuple([]Value{NewInteger(1), NewVarchar("a@x.org"), mustDecimal(150, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateTable("dropped", newCatalogSchema(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DropTable("dropped"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bm.ShutDown(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer restarted.AssertNoPinLeaks(t)
+
+	c, err = NewCatalog(restarted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ListTables(); !slices.Equal(got, []string{"users"}) {
+		t.Fatalf("unexpected tables %v", got)
+	}
+
+	info, err := c.GetTable("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Schema.String() != newCatalogSchema(t).String() {
+		t.Errorf("expected schema %s, got %s", newCatalogSchema(t), info.Schema)
+	}
+	values, err := info.Table.GetTuple(rid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[1].Str() != "a@x.org" || values[2].String() != "1.50" {
+		t.Errorf("unexpected row %v", values)
+	}
+
+	indexes, err := c.GetIndexes("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 1 || !indexes[0].Index.Unique || !slices.Equal(indexes[0].Columns, []string{"email"}) {
+		t.Fatalf("unexpected indexes %v", indexes)
+	}
+	if got, _ := indexes[0].Index.ScanKey([]Value{NewVarchar("a@x.org")}); !slices.Equal(got, []RID{rid}) {
+		t.Errorf("expected %v, got %v", []RID{rid}, got)
+	}
+
+	// 重新打开后索引仍然被维护，新对象的 OID 不与已有的重复
+	if _, err := info.Table.InsertTuple([]Value{NewInteger(2), NewVarchar("a@x.org"), mustDecimal(0, 2)}); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, got %v", err)
+	}
+	orders, err := c.CreateTable("orders", newCatalogSchema(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orders.OID <= indexes[0].OID {
+		t.Errorf("expected a fresh OID, got %d", orders.OID)
+	}
+}
+
+func TestCatalog_CreateTableRollback(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	c, err := NewCatalog(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第二列的默认值放不进一页，写入 sys_columns 时失败，此时 sys_tables 和第一列已经写入
+	schema, err := NewSchema(
+		NewColumn("id", IntegerType()),
+		NewColumn("note", VarcharType(MaxVarcharLength)).WithDefault(NewVarchar(strings.Repeat("x", PageSize))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema.Constraints = []Constraint{PrimaryKey("", "id")}
+	if _, err := c.CreateTable("notes", schema); err == nil {
+		t.Fatal("expected an error for an oversized default")
+	}
+	if got := c.ListTables(); len(got) != 0 {
+		t.Errorf("unexpected tables %v", got)
+	}
+
+	schema.Columns[1] = NewColumn("note", VarcharType(MaxVarcharLength))
+	if _, err := c.CreateTable("notes", schema); err != nil {
+		t.Fatal(err)
+	}
+	if err := bm.ShutDown(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开时只有第二次创建的表
+	restarted := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer restarted.AssertNoPinLeaks(t)
+
+	c, err = NewCatalog(restarted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ListTables(); !slices.Equal(got, []string{"notes"}) {
+		t.Fatalf("unexpected tables %v", got)
+	}
+	if indexes, err := c.GetIndexes("notes"); err != nil || len(indexes) != 1 {
+		t.Errorf("unexpected indexes %v, %v", indexes, err)
+	}
+}
+
+func TestCatalog_InvalidHeader(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	data := make([]byte, PageSize)
+	data[100] = 1
+	if err := dm.WritePage(HeaderPageID, data); err != nil {
+		t.Fatal(err)
+	}
+
+	bm := NewBufferPoolManager(dm, 4, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	if _, err := NewCatalog(bm); !errors.Is(err, ErrInvalidHeaderPage) {
+		t.Errorf("expected ErrInvalidHeaderPage, got %v", err)
+	}
+}
+
+func TestCatalog_Alter(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	c, err := NewCatalog(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := c.CreateTable("users", newCatalogSchema(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateIndex("users_email", "users", []string{"email"}, true); err != nil {
+		t.Fatal(err)
+	}
+	var rids []RID
+	for i := 0; i < 40; i++ {
+		rid, err := users.Table.InsertTuple([]Value{NewInteger(int32(i)), NewVarchar(fmt.Sprintf("u%d@x.org", i)), mustDecimal(int64(i), 2)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rids = append(rids, rid)
+	}
+
+	if _, err := c.DropColumn("users", "balance"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RenameColumn("users", "email", "mail"); err != nil {
+		t.Fatal(err)
+	}
+	// 新加的列不复用被删除的 balance 的 ID
+	info, err := c.AddColumn("users", NewColumn("bio", VarcharType(200)).WithDefault(NewVarchar(strings.Repeat("b", 100))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Schema.Version != 3 || info.Schema.String() != "(id INTEGER, mail VARCHAR(64), bio VARCHAR(200) DEFAULT "+strings.Repeat("b", 100)+")" {
+		t.Errorf("unexpected schema %s version %d", info.Schema, info.Schema.Version)
+	}
+	if users.Schema.Version != 0 {
+		t.Error("an existing TableInfo should keep its schema")
+	}
+	if indexes, _ := c.GetIndexes("users"); !slices.Equal(indexes[0].Columns, []string{"mail"}) {
+		t.Errorf("expected the index column to be renamed, got %v", indexes[0].Columns)
+	}
+	if _, err := c.DropColumn("users", "mail"); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema when dropping an indexed column, got %v", err)
+	}
+	// 默认值放不进 sys_columns 的一页时，表和系统表都保持原来的版本
+	huge := NewColumn("notes", VarcharType(MaxVarcharLength)).WithDefault(NewVarchar(strings.Repeat("n", PageSize)))
+	if _, err := c.AddColumn("users", huge); err == nil {
+		t.Error("expected an error for an oversized default")
+	}
+	if info.Table.Schema.Version != 3 {
+		t.Errorf("expected the table to stay at version 3, got %d", info.Table.Schema.Version)
+	}
+	if _, err := c.AddColumn("missing", NewColumn("x", IntegerType())); !errors.Is(err, ErrTableNotFound) {
+		t.Errorf("expected ErrTableNotFound, got %v", err)
+	}
+	if err := bm.ShutDown(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer restarted.AssertNoPinLeaks(t)
+	if c, err = NewCatalog(restarted); err != nil {
+		t.Fatal(err)
+	}
+	info, err = c.GetTable("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Schema.Version != 3 || info.Schema.ColumnByID(3) != -1 {
+		t.Fatalf("unexpected schema %s version %d", info.Schema, info.Schema.Version)
+	}
+
+	// 重启后旧元组仍然按写入时的版本解码
+	values, err := info.Table.GetTuple(rids[5])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[1].Str() != "u5@x.org" || values[2].Str() != strings.Repeat("b", 100) {
+		t.Errorf("unexpected row %v", values)
+	}
+	if _, err := info.Table.InsertTuple([]Value{NewInteger(99), NewVarchar("u5@x.org"), NewNull(TypeVarchar)}); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, got %v", err)
+	}
+
+	// 改写后的元组变长，部分会被移到新的页
+	if err := <-info.Table.StartRewrite(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	idx := info.Table.Indexes()[0]
+	seen := 0
+	err = scanTable(info.Table, func(rid RID, row []Value) error {
+		seen++
+		if got, err := idx.ScanKey(row[1:2]); err != nil || !slices.Equal(got, []RID{rid}) {
+			t.Errorf("index points to %v for %v at %v", got, row[1], rid)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != 40 {
+		t.Errorf("expected 40 rows, got %d", seen)
+	}
+}
+
+func TestCatalog_Constraints(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	c, err := NewCatalog(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := newCatalogSchema(t)
+	schema.Columns[1] = schema.Columns[1].WithNotNull()
+	schema.Constraints = []Constraint{
+		PrimaryKey("", "id"),
+		Unique("", "email"),
+		Check("", "balance >= 0"),
+		Check("", "id > 0 AND id < 1000000"),
+	}
+	users, err := c.CreateTable("users", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, con := range users.Schema.Constraints {
+		names = append(names, con.Name)
+	}
+	if want := []string{"users_pkey", "users_email_key", "users_balance_check", "users_id_check"}; !slices.Equal(names, want) {
+		t.Errorf("expected constraint names %v, got %v", want, names)
+	}
+	if schema.Constraints[0].Name != "" {
+		t.Error("CreateTable should not modify the given schema")
+	}
+
+	indexes, err := c.GetIndexes("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 2 || indexes[0].Name != "users_pkey" || !indexes[0].Index.Unique || indexes[1].Name != "users_email_key" {
+		t.Fatalf("unexpected indexes %v", indexes)
+	}
+
+	// 约束名与已有的索引重名
+	clash := newCatalogSchema(t)
+	clash.Constraints = []Constraint{Unique("users_pkey", "email")}
+	if _, err := c.CreateTable("orders", clash); !errors.Is(err, ErrIndexExists) {
+		t.Errorf("expected ErrIndexExists, got %v", err)
+	}
+	if _, err := c.GetTable("orders"); !errors.Is(err, ErrTableNotFound) {
+		t.Errorf("expected the table not to be created, got %v", err)
+	}
+
+	if _, err := users.Table.InsertTuple([]Value{NewInteger(1), NewVarchar("a@x.org"), mustDecimal(100, 2)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RenameColumn("users", "balance", "credit"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.DropColumn("users", "credit"); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema when dropping a checked column, got %v", err)
+	}
+	if err := bm.ShutDown(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer restarted.AssertNoPinLeaks(t)
+	if c, err = NewCatalog(restarted); err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.GetTable("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "(id INTEGER, email VARCHAR(64) NOT NULL, credit DECIMAL(10, 2), " +
+		"CONSTRAINT users_pkey PRIMARY KEY (id), CONSTRAINT users_email_key UNIQUE (email), " +
+		"CONSTRAINT users_balance_check CHECK (credit >= 0), CONSTRAINT users_id_check CHECK ((id > 0) AND (id < 1000000)))"
+	if got := info.Schema.String(); got != want {
+		t.Errorf("expected schema %s, got %s", want, got)
+	}
+
+	cases := []struct {
+		values []Value
+		target error
+	}{
+		{[]Value{NewInteger(1), NewVarchar("b@x.org"), mustDecimal(0, 2)}, ErrPrimaryKeyViolation},
+		{[]Value{NewInteger(2), NewVarchar("a@x.org"), mustDecimal(0, 2)}, ErrUniqueViolation},
+		{[]Value{NewInteger(2), NewNull(TypeVarchar), mustDecimal(0, 2)}, ErrNotNullViolation},
+		{[]Value{NewInteger(2), NewVarchar("b@x.org"), mustDecimal(-1, 2)}, ErrCheckViolation},
+		{[]Value{NewInteger(0), NewVarchar("b@x.org"), mustDecimal(0, 2)}, ErrCheckViolation},
+	}
+	for _, tc := range cases {
+		if _, err := info.Table.InsertTuple(tc.values); !errors.Is(err, tc.target) {
+			t.Errorf("%v: expected %v, got %v", tc.values, tc.target, err)
+		}
+	}
+
+	if err := c.DropTable("users"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateTable("users", schema); err != nil {
+		t.Errorf("expected the constraint names to be free again, got %v", err)
+	}
+}
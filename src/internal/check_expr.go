@@ -0,0 +1,441 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CHECK 约束的表达式：
+//
+//	expr    = and { OR and }
+//	and     = not { AND not }
+//	not     = NOT not | primary
+//	primary = ( expr ) | operand op operand | operand IS [NOT] NULL
+//	op      = "=" | "<>" | "!=" | "<" | "<=" | ">" | ">="
+//	operand = 列名 | 数字 | '字符串' | TRUE | FALSE | NULL
+//
+// 求值使用 SQL 的三值逻辑，与 NULL 比较的结果未知，CHECK 只在结果为 FALSE 时失败
+type checkExpr struct {
+	// op 为 AND、OR、NOT、IS NULL、IS NOT NULL 或比较运算符
+	op          string
+	left, right *checkExpr
+
+	// 叶子节点为列或字面量
+	column  string
+	literal *checkLiteral
+
+	// colIdx 绑定到 Schema 后列的下标
+	colIdx int
+}
+
+type checkLiteral struct {
+	// text 字面量在表达式中的写法，用于还原表达式
+	text  string
+	value Value
+}
+
+// parseCheckExpr 解析 CHECK 表达式，列名在 bind 时才检查
+func parseCheckExpr(s string) (*checkExpr, error) {
+	tokens, err := tokenizeCheck(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &checkParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q in check %q", ErrInvalidSchema, p.tokens[p.pos], s)
+	}
+
+	return expr, nil
+}
+
+// tokenizeCheck 切分出标识符、数字、带引号的字符串、运算符和括号
+func tokenizeCheck(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')' || c == '=':
+			tokens = append(tokens, s[i:i+1])
+			i++
+		case c == '<' || c == '>' || c == '!':
+			j := i + 1
+			if j < len(s) && (s[j] == '=' || (c == '<' && s[j] == '>')) {
+				j++
+			}
+			if s[i:j] == "!" {
+				return nil, fmt.Errorf("%w: unexpected ! in check %q", ErrInvalidSchema, s)
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		case c == '\'':
+			j := i + 1
+			for {
+				if j >= len(s) {
+					return nil, fmt.Errorf("%w: unterminated string in check %q", ErrInvalidSchema, s)
+				}
+				if s[j] == '\'' {
+					if j+1 < len(s) && s[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && (s[j] == '.' || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		case isCheckIdentByte(c):
+			j := i + 1
+			for j < len(s) && (isCheckIdentByte(s[j]) || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q in check %q", ErrInvalidSchema, c, s)
+		}
+	}
+
+	return tokens, nil
+}
+
+// isCheckIdentByte 标识符中除数字以外的字节，非 ASCII 的字节都算作标识符的一部分
+func isCheckIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+type checkParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *checkParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// accept 下一个 token 不区分大小写地等于 tok 时消费它
+func (p *checkParser) accept(tok string) bool {
+	if strings.EqualFold(p.peek(), tok) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *checkParser) parseOr() (*checkExpr, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.accept("OR") {
+		var right *checkExpr
+		if right, err = p.parseAnd(); err == nil {
+			left = &checkExpr{op: "OR", left: left, right: right}
+		}
+	}
+
+	return left, err
+}
+
+func (p *checkParser) parseAnd() (*checkExpr, error) {
+	left, err := p.parseNot()
+	for err == nil && p.accept("AND") {
+		var right *checkExpr
+		if right, err = p.parseNot(); err == nil {
+			left = &checkExpr{op: "AND", left: left, right: right}
+		}
+	}
+
+	return left, err
+}
+
+func (p *checkParser) parseNot() (*checkExpr, error) {
+	if p.accept("NOT") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &checkExpr{op: "NOT", left: expr}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *checkParser) parsePrimary() (*checkExpr, error) {
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("%w: missing ) in check", ErrInvalidSchema)
+		}
+		return expr, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.accept("IS") {
+		op := "IS NULL"
+		if p.accept("NOT") {
+			op = "IS NOT NULL"
+		}
+		if !p.accept("NULL") {
+			return nil, fmt.Errorf("%w: expected NULL after IS in check", ErrInvalidSchema)
+		}
+		return &checkExpr{op: op, left: left}, nil
+	}
+
+	op := p.peek()
+	switch op {
+	case "=", "<>", "!=", "<", "<=", ">", ">=":
+		p.pos++
+	default:
+		return nil, fmt.Errorf("%w: expected a comparison in check, got %q", ErrInvalidSchema, op)
+	}
+	if op == "!=" {
+		op = "<>"
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return &checkExpr{op: op, left: left, right: right}, nil
+}
+
+func (p *checkParser) parseOperand() (*checkExpr, error) {
+	tok := p.peek()
+	if tok == "" || tok == "(" || tok == ")" {
+		return nil, fmt.Errorf("%w: expected an operand in check, got %q", ErrInvalidSchema, tok)
+	}
+	p.pos++
+
+	lit := &checkLiteral{text: tok}
+	switch upper := strings.ToUpper(tok); {
+	case tok[0] == '\'':
+		lit.value = NewVarchar(strings.ReplaceAll(tok[1:len(tok)-1], "''", "'"))
+	case tok[0] == '-' || tok[0] == '.' || (tok[0] >= '0' && tok[0] <= '9'):
+		v, err := ParseDecimal(tok)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad number %q in check: %w", ErrInvalidSchema, tok, err)
+		}
+		lit.value = v
+	case upper == "TRUE" || upper == "FALSE":
+		lit.text = upper
+		lit.value = NewBoolean(upper == "TRUE")
+	case upper == "NULL":
+		lit.text = upper
+		lit.value = NewNull(TypeInvalid)
+	case upper == "AND" || upper == "OR" || upper == "NOT" || upper == "IS":
+		return nil, fmt.Errorf("%w: unexpected %s in check", ErrInvalidSchema, upper)
+	default:
+		return &checkExpr{column: tok}, nil
+	}
+
+	return &checkExpr{literal: lit}, nil
+}
+
+// bind 返回绑定到 schema 的副本：解析列的下标，把与列比较的字面量转换为列的类型
+func (e *checkExpr) bind(schema *Schema) (*checkExpr, error) {
+	b := *e
+	var err error
+	if e.left != nil {
+		if b.left, err = e.left.bind(schema); err != nil {
+			return nil, err
+		}
+	}
+	if e.right != nil {
+		if b.right, err = e.right.bind(schema); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case e.column != "":
+		if b.colIdx = schema.ColumnIndex(e.column); b.colIdx < 0 {
+			return nil, fmt.Errorf("%w: no column %s in check", ErrInvalidSchema, e.column)
+		}
+		return &b, nil
+	case e.literal != nil:
+		return &b, nil
+	}
+
+	if !isComparison(b.op) {
+		return &b, nil
+	}
+
+	// 比较的两边必须可以比较，与列比较的字面量转换为列的类型
+	lt, rt := b.left.typeIn(schema), b.right.typeIn(schema)
+	if b.left.literal != nil && b.right.column != "" {
+		if b.left, err = b.left.castLiteral(rt); err != nil {
+			return nil, err
+		}
+		lt = rt
+	}
+	if b.right.literal != nil && b.left.column != "" {
+		if b.right, err = b.right.castLiteral(lt); err != nil {
+			return nil, err
+		}
+		rt = lt
+	}
+	if lt.ID != TypeInvalid && rt.ID != TypeInvalid && lt.ID.class() != rt.ID.class() {
+		return nil, fmt.Errorf("%w: cannot compare %s with %s in check", ErrInvalidSchema, lt, rt)
+	}
+
+	return &b, nil
+}
+
+// typeIn 叶子节点的类型，NULL 字面量为 TypeInvalid
+func (e *checkExpr) typeIn(schema *Schema) Type {
+	if e.column != "" {
+		return schema.Columns[e.colIdx].Type
+	}
+
+	return Type{ID: e.literal.value.TypeID()}
+}
+
+func (e *checkExpr) castLiteral(t Type) (*checkExpr, error) {
+	if e.literal.value.IsNull() {
+		return e, nil
+	}
+
+	v, err := e.literal.value.CastAs(t)
+	if err != nil {
+		return nil, fmt.Errorf("%w: literal %s in check: %w", ErrInvalidSchema, e.literal.text, err)
+	}
+
+	return &checkExpr{literal: &checkLiteral{text: e.literal.text, value: v}}, nil
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "=", "<>", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// eval 对绑定后的表达式求值，返回结果和结果是否已知
+func (e *checkExpr) eval(row []Value) (result, known bool) {
+	switch e.op {
+	case "AND":
+		l, lk := e.left.eval(row)
+		r, rk := e.right.eval(row)
+		if (lk && !l) || (rk && !r) {
+			return false, true
+		}
+		return true, lk && rk
+	case "OR":
+		l, lk := e.left.eval(row)
+		r, rk := e.right.eval(row)
+		if (lk && l) || (rk && r) {
+			return true, true
+		}
+		return false, lk && rk
+	case "NOT":
+		v, k := e.left.eval(row)
+		return !v, k
+	case "IS NULL":
+		return e.left.value(row).IsNull(), true
+	case "IS NOT NULL":
+		return !e.left.value(row).IsNull(), true
+	}
+
+	l, r := e.left.value(row), e.right.value(row)
+	if l.IsNull() || r.IsNull() {
+		return false, false
+	}
+	cmp, err := l.Compare(r)
+	if err != nil {
+		return false, false
+	}
+
+	switch e.op {
+	case "=":
+		return cmp == 0, true
+	case "<>":
+		return cmp != 0, true
+	case "<":
+		return cmp < 0, true
+	case "<=":
+		return cmp <= 0, true
+	case ">":
+		return cmp > 0, true
+	}
+	return cmp >= 0, true
+}
+
+func (e *checkExpr) value(row []Value) Value {
+	if e.column != "" {
+		return row[e.colIdx]
+	}
+
+	return e.literal.value
+}
+
+// columns 表达式引用的列名
+func (e *checkExpr) columns() []string {
+	if e == nil {
+		return nil
+	}
+	if e.column != "" {
+		return []string{e.column}
+	}
+
+	return append(e.left.columns(), e.right.columns()...)
+}
+
+// renameColumn 返回把列 oldName 改名为 newName 的副本
+func (e *checkExpr) renameColumn(oldName, newName string) *checkExpr {
+	if e == nil {
+		return nil
+	}
+
+	r := *e
+	if strings.EqualFold(e.column, oldName) {
+		r.column = newName
+	}
+	r.left = e.left.renameColumn(oldName, newName)
+	r.right = e.right.renameColumn(oldName, newName)
+	return &r
+}
+
+// String 还原表达式，二元运算的两边加上括号以保留结合顺序
+func (e *checkExpr) String() string {
+	switch {
+	case e.column != "":
+		return e.column
+	case e.literal != nil:
+		return e.literal.text
+	}
+
+	switch e.op {
+	case "NOT":
+		return "NOT (" + e.left.String() + ")"
+	case "IS NULL", "IS NOT NULL":
+		return e.left.String() + " " + e.op
+	case "AND", "OR":
+		return "(" + e.left.String() + ") " + e.op + " (" + e.right.String() + ")"
+	}
+
+	return e.left.String() + " " + e.op + " " + e.right.String()
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func newCheckSchema(t *testing.T) *Schema {
+	t.Helper()
+
+	schema, err := NewSchema(
+		NewColumn("price", DecimalType(10, 2)),
+		NewColumn("qty", IntegerType()),
+		NewColumn("status", VarcharType(10)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return schema
+}
+
+func TestCheckExpr_Eval(t *testing.T) {
+	schema := newCheckSchema(t)
+	row := func(price Value, qty Value, status Value) []Value {
+		return []Value{price, qty, status}
+	}
+	ok := row(mustDecimal(250, 2), NewInteger(3), NewVarchar("open"))
+	nulls := row(NewNull(TypeDecimal), NewNull(TypeInteger), NewNull(TypeVarchar))
+
+	cases := []struct {
+		expr   string
+		row    []Value
+		result bool
+		known  bool
+	}{
+		{"price > 0", ok, true, true},
+		{"price > 2.5", ok, false, true},
+		{"price >= 2.50 AND qty < 10", ok, true, true},
+		{"qty = 1 OR status = 'open'", ok, true, true},
+		{"NOT (status <> 'open')", ok, true, true},
+		{"qty != 3", ok, false, true},
+		{"0 < qty", ok, true, true},
+		{"price > 0", nulls, false, false},
+		{"price > 0 OR qty IS NULL", nulls, true, true},
+		{"price > 0 AND qty = 1", row(NewNull(TypeDecimal), NewInteger(2), NewVarchar("x")), false, true},
+		{"NOT (price > 0)", nulls, true, false},
+		{"status IS NOT NULL", nulls, false, true},
+		{"status = 'it''s'", row(mustDecimal(1, 2), NewInteger(1), NewVarchar("it's")), true, true},
+		{"qty = NULL", ok, false, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			expr, err := parseCheckExpr(c.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expr, err = expr.bind(schema); err != nil {
+				t.Fatal(err)
+			}
+
+			result, known := expr.eval(c.row)
+			if known != c.known || (known && result != c.result) {
+				t.Errorf("expected (%v, %v), got (%v, %v)", c.result, c.known, result, known)
+			}
+		})
+	}
+}
+
+func TestCheckExpr_String(t *testing.T) {
+	cases := []struct {
+		expr string
+		want string
+	}{
+		{"price>0", "price > 0"},
+		{"a = 1 or b = 2 and not c is null", "(a = 1) OR ((b = 2) AND (NOT (c IS NULL)))"},
+		{"(a = 1 OR b = 2) AND c = true", "((a = 1) OR (b = 2)) AND (c = TRUE)"},
+		{"s <> 'it''s'", "s <> 'it''s'"},
+	}
+
+	for _, c := range cases {
+		expr, err := parseCheckExpr(c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := expr.String(); got != c.want {
+			t.Errorf("%s: expected %s, got %s", c.expr, c.want, got)
+		}
+
+		// 还原的表达式解析后得到同样的结果
+		again, err := parseCheckExpr(expr.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.String() != c.want {
+			t.Errorf("%s: round trip gave %s", c.expr, again)
+		}
+	}
+
+	expr, err := parseCheckExpr("qty > 0 AND QTY < price")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := expr.renameColumn("qty", "amount").String(); got != "(amount > 0) AND (amount < price)" {
+		t.Errorf("unexpected renamed expression %s", got)
+	}
+	if got := expr.String(); got != "(qty > 0) AND (QTY < price)" {
+		t.Errorf("renameColumn modified the original: %s", got)
+	}
+}
+
+func TestCheckExpr_Invalid(t *testing.T) {
+	schema := newCheckSchema(t)
+
+	for _, s := range []string{
+		"",
+		"price >",
+		"price > 0 AND",
+		"(price > 0",
+		"price > 0)",
+		"price",
+		"status = 'open",
+		"price IS 1",
+		"price > 1.2.3",
+		"missing > 0",
+		"price > 'abc'",
+		"status > qty",
+	} {
+		expr, err := parseCheckExpr(s)
+		if err == nil {
+			_, err = expr.bind(schema)
+		}
+		if !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("%q: expected ErrInvalidSchema, got %v", s, err)
+		}
+	}
+}
@@ -1,6 +1,82 @@
 package internal
 
+import (
+	"fmt"
+	"strings"
+)
+
+// Column 表中的一列
 type Column struct {
 	Name string
-	Type string
+	Type Type
+
+	// ID 列在表中的稳定编号，由 Catalog 分配，改名和删除其他列都不会改变它
+	// 不同版本的 Schema 之间通过 ID 对应同一列，为 0 时表示未分配
+	ID int
+
+	// NotNull 列不能为 NULL，主键的列隐含 NOT NULL
+	NotNull bool
+
+	// Default 列的默认值，为 nil 时没有默认值
+	// 插入时没有给出的列和 ADD COLUMN 加入的列在旧版本写入的元组中取默认值
+	Default *Value
+}
+
+// NewColumn 返回类型为 t 的列
+func NewColumn(name string, t Type) Column {
+	return Column{Name: name, Type: t}
+}
+
+// WithNotNull 返回 NOT NULL 的列
+func (c Column) WithNotNull() Column {
+	c.NotNull = true
+	return c
+}
+
+// WithDefault 返回默认值为 v 的列
+func (c Column) WithDefault(v Value) Column {
+	c.Default = &v
+	return c
+}
+
+// Validate 检查列名非空且类型合法
+func (c Column) Validate() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("%w: empty column name", ErrInvalidSchema)
+	}
+	if err := c.Type.Validate(); err != nil {
+		return fmt.Errorf("column %s: %w", c.Name, err)
+	}
+	if c.Default != nil {
+		if _, err := c.Default.CastAs(c.Type); err != nil {
+			return fmt.Errorf("%w: default of column %s: %w", ErrInvalidSchema, c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// DefaultValue 返回转换为列类型的默认值，没有默认值时返回 NULL
+func (c Column) DefaultValue() Value {
+	if c.Default == nil {
+		return NewNull(c.Type.ID)
+	}
+
+	v, err := c.Default.CastAs(c.Type)
+	if err != nil {
+		return NewNull(c.Type.ID)
+	}
+	return v
+}
+
+func (c Column) String() string {
+	s := c.Name + " " + c.Type.String()
+	if c.NotNull {
+		s += " NOT NULL"
+	}
+	if c.Default != nil {
+		s += " DEFAULT " + c.Default.String()
+	}
+
+	return s
 }
@@ -0,0 +1,257 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConstraintKind 约束的种类
+type ConstraintKind int
+
+const (
+	ConstraintNotNull ConstraintKind = iota
+	ConstraintPrimaryKey
+	ConstraintUnique
+	ConstraintCheck
+	ConstraintForeignKey
+)
+
+func (k ConstraintKind) String() string {
+	switch k {
+	case ConstraintNotNull:
+		return "NOT NULL"
+	case ConstraintPrimaryKey:
+		return "PRIMARY KEY"
+	case ConstraintUnique:
+		return "UNIQUE"
+	case ConstraintCheck:
+		return "CHECK"
+	case ConstraintForeignKey:
+		return "FOREIGN KEY"
+	}
+
+	return fmt.Sprintf("ConstraintKind(%d)", int(k))
+}
+
+// ReferentialAction 被外键引用的行删除或被引用的列更新时，对引用它的行的处理
+type ReferentialAction int
+
+const (
+	// ActionRestrict 还有引用的行时拒绝删除或更新
+	ActionRestrict ReferentialAction = iota
+	// ActionCascade 删除引用的行，或把它们的外键改为新的值
+	ActionCascade
+	// ActionSetNull 把引用的行的外键设为 NULL
+	ActionSetNull
+)
+
+func (a ReferentialAction) String() string {
+	switch a {
+	case ActionRestrict:
+		return "RESTRICT"
+	case ActionCascade:
+		return "CASCADE"
+	case ActionSetNull:
+		return "SET NULL"
+	}
+
+	return fmt.Sprintf("ReferentialAction(%d)", int(a))
+}
+
+// Constraint 表级约束
+// NOT NULL 和 DEFAULT 是列的属性，见 Column；PRIMARY KEY 和 UNIQUE 由同名的唯一索引实现，
+// FOREIGN KEY 由引用方外键列上同名的索引和被引用方被引用列上的唯一索引实现
+type Constraint struct {
+	// Name 约束名，为空时由 Catalog 在建表时生成
+	Name string
+	Kind ConstraintKind
+
+	// Columns PRIMARY KEY、UNIQUE 和 FOREIGN KEY 约束的列
+	Columns []string
+
+	// Check CHECK 约束的表达式，语法见 checkExpr
+	Check string
+
+	// RefTable 和 RefColumns 外键引用的表和列，RefColumns 为空时引用主键
+	RefTable   string
+	RefColumns []string
+
+	// OnDelete 和 OnUpdate 被引用的行删除和被引用的列更新时的处理
+	OnDelete ReferentialAction
+	OnUpdate ReferentialAction
+
+	// expr 绑定到所在 Schema 的 Check，由 Schema.Validate 设置
+	expr *checkExpr
+}
+
+// PrimaryKey 返回 columns 上的主键约束，主键的列隐含 NOT NULL
+func PrimaryKey(name string, columns ...string) Constraint {
+	return Constraint{Name: name, Kind: ConstraintPrimaryKey, Columns: columns}
+}
+
+// Unique 返回 columns 上的唯一约束，含 NULL 的行之间不冲突
+func Unique(name string, columns ...string) Constraint {
+	return Constraint{Name: name, Kind: ConstraintUnique, Columns: columns}
+}
+
+// Check 返回 CHECK 约束，如 Check("positive_price", "price > 0")
+func Check(name, expr string) Constraint {
+	return Constraint{Name: name, Kind: ConstraintCheck, Check: expr}
+}
+
+// ForeignKey 返回 columns 引用 refTable 的 refColumns 的外键约束，refColumns 为空时引用 refTable 的主键
+// 被引用的列上需要有唯一索引，外键列中有 NULL 的行不检查
+func ForeignKey(name string, columns []string, refTable string, refColumns ...string) Constraint {
+	return Constraint{Name: name, Kind: ConstraintForeignKey, Columns: columns, RefTable: refTable, RefColumns: refColumns}
+}
+
+// WithOnDelete 返回被引用的行删除时按 a 处理的外键约束
+func (c Constraint) WithOnDelete(a ReferentialAction) Constraint {
+	c.OnDelete = a
+	return c
+}
+
+// WithOnUpdate 返回被引用的列更新时按 a 处理的外键约束
+func (c Constraint) WithOnUpdate(a ReferentialAction) Constraint {
+	c.OnUpdate = a
+	return c
+}
+
+func (c Constraint) String() string {
+	var s string
+	switch c.Kind {
+	case ConstraintCheck:
+		s = "CHECK (" + c.Check + ")"
+	case ConstraintForeignKey:
+		s = "FOREIGN KEY (" + strings.Join(c.Columns, ", ") + ") REFERENCES " + c.RefTable
+		if len(c.RefColumns) > 0 {
+			s += " (" + strings.Join(c.RefColumns, ", ") + ")"
+		}
+		if c.OnDelete != ActionRestrict {
+			s += " ON DELETE " + c.OnDelete.String()
+		}
+		if c.OnUpdate != ActionRestrict {
+			s += " ON UPDATE " + c.OnUpdate.String()
+		}
+	default:
+		s = c.Kind.String() + " (" + strings.Join(c.Columns, ", ") + ")"
+	}
+	if c.Name != "" {
+		s = "CONSTRAINT " + c.Name + " " + s
+	}
+
+	return s
+}
+
+// referencedColumns 约束引用的列名
+func (c Constraint) referencedColumns() []string {
+	if c.Kind == ConstraintCheck && c.expr != nil {
+		return c.expr.columns()
+	}
+
+	return c.Columns
+}
+
+// validate 检查约束引用的列存在，并绑定 CHECK 表达式
+// 外键引用的表和列由 Catalog 检查
+func (c *Constraint) validate(schema *Schema) error {
+	switch c.Kind {
+	case ConstraintPrimaryKey, ConstraintUnique, ConstraintForeignKey:
+		if len(c.Columns) == 0 {
+			return fmt.Errorf("%w: %s has no columns", ErrInvalidSchema, c)
+		}
+		seen := make(map[int]struct{}, len(c.Columns))
+		for _, name := range c.Columns {
+			i := schema.ColumnIndex(name)
+			if i < 0 {
+				return fmt.Errorf("%w: no column %s in %s", ErrInvalidSchema, name, c)
+			}
+			if _, ok := seen[i]; ok {
+				return fmt.Errorf("%w: duplicate column %s in %s", ErrInvalidSchema, name, c)
+			}
+			seen[i] = struct{}{}
+		}
+		if c.Kind == ConstraintForeignKey {
+			return c.validateForeignKey(schema)
+		}
+	case ConstraintCheck:
+		expr, err := parseCheckExpr(c.Check)
+		if err != nil {
+			return err
+		}
+		if c.expr, err = expr.bind(schema); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: constraint %s has kind %s", ErrInvalidSchema, c.Name, c.Kind)
+	}
+
+	return nil
+}
+
+func (c *Constraint) validateForeignKey(schema *Schema) error {
+	if c.RefTable == "" {
+		return fmt.Errorf("%w: %s references no table", ErrInvalidSchema, c)
+	}
+	if len(c.RefColumns) > 0 && len(c.RefColumns) != len(c.Columns) {
+		return fmt.Errorf("%w: %s has %d columns referencing %d columns", ErrInvalidSchema, c, len(c.Columns), len(c.RefColumns))
+	}
+
+	for _, a := range []ReferentialAction{c.OnDelete, c.OnUpdate} {
+		switch a {
+		case ActionRestrict, ActionCascade:
+		case ActionSetNull:
+			for _, name := range c.Columns {
+				if schema.IsNotNull(schema.ColumnIndex(name)) {
+					return fmt.Errorf("%w: %s sets NOT NULL column %s to NULL", ErrInvalidSchema, c, name)
+				}
+			}
+		default:
+			return fmt.Errorf("%w: %s has action %s", ErrInvalidSchema, c, a)
+		}
+	}
+
+	return nil
+}
+
+// ConstraintError 违反约束的错误
+// errors.Is 可以匹配种类对应的 ErrNotNullViolation 等错误，以及 Err
+type ConstraintError struct {
+	Kind ConstraintKind
+	// Name 约束名，NOT NULL 约束为列名
+	Name string
+	// Detail 违反约束的值
+	Detail string
+	Err    error
+}
+
+func (e *ConstraintError) Error() string {
+	s := fmt.Sprintf("%s constraint %s violated", e.Kind, e.Name)
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+
+	return s
+}
+
+func (e *ConstraintError) Unwrap() []error {
+	var kindErr error
+	switch e.Kind {
+	case ConstraintNotNull:
+		kindErr = ErrNotNullViolation
+	case ConstraintPrimaryKey:
+		kindErr = ErrPrimaryKeyViolation
+	case ConstraintUnique:
+		kindErr = ErrUniqueViolation
+	case ConstraintCheck:
+		kindErr = ErrCheckViolation
+	case ConstraintForeignKey:
+		kindErr = ErrForeignKeyViolation
+	}
+
+	errs := []error{kindErr}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newConstraintSchema(t *testing.T) *Schema {
+	t.Helper()
+
+	schema, err := NewSchema(
+		NewColumn("id", IntegerType()),
+		NewColumn("email", VarcharType(64)).WithNotNull(),
+		NewColumn("price", DecimalType(10, 2)).WithDefault(NewInteger(1)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema.Constraints = []Constraint{
+		PrimaryKey("items_pkey", "id"),
+		Unique("items_email_key", "email"),
+		Check("positive_price", "price > 0"),
+	}
+	if err := schema.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	return schema
+}
+
+func TestSchema_CheckRow(t *testing.T) {
+	schema := newConstraintSchema(t)
+
+	if err := schema.CheckRow([]Value{NewInteger(1), NewVarchar("a"), mustDecimal(100, 2)}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	// CHECK 的结果未知时不算违反
+	if err := schema.CheckRow([]Value{NewInteger(1), NewVarchar("a"), NewNull(TypeDecimal)}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		values []Value
+		kind   ConstraintKind
+		target error
+		cName  string
+	}{
+		{"not null", []Value{NewInteger(1), NewNull(TypeVarchar), mustDecimal(100, 2)}, ConstraintNotNull, ErrNotNullViolation, "email"},
+		{"primary key implies not null", []Value{NewNull(TypeInteger), NewVarchar("a"), mustDecimal(100, 2)}, ConstraintNotNull, ErrNotNullViolation, "id"},
+		{"check", []Value{NewInteger(1), NewVarchar("a"), mustDecimal(-5, 2)}, ConstraintCheck, ErrCheckViolation, "positive_price"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := schema.CheckRow(c.values)
+			if !errors.Is(err, c.target) {
+				t.Fatalf("expected %v, got %v", c.target, err)
+			}
+			var cErr *ConstraintError
+			if !errors.As(err, &cErr) || cErr.Kind != c.kind || cErr.Name != c.cName {
+				t.Errorf("unexpected error %#v", err)
+			}
+			if !strings.Contains(err.Error(), c.cName) {
+				t.Errorf("error %q does not name the constraint", err)
+			}
+		})
+	}
+
+	row, err := schema.FillDefaults([]string{"email", "id"}, []Value{NewVarchar("a"), NewInteger(7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row[0].Int() != 7 || row[1].Str() != "a" || row[2].IsNull() {
+		t.Errorf("unexpected row %v", row)
+	}
+	if _, err := schema.FillDefaults([]string{"id", "ID"}, []Value{NewInteger(1), NewInteger(2)}); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema, got %v", err)
+	}
+}
+
+func TestSchema_Constraints(t *testing.T) {
+	columns := []Column{NewColumn("a", IntegerType()), NewColumn("b", VarcharType(8))}
+
+	invalid := map[string][]Constraint{
+		"two primary keys":    {PrimaryKey("p1", "a"), PrimaryKey("p2", "b")},
+		"duplicate name":      {Unique("k", "a"), Check("K", "a > 0")},
+		"missing column":      {Unique("k", "c")},
+		"duplicate column":    {Unique("k", "a", "A")},
+		"no columns":          {PrimaryKey("k")},
+		"bad check":           {Check("c", "a >")},
+		"check type mismatch": {Check("c", "b > a")},
+	}
+	for name, constraints := range invalid {
+		t.Run(name, func(t *testing.T) {
+			schema := &Schema{Columns: columns, Constraints: constraints}
+			if err := schema.Validate(); !errors.Is(err, ErrInvalidSchema) {
+				t.Errorf("expected ErrInvalidSchema, got %v", err)
+			}
+		})
+	}
+
+	schema := newConstraintSchema(t).withColumnIDs()
+	if got := schema.String(); got != "(id INTEGER, email VARCHAR(64) NOT NULL, price DECIMAL(10, 2) DEFAULT 1, "+
+		"CONSTRAINT items_pkey PRIMARY KEY (id), CONSTRAINT items_email_key UNIQUE (email), CONSTRAINT positive_price CHECK (price > 0))" {
+		t.Errorf("unexpected schema %s", got)
+	}
+
+	if _, err := schema.DropColumn("price"); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema when dropping a checked column, got %v", err)
+	}
+	if _, err := schema.AddColumn(NewColumn("c", IntegerType()).WithNotNull()); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema for NOT NULL without a default, got %v", err)
+	}
+
+	renamed, err := schema.RenameColumn("price", "cost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := renamed.Constraints[2]; c.Check != "cost > 0" {
+		t.Errorf("expected the check to be rewritten, got %s", c.Check)
+	}
+	if err := renamed.CheckRow([]Value{NewInteger(1), NewVarchar("a"), mustDecimal(0, 2)}); !errors.Is(err, ErrCheckViolation) {
+		t.Errorf("expected ErrCheckViolation, got %v", err)
+	}
+	renamed, err = renamed.RenameColumn("id", "item_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pk := renamed.PrimaryKey(); pk == nil || pk.Columns[0] != "item_id" || schema.PrimaryKey().Columns[0] != "id" {
+		t.Errorf("unexpected primary key %v", pk)
+	}
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"errors"
 	"log"
 	"os"
 	"testing"
@@ -95,3 +96,37 @@ func cleanup() {
 		log.Printf("Failed to remove log file: %v", err)
 	}
 }
+
+func TestAllocatePage(t *testing.T) {
+	dm, err := NewDiskManager("test.db")
+	if err != nil {
+		t.Fatalf("Failed to create DiskManager: %v", err)
+	}
+	defer dm.ShutDown()
+
+	first, err := dm.AllocatePage()
+	if err != nil {
+		t.Fatalf("Failed to allocate page: %v", err)
+	}
+	second, err := dm.AllocatePage()
+	if err != nil {
+		t.Fatalf("Failed to allocate page: %v", err)
+	}
+	if second != first+1 {
+		t.Fatalf("expected consecutive page ids, got %d and %d", first, second)
+	}
+
+	// 已分配但没写入过的页读取为全零
+	readData := make([]byte, PageSize)
+	readData[0] = 1
+	if err := dm.ReadPage(second, readData); err != nil {
+		t.Fatalf("Failed to read allocated page: %v", err)
+	}
+	if readData[0] != 0 {
+		t.Fatalf("expected zero-filled page")
+	}
+
+	if err := dm.ReadPage(second+1, readData); !errors.Is(err, ErrPageNotAllocated) {
+		t.Fatalf("expected ErrPageNotAllocated, got %v", err)
+	}
+}
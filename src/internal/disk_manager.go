@@ -1,9 +1,12 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sync"
+	"time"
 )
 
 const (
@@ -22,6 +25,12 @@ type DiskManager struct {
 	mu           sync.Mutex
 	NumWrites    int
 	PageCapacity int
+
+	// numPages 已分配的页数，页面 id 小于它的页即使还没写入也可以读取
+	numPages int
+
+	// Stats 读写次数、字节数和延迟统计
+	Stats DiskStats
 }
 
 // NewDiskManager 构造函数
@@ -37,13 +46,61 @@ func NewDiskManager(dbFileName string) (*DiskManager, error) {
 		return nil, fmt.Errorf("failed to open log file: %v", err)
 	}
 
-	return &DiskManager{
+	dm := &DiskManager{
 		DBFile:      dbFile,
 		LogFile:     logFile,
 		DBFileName:  dbFileName,
 		LogFileName: logFileName,
 		mu:          sync.Mutex{},
-	}, nil
+	}
+	if err := dm.loadNumPages(); err != nil {
+		return nil, err
+	}
+
+	return dm, nil
+}
+
+// AllocatePage 分配一个新的页面 id，页面在第一次写入前读取为全零
+func (dm *DiskManager) AllocatePage() (int, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if err := dm.loadNumPages(); err != nil {
+		return -1, err
+	}
+
+	pageID := dm.numPages
+	dm.numPages++
+
+	return pageID, nil
+}
+
+// NumPages 返回已分配的页数
+func (dm *DiskManager) NumPages() (int, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if err := dm.loadNumPages(); err != nil {
+		return 0, err
+	}
+
+	return dm.numPages, nil
+}
+
+// loadNumPages 根据文件大小初始化已分配的页数
+// 直接构造的 DiskManager 没有经过 NewDiskManager，因此在第一次使用时补上
+func (dm *DiskManager) loadNumPages() error {
+	if dm.numPages > 0 {
+		return nil
+	}
+
+	info, err := dm.DBFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat db file: %v", err)
+	}
+
+	dm.numPages = int((info.Size() + PageSize - 1) / PageSize)
+	return nil
 }
 
 // WritePage 将数据写入文件
@@ -55,13 +112,23 @@ func (dm *DiskManager) WritePage(pageID int, pageData []byte) error {
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
 
+	start := time.Now()
 	offset := int64(pageID) * PageSize
-	_, err := dm.DBFile.WriteAt(pageData, offset)
+	n, err := dm.DBFile.WriteAt(pageData, offset)
+	dm.Stats.WriteLatency.Observe(time.Since(start))
+	dm.Stats.BytesWritten.Add(int64(n))
 	if err != nil {
 		return fmt.Errorf("write page error: %v", err)
 	}
 
 	dm.NumWrites++
+	dm.Stats.Writes.Add(1)
+	if err := dm.loadNumPages(); err != nil {
+		return err
+	}
+	if pageID >= dm.numPages {
+		dm.numPages = pageID + 1
+	}
 	return nil
 }
 
@@ -74,8 +141,23 @@ func (dm *DiskManager) ReadPage(pageID int, pageData []byte) error {
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
 
+	start := time.Now()
 	offset := int64(pageID) * PageSize
 	n, err := dm.DBFile.ReadAt(pageData, offset)
+	dm.Stats.ReadLatency.Observe(time.Since(start))
+	dm.Stats.BytesRead.Add(int64(n))
+	dm.Stats.Reads.Add(1)
+	if errors.Is(err, io.EOF) {
+		if err := dm.loadNumPages(); err != nil {
+			return err
+		}
+		// 已分配但还没写入过的页面读取为全零
+		if pageID < dm.numPages {
+			clear(pageData[n:])
+			return nil
+		}
+		return fmt.Errorf("%w: page %d", ErrPageNotAllocated, pageID)
+	}
 	if err != nil {
 		return fmt.Errorf("read page error: %v", err)
 	}
@@ -0,0 +1,741 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// diskTrieMagic 元数据页的魔数 "GDBT"
+	diskTrieMagic = 0x47444254
+
+	// MaxDiskTrieValueSize 单个值的最大字节数
+	MaxDiskTrieValueSize = 1024
+	// maxDiskTrieEdge 单个节点前缀的最大字节数，更长的边会拆成多个节点
+	maxDiskTrieEdge = 512
+
+	// 节点页：头部为槽数和空闲空间指针，槽目录从前往后增长，记录从后往前增长
+	trieSlotCountOffset = 0
+	trieFreePtrOffset   = 2
+	trieHeaderSize      = 4
+	trieSlotSize        = 4
+
+	// 节点记录中一个子节点指针的大小：首字节、页面 id、槽号
+	trieChildSize = 7
+)
+
+// diskTrieAddr 节点在磁盘上的位置
+type diskTrieAddr struct {
+	pageID int
+	slot   int
+}
+
+type diskTrieChild struct {
+	key  byte
+	addr diskTrieAddr
+}
+
+// diskTrieNode 从页面中解码出来的节点，children 按首字节排序
+type diskTrieNode struct {
+	addr     diskTrieAddr
+	prefix   []byte
+	hasVal   bool
+	val      []byte
+	children []diskTrieChild
+}
+
+// DiskTrie 存储在缓冲池页面中的 Trie，用作持久化的字符串键索引
+// 节点以变长记录的形式保存在节点页中，节点变大放不下时会挪到别的页，
+// 父节点中的指针定长，因此只需原地更新父节点
+// 根节点的位置保存在元数据页中，通过 OpenDiskTrie 可以在重启后重新打开
+type DiskTrie struct {
+	bpm        *BufferPoolManager
+	metaPageID int
+	root       diskTrieAddr
+	// insertPageID 新记录优先放入的节点页
+	insertPageID int
+	mu           sync.RWMutex
+}
+
+// NewDiskTrie 分配元数据页和根节点，创建一个空的 DiskTrie
+func NewDiskTrie(bpm *BufferPoolManager) (*DiskTrie, error) {
+	meta, err := bpm.NewPage()
+	if err != nil {
+		return nil, err
+	}
+	metaPageID := meta.PageID
+	if err := bpm.UnpinPage(metaPageID, false); err != nil {
+		return nil, err
+	}
+
+	t := &DiskTrie{
+		bpm:          bpm,
+		metaPageID:   metaPageID,
+		insertPageID: -1,
+	}
+
+	root, err := t.allocate(&diskTrieNode{})
+	if err != nil {
+		return nil, err
+	}
+	t.root = root
+
+	if err := t.writeMeta(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// OpenDiskTrie 打开元数据页为 metaPageID 的 DiskTrie
+func OpenDiskTrie(bpm *BufferPoolManager, metaPageID int) (*DiskTrie, error) {
+	p, err := bpm.FetchPage(metaPageID)
+	if err != nil {
+		return nil, err
+	}
+	defer bpm.UnpinPage(metaPageID, false)
+
+	if binary.LittleEndian.Uint32(p.Data[0:]) != diskTrieMagic {
+		return nil, fmt.Errorf("%w: page %d is not a trie meta page", ErrInvalidTriePage, metaPageID)
+	}
+
+	return &DiskTrie{
+		bpm:        bpm,
+		metaPageID: metaPageID,
+		root: diskTrieAddr{
+			pageID: int(binary.LittleEndian.Uint32(p.Data[4:])),
+			slot:   int(binary.LittleEndian.Uint16(p.Data[8:])),
+		},
+		insertPageID: int(int32(binary.LittleEndian.Uint32(p.Data[10:]))),
+	}, nil
+}
+
+// MetaPageID 返回元数据页的 id，用于 OpenDiskTrie
+func (t *DiskTrie) MetaPageID() int {
+	return t.metaPageID
+}
+
+// Get 返回 key 对应的值
+func (t *DiskTrie) Get(key string) ([]byte, bool, error) {
+	if len(key) == 0 {
+		return nil, false, ErrEmptyKey
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	node, err := t.load(t.root)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rest := []byte(key)
+	for len(rest) > 0 {
+		child, ok := node.child(rest[0])
+		if !ok {
+			return nil, false, nil
+		}
+		if node, err = t.load(child); err != nil {
+			return nil, false, err
+		}
+		if !bytes.HasPrefix(rest, node.prefix) {
+			return nil, false, nil
+		}
+		rest = rest[len(node.prefix):]
+	}
+
+	if !node.hasVal {
+		return nil, false, nil
+	}
+
+	return node.val, true, nil
+}
+
+// Put 插入一个键值对，键已存在时返回 ErrKeyExists
+func (t *DiskTrie) Put(key string, val []byte) error {
+	switch {
+	case len(key) == 0:
+		return ErrEmptyKey
+	case len(val) > MaxDiskTrieValueSize:
+		return ErrValueTooLarge
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	root, err := t.put(t.root, []byte(key), val)
+	if err != nil {
+		return err
+	}
+
+	return t.setRoot(root)
+}
+
+// Delete 删除一个键，空的分支会被释放，只剩一个子节点的节点会与子节点合并
+func (t *DiskTrie) Delete(key string) error {
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	root, _, err := t.delete(t.root, []byte(key), true)
+	if err != nil {
+		return err
+	}
+
+	return t.setRoot(root)
+}
+
+// WalkPrefix 按字典序对所有以 prefix 开头的键调用 fn，fn 返回 false 时停止
+func (t *DiskTrie) WalkPrefix(prefix string, fn func(key string, val []byte) bool) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	node, err := t.load(t.root)
+	if err != nil {
+		return err
+	}
+
+	path := []byte{}
+	rest := []byte(prefix)
+	for len(rest) > 0 {
+		child, ok := node.child(rest[0])
+		if !ok {
+			return nil
+		}
+		if node, err = t.load(child); err != nil {
+			return err
+		}
+
+		switch {
+		case bytes.HasPrefix(rest, node.prefix):
+			rest = rest[len(node.prefix):]
+		case bytes.HasPrefix(node.prefix, rest):
+			rest = nil
+		default:
+			return nil
+		}
+		path = append(path, node.prefix...)
+	}
+
+	_, err = t.walk(node, path, fn)
+	return err
+}
+
+func (t *DiskTrie) walk(node *diskTrieNode, path []byte, fn func(key string, val []byte) bool) (bool, error) {
+	if node.hasVal && !fn(string(path), node.val) {
+		return false, nil
+	}
+
+	for _, c := range node.children {
+		child, err := t.load(c.addr)
+		if err != nil {
+			return false, err
+		}
+
+		ok, err := t.walk(child, append(path, child.prefix...), fn)
+		if !ok || err != nil {
+			return ok, err
+		}
+	}
+
+	return true, nil
+}
+
+// put 将 rest 插入到 addr 处节点下面，rest 已经去掉了该节点的前缀
+// 返回节点的新位置
+func (t *DiskTrie) put(addr diskTrieAddr, rest, val []byte) (diskTrieAddr, error) {
+	node, err := t.load(addr)
+	if err != nil {
+		return addr, err
+	}
+
+	if len(rest) == 0 {
+		if node.hasVal {
+			return addr, ErrKeyExists
+		}
+		node.hasVal = true
+		node.val = val
+		return t.store(node)
+	}
+
+	childAddr, ok := node.child(rest[0])
+	if !ok {
+		leaf, err := t.allocateChain(rest, val)
+		if err != nil {
+			return addr, err
+		}
+		node.setChild(rest[0], leaf)
+		return t.store(node)
+	}
+
+	child, err := t.load(childAddr)
+	if err != nil {
+		return addr, err
+	}
+
+	n := 0
+	for n < len(child.prefix) && n < len(rest) && child.prefix[n] == rest[n] {
+		n++
+	}
+
+	if n == len(child.prefix) {
+		newAddr, err := t.put(childAddr, rest[n:], val)
+		if err != nil || newAddr == childAddr {
+			return addr, err
+		}
+		node.setChild(rest[0], newAddr)
+		return t.store(node)
+	}
+
+	// 拆分边：新节点持有公共前缀，原来的子节点挂在它下面
+	split := &diskTrieNode{prefix: child.prefix[:n:n]}
+	child.prefix = child.prefix[n:]
+	if childAddr, err = t.store(child); err != nil {
+		return addr, err
+	}
+	split.setChild(child.prefix[0], childAddr)
+
+	if n == len(rest) {
+		split.hasVal = true
+		split.val = val
+	} else {
+		leaf, err := t.allocateChain(rest[n:], val)
+		if err != nil {
+			return addr, err
+		}
+		split.setChild(rest[n], leaf)
+	}
+
+	splitAddr, err := t.allocate(split)
+	if err != nil {
+		return addr, err
+	}
+	node.setChild(rest[0], splitAddr)
+
+	return t.store(node)
+}
+
+// allocateChain 为 key 创建叶子节点，超过 maxDiskTrieEdge 的部分拆成一串节点
+func (t *DiskTrie) allocateChain(key, val []byte) (diskTrieAddr, error) {
+	if len(key) <= maxDiskTrieEdge {
+		return t.allocate(&diskTrieNode{prefix: key, hasVal: true, val: val})
+	}
+
+	child, err := t.allocateChain(key[maxDiskTrieEdge:], val)
+	if err != nil {
+		return child, err
+	}
+
+	node := &diskTrieNode{prefix: key[:maxDiskTrieEdge]}
+	node.setChild(key[maxDiskTrieEdge], child)
+	return t.allocate(node)
+}
+
+// delete 从 addr 处节点下面删除 rest，返回节点的新位置，以及节点是否已被释放
+func (t *DiskTrie) delete(addr diskTrieAddr, rest []byte, isRoot bool) (diskTrieAddr, bool, error) {
+	node, err := t.load(addr)
+	if err != nil {
+		return addr, false, err
+	}
+
+	if len(rest) == 0 {
+		if !node.hasVal {
+			return addr, false, ErrKeyNotFound
+		}
+		node.hasVal = false
+		node.val = nil
+	} else {
+		childAddr, ok := node.child(rest[0])
+		if !ok {
+			return addr, false, ErrKeyNotFound
+		}
+		child, err := t.load(childAddr)
+		if err != nil {
+			return addr, false, err
+		}
+		if !bytes.HasPrefix(rest, child.prefix) {
+			return addr, false, ErrKeyNotFound
+		}
+
+		newAddr, freed, err := t.delete(childAddr, rest[len(child.prefix):], false)
+		if err != nil {
+			return addr, false, err
+		}
+		if freed {
+			node.removeChild(rest[0])
+		} else {
+			node.setChild(rest[0], newAddr)
+		}
+	}
+
+	if !isRoot && !node.hasVal {
+		switch len(node.children) {
+		case 0:
+			return addr, true, t.free(addr)
+		case 1:
+			if err := t.mergeChild(node); err != nil {
+				return addr, false, err
+			}
+		}
+	}
+
+	newAddr, err := t.store(node)
+	return newAddr, false, err
+}
+
+// mergeChild 把唯一的子节点合并进 node，合并后前缀过长时保持原样
+func (t *DiskTrie) mergeChild(node *diskTrieNode) error {
+	child, err := t.load(node.children[0].addr)
+	if err != nil {
+		return err
+	}
+	if len(node.prefix)+len(child.prefix) > maxDiskTrieEdge {
+		return nil
+	}
+
+	node.prefix = append(node.prefix, child.prefix...)
+	node.hasVal = child.hasVal
+	node.val = child.val
+	node.children = child.children
+
+	return t.free(child.addr)
+}
+
+func (n *diskTrieNode) child(b byte) (diskTrieAddr, bool) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].key >= b })
+	if i < len(n.children) && n.children[i].key == b {
+		return n.children[i].addr, true
+	}
+
+	return diskTrieAddr{}, false
+}
+
+func (n *diskTrieNode) setChild(b byte, addr diskTrieAddr) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].key >= b })
+	if i < len(n.children) && n.children[i].key == b {
+		n.children[i].addr = addr
+		return
+	}
+
+	n.children = append(n.children, diskTrieChild{})
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = diskTrieChild{key: b, addr: addr}
+}
+
+func (n *diskTrieNode) removeChild(b byte) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].key >= b })
+	if i < len(n.children) && n.children[i].key == b {
+		n.children = append(n.children[:i], n.children[i+1:]...)
+	}
+}
+
+// encode 节点记录：标志位、前缀、值、子节点指针
+func (n *diskTrieNode) encode() []byte {
+	size := 1 + 2 + len(n.prefix) + 2 + len(n.val) + 2 + len(n.children)*trieChildSize
+	buf := make([]byte, 0, size)
+
+	var flags byte
+	if n.hasVal {
+		flags = 1
+	}
+	buf = append(buf, flags)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(n.prefix)))
+	buf = append(buf, n.prefix...)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(n.val)))
+	buf = append(buf, n.val...)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(n.children)))
+	for _, c := range n.children {
+		buf = append(buf, c.key)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(c.addr.pageID))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(c.addr.slot))
+	}
+
+	return buf
+}
+
+func decodeDiskTrieNode(addr diskTrieAddr, rec []byte) *diskTrieNode {
+	n := &diskTrieNode{addr: addr, hasVal: rec[0] == 1}
+	pos := 1
+
+	prefixLen := int(binary.LittleEndian.Uint16(rec[pos:]))
+	pos += 2
+	n.prefix = bytes.Clone(rec[pos : pos+prefixLen])
+	pos += prefixLen
+
+	valLen := int(binary.LittleEndian.Uint16(rec[pos:]))
+	pos += 2
+	if n.hasVal {
+		n.val = bytes.Clone(rec[pos : pos+valLen])
+	}
+	pos += valLen
+
+	numChildren := int(binary.LittleEndian.Uint16(rec[pos:]))
+	pos += 2
+	n.children = make([]diskTrieChild, numChildren)
+	for i := range n.children {
+		n.children[i] = diskTrieChild{
+			key: rec[pos],
+			addr: diskTrieAddr{
+				pageID: int(binary.LittleEndian.Uint32(rec[pos+1:])),
+				slot:   int(binary.LittleEndian.Uint16(rec[pos+5:])),
+			},
+		}
+		pos += trieChildSize
+	}
+
+	return n
+}
+
+// load 读取 addr 处的节点
+func (t *DiskTrie) load(addr diskTrieAddr) (*diskTrieNode, error) {
+	p, err := t.bpm.FetchPage(addr.pageID)
+	if err != nil {
+		return nil, err
+	}
+	defer t.bpm.UnpinPage(addr.pageID, false)
+
+	rec := trieRecord(p.Data, addr.slot)
+	if rec == nil {
+		return nil, fmt.Errorf("%w: no node at page %d slot %d", ErrInvalidTriePage, addr.pageID, addr.slot)
+	}
+
+	return decodeDiskTrieNode(addr, rec), nil
+}
+
+// store 写回节点，原位置放不下时挪到其他页，返回节点的新位置
+// 挪动时先写好新记录再释放旧记录，分配失败时父节点仍指向完整的旧节点
+func (t *DiskTrie) store(n *diskTrieNode) (diskTrieAddr, error) {
+	rec := n.encode()
+
+	p, err := t.bpm.FetchPage(n.addr.pageID)
+	if err != nil {
+		return n.addr, err
+	}
+	if trieUpdateRecord(p.Data, n.addr.slot, rec) {
+		return n.addr, t.bpm.UnpinPage(n.addr.pageID, true)
+	}
+	if err := t.bpm.UnpinPage(n.addr.pageID, true); err != nil {
+		return n.addr, err
+	}
+
+	old := n.addr
+	addr, err := t.allocate(n)
+	if err != nil {
+		n.addr = old
+		return old, err
+	}
+	if err := t.free(old); err != nil {
+		return addr, err
+	}
+
+	return addr, nil
+}
+
+// allocate 为节点分配一个新记录
+func (t *DiskTrie) allocate(n *diskTrieNode) (diskTrieAddr, error) {
+	rec := n.encode()
+
+	if t.insertPageID >= 0 {
+		p, err := t.bpm.FetchPage(t.insertPageID)
+		if err != nil {
+			return diskTrieAddr{}, err
+		}
+		if slot, ok := trieInsertRecord(p.Data, rec); ok {
+			n.addr = diskTrieAddr{pageID: t.insertPageID, slot: slot}
+			return n.addr, t.bpm.UnpinPage(t.insertPageID, true)
+		}
+		if err := t.bpm.UnpinPage(t.insertPageID, false); err != nil {
+			return diskTrieAddr{}, err
+		}
+	}
+
+	// 当前页已满，分配新的节点页
+	p, err := t.bpm.NewPage()
+	if err != nil {
+		return diskTrieAddr{}, err
+	}
+	trieInitPage(p.Data)
+	slot, _ := trieInsertRecord(p.Data, rec)
+	if err := t.bpm.UnpinPage(p.PageID, true); err != nil {
+		return diskTrieAddr{}, err
+	}
+
+	t.insertPageID = p.PageID
+	n.addr = diskTrieAddr{pageID: p.PageID, slot: slot}
+	if err := t.writeMeta(); err != nil {
+		return n.addr, err
+	}
+
+	return n.addr, nil
+}
+
+// free 释放 addr 处的记录
+func (t *DiskTrie) free(addr diskTrieAddr) error {
+	p, err := t.bpm.FetchPage(addr.pageID)
+	if err != nil {
+		return err
+	}
+	trieFreeRecord(p.Data, addr.slot)
+
+	return t.bpm.UnpinPage(addr.pageID, true)
+}
+
+func (t *DiskTrie) setRoot(root diskTrieAddr) error {
+	if root == t.root {
+		return nil
+	}
+
+	t.root = root
+	return t.writeMeta()
+}
+
+// writeMeta 元数据页：魔数、根节点页面 id 和槽号、当前插入页
+func (t *DiskTrie) writeMeta() error {
+	p, err := t.bpm.FetchPage(t.metaPageID)
+	if err != nil {
+		return err
+	}
+
+	binary.LittleEndian.PutUint32(p.Data[0:], diskTrieMagic)
+	binary.LittleEndian.PutUint32(p.Data[4:], uint32(t.root.pageID))
+	binary.LittleEndian.PutUint16(p.Data[8:], uint16(t.root.slot))
+	binary.LittleEndian.PutUint32(p.Data[10:], uint32(int32(t.insertPageID)))
+
+	return t.bpm.UnpinPage(t.metaPageID, true)
+}
+
+// trieInitPage 初始化一个空的节点页
+func trieInitPage(data []byte) {
+	binary.LittleEndian.PutUint16(data[trieSlotCountOffset:], 0)
+	binary.LittleEndian.PutUint16(data[trieFreePtrOffset:], uint16(len(data)))
+}
+
+func trieSlot(data []byte, slot int) (offset, length int) {
+	pos := trieHeaderSize + slot*trieSlotSize
+	return int(binary.LittleEndian.Uint16(data[pos:])), int(binary.LittleEndian.Uint16(data[pos+2:]))
+}
+
+func trieSetSlot(data []byte, slot, offset, length int) {
+	pos := trieHeaderSize + slot*trieSlotSize
+	binary.LittleEndian.PutUint16(data[pos:], uint16(offset))
+	binary.LittleEndian.PutUint16(data[pos+2:], uint16(length))
+}
+
+// trieRecord 返回槽中的记录，槽为空时返回 nil
+func trieRecord(data []byte, slot int) []byte {
+	if slot >= int(binary.LittleEndian.Uint16(data[trieSlotCountOffset:])) {
+		return nil
+	}
+
+	offset, length := trieSlot(data, slot)
+	if length == 0 {
+		return nil
+	}
+
+	return data[offset : offset+length]
+}
+
+// trieInsertRecord 插入记录，优先复用空槽，空间不够时先整理页面
+func trieInsertRecord(data, rec []byte) (int, bool) {
+	numSlots := int(binary.LittleEndian.Uint16(data[trieSlotCountOffset:]))
+
+	slot := numSlots
+	for i := 0; i < numSlots; i++ {
+		if _, length := trieSlot(data, i); length == 0 {
+			slot = i
+			break
+		}
+	}
+
+	slotSpace := 0
+	if slot == numSlots {
+		slotSpace = trieSlotSize
+	}
+	if !trieReserve(data, len(rec)+slotSpace) {
+		return -1, false
+	}
+
+	if slot == numSlots {
+		binary.LittleEndian.PutUint16(data[trieSlotCountOffset:], uint16(numSlots+1))
+	}
+	trieWriteRecord(data, slot, rec)
+
+	return slot, true
+}
+
+// trieUpdateRecord 原地更新记录，放不下时返回 false 且不修改页面
+func trieUpdateRecord(data []byte, slot int, rec []byte) bool {
+	offset, length := trieSlot(data, slot)
+	if len(rec) <= length {
+		copy(data[offset:], rec)
+		trieSetSlot(data, slot, offset, len(rec))
+		return true
+	}
+
+	// 旧记录的空间在整理后可以复用
+	old := bytes.Clone(data[offset : offset+length])
+	trieSetSlot(data, slot, 0, 0)
+	if !trieReserve(data, len(rec)) {
+		// 恢复旧记录
+		trieWriteRecord(data, slot, old)
+		return false
+	}
+	trieWriteRecord(data, slot, rec)
+
+	return true
+}
+
+func trieFreeRecord(data []byte, slot int) {
+	trieSetSlot(data, slot, 0, 0)
+}
+
+// trieWriteRecord 将记录写到空闲空间的末尾，调用方需确保空间足够
+func trieWriteRecord(data []byte, slot int, rec []byte) {
+	freePtr := int(binary.LittleEndian.Uint16(data[trieFreePtrOffset:])) - len(rec)
+	copy(data[freePtr:], rec)
+	binary.LittleEndian.PutUint16(data[trieFreePtrOffset:], uint16(freePtr))
+	trieSetSlot(data, slot, freePtr, len(rec))
+}
+
+// trieReserve 确保页面中有 size 字节的连续空闲空间，必要时整理页面
+func trieReserve(data []byte, size int) bool {
+	if trieFreeSpace(data) >= size {
+		return true
+	}
+
+	trieCompact(data)
+	return trieFreeSpace(data) >= size
+}
+
+func trieFreeSpace(data []byte) int {
+	numSlots := int(binary.LittleEndian.Uint16(data[trieSlotCountOffset:]))
+	freePtr := int(binary.LittleEndian.Uint16(data[trieFreePtrOffset:]))
+	return freePtr - trieHeaderSize - numSlots*trieSlotSize
+}
+
+// trieCompact 把所有记录紧凑地移到页尾，回收已释放记录的空间
+func trieCompact(data []byte) {
+	numSlots := int(binary.LittleEndian.Uint16(data[trieSlotCountOffset:]))
+
+	records := make([][]byte, numSlots)
+	for i := range records {
+		if rec := trieRecord(data, i); rec != nil {
+			records[i] = bytes.Clone(rec)
+		}
+	}
+
+	binary.LittleEndian.PutUint16(data[trieFreePtrOffset:], uint16(len(data)))
+	for i, rec := range records {
+		if rec == nil {
+			trieSetSlot(data, i, 0, 0)
+			continue
+		}
+		trieWriteRecord(data, i, rec)
+	}
+}
@@ -0,0 +1,300 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiskTrie_PutGet(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	trie, err := NewDiskTrie(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		if err := trie.Put("", nil); !errors.Is(err, ErrEmptyKey) {
+			t.Error("expected ErrEmptyKey")
+		}
+		if err := trie.Put("key", make([]byte, MaxDiskTrieValueSize+1)); !errors.Is(err, ErrValueTooLarge) {
+			t.Error("expected ErrValueTooLarge")
+		}
+		if _, _, err := trie.Get(""); !errors.Is(err, ErrEmptyKey) {
+			t.Error("expected ErrEmptyKey")
+		}
+		if err := trie.Delete("key"); !errors.Is(err, ErrKeyNotFound) {
+			t.Error("expected ErrKeyNotFound")
+		}
+	})
+
+	t.Run("split edges", func(t *testing.T) {
+		for _, key := range []string{"romane", "romanus", "romulus", "rom", "r"} {
+			if err := trie.Put(key, []byte(strings.ToUpper(key))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := trie.Put("rom", nil); !errors.Is(err, ErrKeyExists) {
+			t.Error("expected ErrKeyExists")
+		}
+
+		for _, key := range []string{"romane", "romanus", "romulus", "rom", "r"} {
+			val, ok, err := trie.Get(key)
+			if err != nil || !ok || string(val) != strings.ToUpper(key) {
+				t.Errorf("expected %s, got %s", strings.ToUpper(key), val)
+			}
+		}
+		for _, key := range []string{"ro", "roman", "romanes", "x"} {
+			if _, ok, err := trie.Get(key); err != nil || ok {
+				t.Errorf("expected %s not found", key)
+			}
+		}
+	})
+
+	t.Run("long key", func(t *testing.T) {
+		key := strings.Repeat("abcdefgh", 300)
+		if err := trie.Put(key, []byte("long")); err != nil {
+			t.Fatal(err)
+		}
+		if err := trie.Put(key[:1000], []byte("middle")); err != nil {
+			t.Fatal(err)
+		}
+
+		if val, ok, err := trie.Get(key); err != nil || !ok || string(val) != "long" {
+			t.Errorf("expected long, got %s", val)
+		}
+		if val, ok, err := trie.Get(key[:1000]); err != nil || !ok || string(val) != "middle" {
+			t.Errorf("expected middle, got %s", val)
+		}
+	})
+}
+
+func TestDiskTrie_AllocationFailure(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	trie, err := NewDiskTrie(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := make(map[string]int)
+	put := func(key string, n int) error {
+		err := trie.Put(key, []byte(strings.Repeat("v", n)))
+		if err == nil {
+			stored[key] = n
+		}
+		return err
+	}
+	for _, key := range []string{"p1", "p2"} {
+		if err := put(key, 8); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 12; i++ {
+		if err := put(fmt.Sprintf("f%02d", i), 900); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 占满缓冲池：pin 住已有的页面，再用新页面填满空闲的 frame，之后分配节点页会失败
+	var pinned []int
+	for pageID := range bm.PageTable {
+		pinned = append(pinned, pageID)
+	}
+	for _, pageID := range pinned {
+		if _, err := bm.FetchPage(pageID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for {
+		p, err := bm.NewPage()
+		if err != nil {
+			break
+		}
+		pinned = append(pinned, p.PageID)
+	}
+
+	// 填满当前的插入页，之后 p 加上值在原来的页放不下，需要挪到新的节点页
+	for i := 0; err == nil; i++ {
+		err = put(fmt.Sprintf("z%02d", i), 900)
+	}
+	if !errors.Is(err, ErrNoEvictableFrame) {
+		t.Fatalf("expected ErrNoEvictableFrame, got %v", err)
+	}
+	if err := put("p", MaxDiskTrieValueSize); !errors.Is(err, ErrNoEvictableFrame) {
+		t.Fatalf("expected ErrNoEvictableFrame, got %v", err)
+	}
+
+	for _, pageID := range pinned {
+		if err := bm.UnpinPage(pageID, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 失败的写入不破坏已有的节点
+	for key, n := range stored {
+		if val, ok, err := trie.Get(key); err != nil || !ok || len(val) != n {
+			t.Errorf("%s: expected %d bytes, got %d, %v, %v", key, n, len(val), ok, err)
+		}
+	}
+	if err := put("p", MaxDiskTrieValueSize); err != nil {
+		t.Fatal(err)
+	}
+	if val, ok, err := trie.Get("p"); err != nil || !ok || len(val) != MaxDiskTrieValueSize {
+		t.Errorf("expected p to be stored, got %d bytes, %v, %v", len(val), ok, err)
+	}
+}
+
+func TestDiskTrie_Persistence(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	rnd := rand.New(rand.NewSource(1))
+	expected := make(map[string]string)
+
+	bm := NewBufferPoolManager(dm, 4, PageSize, 2)
+	trie, err := NewDiskTrie(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// enough keys and values to spread the nodes over many pages
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("table_%d.column_%d", rnd.Intn(300), rnd.Intn(50))
+		if _, ok := expected[key]; ok {
+			if err := trie.Delete(key); err != nil {
+				t.Fatal(err)
+			}
+			delete(expected, key)
+			continue
+		}
+
+		val := strings.Repeat("v", rnd.Intn(64))
+		if err := trie.Put(key, []byte(val)); err != nil {
+			t.Fatal(err)
+		}
+		expected[key] = val
+	}
+	if err := bm.ShutDown(); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopen through a fresh buffer pool
+	bm = NewBufferPoolManager(dm, 4, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	trie, err = OpenDiskTrie(bm, trie.MetaPageID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, val := range expected {
+		got, ok, err := trie.Get(key)
+		if err != nil || !ok || string(got) != val {
+			t.Fatalf("expected %s = %q, got %q", key, val, got)
+		}
+	}
+
+	var keys []string
+	if err := trie.WalkPrefix("", func(key string, val []byte) bool {
+		keys = append(keys, key)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != len(expected) || !slices.IsSorted(keys) {
+		t.Errorf("expected %d sorted keys, got %d", len(expected), len(keys))
+	}
+
+	// deleting everything leaves an empty root
+	for key := range expected {
+		if err := trie.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := trie.load(trie.root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.children) != 0 {
+		t.Errorf("expected empty root, got %d children", len(root.children))
+	}
+}
+
+func TestDiskTrie_WalkPrefix(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	trie, err := NewDiskTrie(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"tables", "tab", "apple", "table", "tac"} {
+		if err := trie.Put(key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var keys []string
+	walk := func(key string, val []byte) bool {
+		if key != string(val) {
+			t.Errorf("value mismatch for %s", key)
+		}
+		keys = append(keys, key)
+		return true
+	}
+
+	if err := trie.WalkPrefix("tab", walk); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"tab", "table", "tables"}) {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	// prefix ending in the middle of an edge
+	keys = nil
+	if err := trie.WalkPrefix("tabl", walk); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"table", "tables"}) {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	keys = nil
+	if err := trie.WalkPrefix("", func(key string, val []byte) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"apple", "tab"}) {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestOpenDiskTrie(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 4, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	if _, err := OpenDiskTrie(bm, 0); !errors.Is(err, ErrInvalidTriePage) {
+		t.Error("expected ErrInvalidTriePage")
+	}
+}
@@ -10,14 +10,57 @@ var (
 	ErrUnEvictableFrame  = errors.New("un evictable frame")
 	ErrUnInitialized     = errors.New("un initialized")
 	ErrNoEvictableFrame  = errors.New("no evictable frame")
+	ErrInvalidPoolSize   = errors.New("invalid pool size, should be greater than zero")
 	// ErrCapacityExceeded  = errors.New("capacity exceeded")
 
 	ErrUnRemovableFrame = errors.New("un removable frame")
 
+	// ErrPinLeak 关闭缓冲池时仍有页面被 pin 住
+	ErrPinLeak = errors.New("pin leak")
+
+	ErrInvalidWarmupFile = errors.New("invalid warmup file")
+
+	// ErrPageNotAllocated 读取的页面超出了数据库文件中已分配的页面
+	ErrPageNotAllocated = errors.New("page not allocated")
+
 	ErrEmptyTrie = errors.New("empty trie")
 
 	// ErrEmptyKey empty key is not allowed
 	ErrEmptyKey    = errors.New("empty key")
 	ErrKeyNotFound = errors.New("key not found")
 	ErrKeyExists   = errors.New("key exists")
+
+	ErrValueTooLarge   = errors.New("value too large")
+	ErrInvalidTriePage = errors.New("invalid trie page")
+
+	ErrInvalidTrieSnapshot = errors.New("invalid trie snapshot")
+
+	ErrInvalidType    = errors.New("invalid type")
+	ErrTypeMismatch   = errors.New("type mismatch")
+	ErrInvalidCast    = errors.New("invalid cast")
+	ErrOverflow       = errors.New("numeric overflow")
+	ErrDivisionByZero = errors.New("division by zero")
+	ErrValueTooLong   = errors.New("value too long")
+	ErrNullValue      = errors.New("null value")
+	ErrInvalidSchema  = errors.New("invalid schema")
+	ErrInvalidTuple   = errors.New("invalid tuple")
+
+	ErrInvalidTablePage = errors.New("invalid table page")
+	ErrInvalidSlot      = errors.New("invalid slot")
+	ErrTupleDeleted     = errors.New("tuple deleted")
+	ErrNotEnoughSpace   = errors.New("not enough space in page")
+
+	ErrDuplicateKey       = errors.New("duplicate key")
+	ErrTableExists        = errors.New("table already exists")
+	ErrTableNotFound      = errors.New("table not found")
+	ErrIndexExists        = errors.New("index already exists")
+	ErrIndexNotFound      = errors.New("index not found")
+	ErrInvalidHeaderPage  = errors.New("invalid database header page")
+	ErrInvalidCatalogData = errors.New("invalid catalog data")
+
+	ErrNotNullViolation    = errors.New("not null violation")
+	ErrPrimaryKeyViolation = errors.New("primary key violation")
+	ErrUniqueViolation     = errors.New("unique violation")
+	ErrCheckViolation      = errors.New("check violation")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
 )
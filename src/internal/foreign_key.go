@@ -0,0 +1,350 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// foreignKey 外键约束在运行时的状态，由 Catalog 在建表和打开时连接到两张表上
+// 两边都通过索引查找对方的行，索引在 ALTER TABLE 后按列 ID 重新绑定，因此不需要记录列的下标
+type foreignKey struct {
+	name string
+
+	// child 和 childIndex 引用方的表和外键列上的索引
+	child      *Table
+	childIndex *Index
+
+	// parent 和 parentIndex 被引用方的表和被引用列上的唯一索引
+	parent      *Table
+	parentIndex *Index
+
+	onDelete ReferentialAction
+	onUpdate ReferentialAction
+}
+
+// connectForeignKey 把外键挂到两张表上，之后对两张表的写入都会检查它
+// fkMu 是 Catalog 中所有涉及外键的表共用的锁，见 writeScope
+func connectForeignKey(fkMu *sync.Mutex, fk *foreignKey) {
+	fkMu.Lock()
+	defer fkMu.Unlock()
+
+	s := &writeScope{}
+	s.lock(fk.child)
+	s.lock(fk.parent)
+	defer s.unlockTables()
+
+	fk.child.fkMu.Store(fkMu)
+	fk.parent.fkMu.Store(fkMu)
+	fk.child.foreignKeys = append(fk.child.foreignKeys, fk)
+	fk.parent.referencedBy = append(fk.parent.referencedBy, fk)
+}
+
+// disconnectForeignKeys 从被引用的表上摘下 t 的外键，用于删除表
+func disconnectForeignKeys(fkMu *sync.Mutex, t *Table) {
+	fkMu.Lock()
+	defer fkMu.Unlock()
+
+	s := &writeScope{}
+	s.lock(t)
+	for _, fk := range t.foreignKeys {
+		s.lock(fk.parent)
+	}
+	defer s.unlockTables()
+
+	for _, fk := range t.foreignKeys {
+		fk.parent.referencedBy = slices.DeleteFunc(fk.parent.referencedBy, func(other *foreignKey) bool {
+			return other == fk
+		})
+	}
+	t.foreignKeys = nil
+}
+
+// writeScope 一次写操作锁住的表
+// 外键检查和级联修改会写入其他表，涉及外键的表的写操作都先取得共用的 fkMu，
+// 因此同时只有一个写操作会锁住多张表，不会死锁
+type writeScope struct {
+	fkMu   *sync.Mutex
+	locked []*Table
+}
+
+// lockWrite 开始对 t 的一次写操作，完成后调用 unlock
+func (t *Table) lockWrite() *writeScope {
+	for {
+		fkMu := t.fkMu.Load()
+		if fkMu != nil {
+			fkMu.Lock()
+		}
+		t.mu.Lock()
+
+		// 加锁期间 t 可能刚被连接到外键上，此时需要改为先取得 fkMu
+		if t.fkMu.Load() == fkMu {
+			return &writeScope{fkMu: fkMu, locked: []*Table{t}}
+		}
+		t.mu.Unlock()
+		if fkMu != nil {
+			fkMu.Unlock()
+		}
+	}
+}
+
+// lock 锁住 t，已经锁住时什么都不做
+func (s *writeScope) lock(t *Table) {
+	if slices.Contains(s.locked, t) {
+		return
+	}
+
+	t.mu.Lock()
+	s.locked = append(s.locked, t)
+}
+
+func (s *writeScope) unlock() {
+	s.unlockTables()
+	if s.fkMu != nil {
+		s.fkMu.Unlock()
+	}
+}
+
+func (s *writeScope) unlockTables() {
+	for i := len(s.locked) - 1; i >= 0; i-- {
+		s.locked[i].mu.Unlock()
+	}
+	s.locked = nil
+}
+
+// checkReferences 检查一行的外键引用的行存在，old 不为 nil 时只检查外键列变化了的外键
+// 外键列中有 NULL 时不检查
+func (t *Table) checkReferences(s *writeScope, old, values []Value) error {
+	for _, fk := range t.foreignKeys {
+		if old != nil {
+			changed, err := fk.childIndex.keyChanged(old, values)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+		}
+
+		key := fk.childIndex.KeyOf(values)
+		if slices.ContainsFunc(key, Value.IsNull) {
+			continue
+		}
+
+		s.lock(fk.parent)
+		rids, err := fk.parentIndex.ScanKey(key)
+		if err != nil {
+			return err
+		}
+		if len(rids) > 0 {
+			continue
+		}
+
+		// 引用自己的表中，一行可以引用它自己
+		if fk.parent == t {
+			self, _, err := fk.parentIndex.encodeKey(fk.parentIndex.KeyOf(values))
+			if err != nil {
+				return err
+			}
+			if ref, _, err := fk.parentIndex.encodeKey(key); err == nil && ref == self {
+				continue
+			}
+		}
+
+		return &ConstraintError{
+			Kind:   ConstraintForeignKey,
+			Name:   fk.name,
+			Detail: fmt.Sprintf("key %s is not present in table %s", formatIndexKey(key), fk.parent.Name),
+		}
+	}
+
+	return nil
+}
+
+// referencingRows 返回通过 fk 引用 old 的行，不包括 rid 处的这一行自己
+func (fk *foreignKey) referencingRows(s *writeScope, rid RID, old []Value) ([]RID, error) {
+	key := fk.parentIndex.KeyOf(old)
+	if slices.ContainsFunc(key, Value.IsNull) {
+		return nil, nil
+	}
+
+	s.lock(fk.child)
+	rids, err := fk.childIndex.ScanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(rids, func(r RID) bool { return fk.child == fk.parent && r == rid }), nil
+}
+
+// referenceKey 唯一标识一张表中的一行
+type referenceKey struct {
+	table *Table
+	rid   RID
+}
+
+// pendingKey 唯一索引上的一个键
+type pendingKey struct {
+	index *Index
+	key   string
+}
+
+// cascadeCheck 一次删除或更新沿着级联检查过的行
+type cascadeCheck struct {
+	// visited 已经检查过的行，避免循环引用时重复检查
+	visited map[referenceKey]struct{}
+	// keys 更新后的行在唯一索引上的键，级联修改的两行不能得到同一个键
+	keys map[pendingKey]struct{}
+}
+
+func newCascadeCheck() *cascadeCheck {
+	return &cascadeCheck{
+		visited: make(map[referenceKey]struct{}),
+		keys:    make(map[pendingKey]struct{}),
+	}
+}
+
+// addKeys 记录 t 中更新后的一行 values 在唯一索引上的键，与之前记录的键重复时返回 ErrDuplicateKey
+func (c *cascadeCheck) addKeys(t *Table, values []Value) error {
+	for _, idx := range t.indexes {
+		if !idx.Unique {
+			continue
+		}
+		key := idx.KeyOf(values)
+		if slices.ContainsFunc(key, Value.IsNull) {
+			continue
+		}
+
+		encoded, _, err := idx.encodeKey(key)
+		if err != nil {
+			return err
+		}
+		if _, ok := c.keys[pendingKey{idx, encoded}]; ok {
+			return t.uniqueViolation(idx, values, fmt.Errorf("%w: index %s, key %s", ErrDuplicateKey, idx.Name, formatIndexKey(key)))
+		}
+		c.keys[pendingKey{idx, encoded}] = struct{}{}
+	}
+
+	return nil
+}
+
+// checkReferencedBy 在删除或更新 rid 处的一行之前，沿着级联检查是否有 RESTRICT 的外键被违反，
+// 以及级联修改后的行是否满足所在表的约束和唯一索引
+// values 为 nil 表示删除，否则为更新后的一行
+// 检查在修改任何一行之前完成，因此检查失败时所有的表都不会被修改
+func (t *Table) checkReferencedBy(s *writeScope, rid RID, old, values []Value, check *cascadeCheck) error {
+	if _, ok := check.visited[referenceKey{t, rid}]; ok {
+		return nil
+	}
+	check.visited[referenceKey{t, rid}] = struct{}{}
+	if values != nil {
+		if err := check.addKeys(t, values); err != nil {
+			return err
+		}
+	}
+
+	for _, fk := range t.referencedBy {
+		action := fk.onDelete
+		if values != nil {
+			changed, err := fk.parentIndex.keyChanged(old, values)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+			action = fk.onUpdate
+		}
+
+		rids, err := fk.referencingRows(s, rid, old)
+		if err != nil {
+			return err
+		}
+		if len(rids) == 0 {
+			continue
+		}
+		if action == ActionRestrict {
+			return &ConstraintError{
+				Kind:   ConstraintForeignKey,
+				Name:   fk.name,
+				Detail: fmt.Sprintf("key %s is still referenced from table %s", formatIndexKey(fk.parentIndex.KeyOf(old)), fk.child.Name),
+			}
+		}
+
+		for _, childRID := range rids {
+			childOld, err := fk.child.getTuple(childRID)
+			if err != nil {
+				return err
+			}
+			var childValues []Value
+			if values != nil || action == ActionSetNull {
+				childValues, err = fk.child.checkRow(fk.referencingValues(childOld, values, action), childRID)
+				if err != nil {
+					return err
+				}
+			}
+			if err := fk.child.checkReferencedBy(s, childRID, childOld, childValues, check); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// cascade 在删除或更新 rid 处的一行之后，按外键的设置删除或修改引用它的行
+// RESTRICT 的外键和修改后的行的约束已经由 checkReferencedBy 检查过
+func (t *Table) cascade(s *writeScope, rid RID, old, values []Value) error {
+	for _, fk := range t.referencedBy {
+		action := fk.onDelete
+		if values != nil {
+			changed, err := fk.parentIndex.keyChanged(old, values)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+			action = fk.onUpdate
+		}
+		if action == ActionRestrict {
+			continue
+		}
+
+		rids, err := fk.referencingRows(s, rid, old)
+		if err != nil {
+			return err
+		}
+		for _, childRID := range rids {
+			childOld, err := fk.child.getTuple(childRID)
+			if err != nil {
+				return err
+			}
+			if values == nil && action == ActionCascade {
+				err = fk.child.delete(s, childRID, childOld)
+			} else {
+				_, err = fk.child.update(s, childRID, childOld, fk.referencingValues(childOld, values, action))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// referencingValues 返回引用方的一行在被引用的行更新为 values 后的样子，
+// SET NULL 时外键列为 NULL，CASCADE 时外键列为 values 中被引用的列
+func (fk *foreignKey) referencingValues(row, values []Value, action ReferentialAction) []Value {
+	row = slices.Clone(row)
+	for i, attr := range fk.childIndex.keyAttrs {
+		if action == ActionSetNull {
+			row[attr] = NewNull(fk.childIndex.keyTypes[i].ID)
+		} else {
+			row[attr] = values[fk.parentIndex.keyAttrs[i]]
+		}
+	}
+
+	return row
+}
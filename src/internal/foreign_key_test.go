@@ -0,0 +1,562 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// newForeignKeyCatalog 创建 users、orders 和 items 三张表：
+// orders 引用 users，删除和更新时都级联；items 引用 orders，删除时拒绝，更新时置为 NULL
+func newForeignKeyCatalog(t *testing.T, bm *BufferPoolManager) *Catalog {
+	t.Helper()
+
+	c, err := NewCatalog(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := &Schema{
+		Columns:     []Column{NewColumn("id", IntegerType()), NewColumn("name", VarcharType(20))},
+		Constraints: []Constraint{PrimaryKey("", "id")},
+	}
+	orders := &Schema{
+		Columns: []Column{NewColumn("id", IntegerType()), NewColumn("user_id", IntegerType())},
+		Constraints: []Constraint{
+			PrimaryKey("", "id"),
+			ForeignKey("", []string{"user_id"}, "users").WithOnDelete(ActionCascade).WithOnUpdate(ActionCascade),
+		},
+	}
+	items := &Schema{
+		Columns: []Column{NewColumn("id", IntegerType()), NewColumn("order_id", IntegerType())},
+		Constraints: []Constraint{
+			ForeignKey("", []string{"order_id"}, "orders", "id").WithOnUpdate(ActionSetNull),
+		},
+	}
+	for _, table := range []struct {
+		name   string
+		schema *Schema
+	}{{"users", users}, {"orders", orders}, {"items", items}} {
+		if _, err := c.CreateTable(table.name, table.schema); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return c
+}
+
+func insertRows(t *testing.T, c *Catalog, table string, rows ...[]Value) []RID {
+	t.Helper()
+
+	info, err := c.GetTable(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rids := make([]RID, len(rows))
+	for i, row := range rows {
+		if rids[i], err = info.Table.InsertTuple(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rids
+}
+
+// tableRows 返回表中所有行的字符串形式，按字典序排序
+func tableRows(t *testing.T, c *Catalog, table string) []string {
+	t.Helper()
+
+	info, err := c.GetTable(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []string
+	err = scanTable(info.Table, func(_ RID, row []Value) error {
+		rows = append(rows, fmt.Sprint(row))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(rows)
+
+	return rows
+}
+
+func TestForeignKey(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 16, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	c := newForeignKeyCatalog(t, bm)
+	users := insertRows(t, c, "users",
+		[]Value{NewInteger(1), NewVarchar("alice")},
+		[]Value{NewInteger(2), NewVarchar("bob")},
+	)
+	orders := insertRows(t, c, "orders",
+		[]Value{NewInteger(10), NewInteger(1)},
+		[]Value{NewInteger(11), NewInteger(1)},
+		[]Value{NewInteger(20), NewInteger(2)},
+	)
+	items := insertRows(t, c, "items",
+		[]Value{NewInteger(100), NewInteger(10)},
+		[]Value{NewInteger(101), NewInteger(11)},
+		[]Value{NewInteger(102), NewNull(TypeInteger)},
+	)
+	usersInfo, _ := c.GetTable("users")
+	ordersInfo, _ := c.GetTable("orders")
+	itemsInfo, _ := c.GetTable("items")
+
+	t.Run("insert and update of the child", func(t *testing.T) {
+		_, err := ordersInfo.Table.InsertTuple([]Value{NewInteger(12), NewInteger(3)})
+		if !errors.Is(err, ErrForeignKeyViolation) {
+			t.Fatalf("expected ErrForeignKeyViolation, got %v", err)
+		}
+		var cErr *ConstraintError
+		if !errors.As(err, &cErr) || cErr.Name != "orders_user_id_fkey" {
+			t.Errorf("expected a violation of orders_user_id_fkey, got %v", err)
+		}
+		if _, err := ordersInfo.Table.UpdateTuple(orders[2], []Value{NewInteger(20), NewInteger(3)}); !errors.Is(err, ErrForeignKeyViolation) {
+			t.Errorf("expected ErrForeignKeyViolation, got %v", err)
+		}
+		if _, err := itemsInfo.Table.InsertTuple([]Value{NewInteger(103), NewNull(TypeInteger)}); err != nil {
+			t.Errorf("a NULL foreign key should not be checked: %v", err)
+		}
+	})
+
+	t.Run("restrict", func(t *testing.T) {
+		if err := ordersInfo.Table.DeleteTuple(orders[0]); !errors.Is(err, ErrForeignKeyViolation) {
+			t.Errorf("expected ErrForeignKeyViolation, got %v", err)
+		}
+		// 级联删除的 orders 被 items 引用，所有的表都不变
+		if err := usersInfo.Table.DeleteTuple(users[0]); !errors.Is(err, ErrForeignKeyViolation) {
+			t.Errorf("expected ErrForeignKeyViolation, got %v", err)
+		}
+		if got := tableRows(t, c, "users"); !slices.Equal(got, []string{"[1 alice]", "[2 bob]"}) {
+			t.Errorf("unexpected users %v", got)
+		}
+		if got := tableRows(t, c, "orders"); !slices.Equal(got, []string{"[10 1]", "[11 1]", "[20 2]"}) {
+			t.Errorf("unexpected orders %v", got)
+		}
+	})
+
+	t.Run("update cascade and set null", func(t *testing.T) {
+		if _, err := usersInfo.Table.UpdateTuple(users[1], []Value{NewInteger(3), NewVarchar("bob")}); err != nil {
+			t.Fatal(err)
+		}
+		if got := tableRows(t, c, "orders"); !slices.Equal(got, []string{"[10 1]", "[11 1]", "[20 3]"}) {
+			t.Errorf("unexpected orders %v", got)
+		}
+
+		if _, err := ordersInfo.Table.UpdateTuple(orders[0], []Value{NewInteger(15), NewInteger(1)}); err != nil {
+			t.Fatal(err)
+		}
+		if got := tableRows(t, c, "items"); !slices.Equal(got, []string{"[100 NULL]", "[101 11]", "[102 NULL]", "[103 NULL]"}) {
+			t.Errorf("unexpected items %v", got)
+		}
+		if rids, _ := itemsInfo.Table.Indexes()[0].ScanKey([]Value{NewInteger(10)}); len(rids) != 0 {
+			t.Errorf("expected no index entries for order 10, got %v", rids)
+		}
+
+		// 没有被引用的列可以随意修改
+		if _, err := usersInfo.Table.UpdateTuple(users[0], []Value{NewInteger(1), NewVarchar("alicia")}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("delete cascade", func(t *testing.T) {
+		if err := itemsInfo.Table.DeleteTuple(items[1]); err != nil {
+			t.Fatal(err)
+		}
+		if err := usersInfo.Table.DeleteTuple(users[0]); err != nil {
+			t.Fatal(err)
+		}
+		if got := tableRows(t, c, "orders"); !slices.Equal(got, []string{"[20 3]"}) {
+			t.Errorf("unexpected orders %v", got)
+		}
+		if _, err := itemsInfo.Table.UpdateTuple(items[0], []Value{NewInteger(100), NewInteger(20)}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := itemsInfo.Table.UpdateTuple(items[2], []Value{NewInteger(102), NewInteger(10)}); !errors.Is(err, ErrForeignKeyViolation) {
+			t.Errorf("expected ErrForeignKeyViolation, got %v", err)
+		}
+	})
+}
+
+func TestForeignKey_SelfReference(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 16, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	c, err := NewCatalog(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.CreateTable("employees", &Schema{
+		Columns: []Column{NewColumn("id", IntegerType()), NewColumn("manager_id", IntegerType())},
+		Constraints: []Constraint{
+			PrimaryKey("", "id"),
+			ForeignKey("", []string{"manager_id"}, "employees").WithOnDelete(ActionCascade),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 一行可以引用它自己
+	rids := insertRows(t, c, "employees",
+		[]Value{NewInteger(1), NewInteger(1)},
+		[]Value{NewInteger(2), NewInteger(1)},
+		[]Value{NewInteger(3), NewInteger(2)},
+		[]Value{NewInteger(4), NewNull(TypeInteger)},
+	)
+	if _, err := info.Table.InsertTuple([]Value{NewInteger(5), NewInteger(6)}); !errors.Is(err, ErrForeignKeyViolation) {
+		t.Errorf("expected ErrForeignKeyViolation, got %v", err)
+	}
+
+	if err := info.Table.DeleteTuple(rids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if got := tableRows(t, c, "employees"); !slices.Equal(got, []string{"[1 1]", "[4 NULL]"}) {
+		t.Errorf("unexpected employees %v", got)
+	}
+	if err := info.Table.DeleteTuple(rids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DropTable("employees"); err != nil {
+		t.Errorf("a table referencing only itself can be dropped: %v", err)
+	}
+}
+
+func TestForeignKey_CascadeConstraints(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 16, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	c, err := NewCatalog(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := []struct {
+		name   string
+		schema *Schema
+	}{
+		{"p", &Schema{
+			Columns:     []Column{NewColumn("a", IntegerType()), NewColumn("b", IntegerType()), NewColumn("c", IntegerType())},
+			Constraints: []Constraint{PrimaryKey("", "a", "b"), Unique("p_c_key", "c")},
+		}},
+		{"checked", &Schema{
+			Columns: []Column{NewColumn("a", IntegerType()), NewColumn("b", IntegerType())},
+			Constraints: []Constraint{
+				Check("small", "a < 5"),
+				ForeignKey("", []string{"a", "b"}, "p").WithOnUpdate(ActionCascade),
+			},
+		}},
+		{"unique", &Schema{
+			Columns: []Column{NewColumn("a", IntegerType()), NewColumn("b", IntegerType())},
+			Constraints: []Constraint{
+				Unique("unique_a_key", "a"),
+				ForeignKey("", []string{"a", "b"}, "p").WithOnUpdate(ActionCascade),
+			},
+		}},
+		{"not_null", &Schema{
+			Columns: []Column{NewColumn("c", IntegerType()).WithNotNull()},
+			Constraints: []Constraint{
+				ForeignKey("", []string{"c"}, "p", "c").WithOnUpdate(ActionCascade),
+			},
+		}},
+	}
+	for _, table := range tables {
+		if _, err := c.CreateTable(table.name, table.schema); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parent, _ := c.GetTable("p")
+
+	ps := insertRows(t, c, "p",
+		[]Value{NewInteger(1), NewInteger(1), NewNull(TypeInteger)},
+		[]Value{NewInteger(2), NewInteger(1), NewNull(TypeInteger)},
+		[]Value{NewInteger(3), NewInteger(1), NewInteger(3)},
+	)
+	insertRows(t, c, "checked", []Value{NewInteger(1), NewInteger(1)})
+	insertRows(t, c, "unique", []Value{NewInteger(1), NewInteger(1)}, []Value{NewInteger(2), NewInteger(1)})
+	insertRows(t, c, "not_null", []Value{NewInteger(3)})
+
+	// 级联修改的行违反约束时，所有的表都不变
+	unchanged := func(t *testing.T) {
+		t.Helper()
+		want := map[string][]string{
+			"p":        {"[1 1 NULL]", "[2 1 NULL]", "[3 1 3]"},
+			"checked":  {"[1 1]"},
+			"unique":   {"[1 1]", "[2 1]"},
+			"not_null": {"[3]"},
+		}
+		for table, rows := range want {
+			if got := tableRows(t, c, table); !slices.Equal(got, rows) {
+				t.Errorf("unexpected %s %v", table, got)
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		run     func() error
+		kind    ConstraintKind
+		wantErr error
+	}{
+		{"check", func() error {
+			_, err := parent.Table.UpdateTuple(ps[0], []Value{NewInteger(10), NewInteger(1), NewNull(TypeInteger)})
+			return err
+		}, ConstraintCheck, ErrCheckViolation},
+		{"unique", func() error {
+			// unique 中 (1, 1) 修改为 (2, 2) 后与已有的 (2, 1) 重复
+			_, err := parent.Table.UpdateTuple(ps[0], []Value{NewInteger(2), NewInteger(2), NewNull(TypeInteger)})
+			return err
+		}, ConstraintUnique, ErrDuplicateKey},
+		{"not null", func() error {
+			_, err := parent.Table.UpdateTuple(ps[2], []Value{NewInteger(3), NewInteger(1), NewNull(TypeInteger)})
+			return err
+		}, ConstraintNotNull, ErrNotNullViolation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			var cErr *ConstraintError
+			if !errors.As(err, &cErr) || cErr.Kind != tt.kind {
+				t.Errorf("expected a constraint error of kind %v, got %v", tt.kind, err)
+			}
+			unchanged(t)
+		})
+	}
+}
+
+func TestForeignKey_CascadePendingKeys(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 16, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	c, err := NewCatalog(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.CreateTable("nodes", &Schema{
+		Columns: []Column{NewColumn("id", IntegerType()), NewColumn("next", IntegerType())},
+		Constraints: []Constraint{
+			PrimaryKey("", "id"),
+			Unique("nodes_next_key", "next"),
+			ForeignKey("", []string{"next"}, "nodes").WithOnUpdate(ActionCascade),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rids := insertRows(t, c, "nodes",
+		[]Value{NewInteger(1), NewNull(TypeInteger)},
+		[]Value{NewInteger(2), NewInteger(1)},
+	)
+
+	// 1 更新为引用它自己的 3，2 级联修改后也引用 3；两行都不与表中已有的行重复
+	_, err = info.Table.UpdateTuple(rids[0], []Value{NewInteger(3), NewInteger(3)})
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, got %v", err)
+	}
+	if got := tableRows(t, c, "nodes"); !slices.Equal(got, []string{"[1 NULL]", "[2 1]"}) {
+		t.Errorf("unexpected nodes %v", got)
+	}
+
+	if _, err := info.Table.UpdateTuple(rids[0], []Value{NewInteger(3), NewNull(TypeInteger)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := tableRows(t, c, "nodes"); !slices.Equal(got, []string{"[2 3]", "[3 NULL]"}) {
+		t.Errorf("unexpected nodes %v", got)
+	}
+}
+
+func TestForeignKey_Catalog(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 16, PageSize, 2)
+	c := newForeignKeyCatalog(t, bm)
+	insertRows(t, c, "users", []Value{NewInteger(1), NewVarchar("alice")})
+	insertRows(t, c, "orders", []Value{NewInteger(10), NewInteger(1)})
+
+	orders, err := c.GetTable("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := orders.Schema.String(), "(id INTEGER, user_id INTEGER, CONSTRAINT orders_pkey PRIMARY KEY (id), "+
+		"CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE)"; got != want {
+		t.Errorf("expected schema %s, got %s", want, got)
+	}
+	indexes, err := c.GetIndexes("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 2 || indexes[1].Name != "orders_user_id_fkey" || indexes[1].Index.Unique {
+		t.Errorf("expected a non unique index for the foreign key, got %v", indexes)
+	}
+
+	invalid := []struct {
+		name   string
+		schema *Schema
+		target error
+	}{
+		{"missing table", &Schema{
+			Columns:     []Column{NewColumn("a", IntegerType())},
+			Constraints: []Constraint{ForeignKey("", []string{"a"}, "missing")},
+		}, ErrTableNotFound},
+		{"no unique index", &Schema{
+			Columns:     []Column{NewColumn("a", VarcharType(20))},
+			Constraints: []Constraint{ForeignKey("", []string{"a"}, "users", "name")},
+		}, ErrInvalidSchema},
+		{"type mismatch", &Schema{
+			Columns:     []Column{NewColumn("a", BigIntType())},
+			Constraints: []Constraint{ForeignKey("", []string{"a"}, "users")},
+		}, ErrInvalidSchema},
+		{"no primary key", &Schema{
+			Columns:     []Column{NewColumn("a", IntegerType())},
+			Constraints: []Constraint{ForeignKey("", []string{"a"}, "items")},
+		}, ErrInvalidSchema},
+		{"set null on not null", &Schema{
+			Columns:     []Column{NewColumn("a", IntegerType()).WithNotNull()},
+			Constraints: []Constraint{ForeignKey("", []string{"a"}, "users").WithOnDelete(ActionSetNull)},
+		}, ErrInvalidSchema},
+		{"column count", &Schema{
+			Columns:     []Column{NewColumn("a", IntegerType())},
+			Constraints: []Constraint{ForeignKey("", []string{"a"}, "users", "id", "name")},
+		}, ErrInvalidSchema},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := c.CreateTable("bad", tc.schema); !errors.Is(err, tc.target) {
+				t.Errorf("expected %v, got %v", tc.target, err)
+			}
+		})
+	}
+	if _, err := c.GetTable("bad"); !errors.Is(err, ErrTableNotFound) {
+		t.Errorf("expected no table to be created, got %v", err)
+	}
+
+	if err := c.DropTable("users"); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema when dropping a referenced table, got %v", err)
+	}
+	if _, err := c.RenameColumn("users", "id", "user_id"); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema when renaming a referenced column, got %v", err)
+	}
+	if _, err := c.DropColumn("orders", "user_id"); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema when dropping a foreign key column, got %v", err)
+	}
+	// 引用方的列改名后外键仍然有效
+	if _, err := c.RenameColumn("orders", "user_id", "owner_id"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bm.ShutDown(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewBufferPoolManager(dm, 16, PageSize, 2)
+	defer restarted.AssertNoPinLeaks(t)
+	if c, err = NewCatalog(restarted); err != nil {
+		t.Fatal(err)
+	}
+	orders, err = c.GetTable("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := orders.Schema.String(), "(id INTEGER, owner_id INTEGER, CONSTRAINT orders_pkey PRIMARY KEY (id), "+
+		"CONSTRAINT orders_user_id_fkey FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE)"; got != want {
+		t.Errorf("expected schema %s, got %s", want, got)
+	}
+	if _, err := orders.Table.InsertTuple([]Value{NewInteger(11), NewInteger(2)}); !errors.Is(err, ErrForeignKeyViolation) {
+		t.Errorf("expected ErrForeignKeyViolation after a restart, got %v", err)
+	}
+	users, err := c.GetTable("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rids, err := users.Table.Indexes()[0].ScanKey([]Value{NewInteger(1)})
+	if err != nil || len(rids) != 1 {
+		t.Fatalf("unexpected rids %v, %v", rids, err)
+	}
+	if err := users.Table.DeleteTuple(rids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := tableRows(t, c, "orders"); len(got) != 0 {
+		t.Errorf("expected the orders to be deleted, got %v", got)
+	}
+
+	if err := c.DropTable("items"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DropTable("orders"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DropTable("users"); err != nil {
+		t.Errorf("expected users to be droppable once nothing references it, got %v", err)
+	}
+}
+
+func TestForeignKey_Concurrent(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 32, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	c := newForeignKeyCatalog(t, bm)
+	users, _ := c.GetTable("users")
+	orders, _ := c.GetTable("orders")
+
+	const n = 50
+	userRIDs := make([]RID, n)
+	for i := range n {
+		userRIDs[i] = insertRows(t, c, "users", []Value{NewInteger(int32(i)), NewVarchar("u")})[0]
+	}
+
+	// 一边删除用户，一边插入引用它们的订单，结束后不应有引用不存在的用户的订单
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range n {
+			if err := users.Table.DeleteTuple(userRIDs[i]); err != nil {
+				errs <- err
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := range n {
+			_, err := orders.Table.InsertTuple([]Value{NewInteger(int32(i)), NewInteger(int32(i))})
+			if err != nil && !errors.Is(err, ErrForeignKeyViolation) {
+				errs <- err
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if got := tableRows(t, c, "orders"); len(got) != 0 {
+		t.Errorf("expected no orphaned orders, got %v", got)
+	}
+}
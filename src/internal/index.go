@@ -0,0 +1,298 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 索引键编码：
+//
+//	| 列 1 | 列 2 | ... |
+//
+// 每列以一个标记字节开头，NULL 为 0x00，非 NULL 为 0x01 后跟按字节序可比较的编码：
+// 整数类为符号位取反的 8 字节大端序，DOUBLE 为调整后的 IEEE 754 位，
+// 字符串类中的 0x00 转义为 0x00 0xFF 并以 0x00 0x01 结尾
+// 每列的编码都是自定界的，因此一个键的编码不会是另一个不同键的编码的前缀
+//
+// 唯一索引中不含 NULL 的键直接作为 DiskTrie 的键，其余情况在键后追加 RID，
+// 多个 NULL 因此不会互相冲突
+const (
+	indexKeyNull    = 0x00
+	indexKeyNotNull = 0x01
+	indexRIDSize    = 6
+)
+
+// Index 表上的索引，把索引列的值映射到元组的 RID，存放在 DiskTrie 中
+// 写入由 Table 串行化，Table 在修改堆之前检查唯一性，InsertEntry 只兜底拒绝完全相同的键
+type Index struct {
+	Name   string
+	Unique bool
+
+	// keyAttrs 索引列在表 Schema 中的下标，keyIDs 为这些列的 ID
+	// ALTER TABLE 之后通过 rebind 按 ID 重新计算下标
+	keyAttrs []int
+	keyIDs   []int
+	keyTypes []Type
+	trie     *DiskTrie
+}
+
+// NewIndex 在 schema 的 keyAttrs 列上创建一个空索引
+func NewIndex(bpm *BufferPoolManager, name string, schema *Schema, keyAttrs []int, unique bool) (*Index, error) {
+	idx, err := newIndex(name, schema, keyAttrs, unique)
+	if err != nil {
+		return nil, err
+	}
+
+	if idx.trie, err = NewDiskTrie(bpm); err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+// OpenIndex 打开元数据页为 metaPageID 的已有索引
+func OpenIndex(bpm *BufferPoolManager, name string, schema *Schema, keyAttrs []int, unique bool, metaPageID int) (*Index, error) {
+	idx, err := newIndex(name, schema, keyAttrs, unique)
+	if err != nil {
+		return nil, err
+	}
+
+	if idx.trie, err = OpenDiskTrie(bpm, metaPageID); err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+func newIndex(name string, schema *Schema, keyAttrs []int, unique bool) (*Index, error) {
+	if len(keyAttrs) == 0 {
+		return nil, fmt.Errorf("%w: index %s has no columns", ErrInvalidSchema, name)
+	}
+
+	seen := make(map[int]struct{}, len(keyAttrs))
+	keyIDs := make([]int, len(keyAttrs))
+	keyTypes := make([]Type, len(keyAttrs))
+	for i, attr := range keyAttrs {
+		if attr < 0 || attr >= len(schema.Columns) {
+			return nil, fmt.Errorf("%w: index %s column %d out of range", ErrInvalidSchema, name, attr)
+		}
+		if _, ok := seen[attr]; ok {
+			return nil, fmt.Errorf("%w: index %s has duplicate column %s", ErrInvalidSchema, name, schema.Columns[attr].Name)
+		}
+		seen[attr] = struct{}{}
+		keyIDs[i] = schema.Columns[attr].ID
+		keyTypes[i] = schema.Columns[attr].Type
+	}
+
+	return &Index{
+		Name:     name,
+		Unique:   unique,
+		keyAttrs: keyAttrs,
+		keyIDs:   keyIDs,
+		keyTypes: keyTypes,
+	}, nil
+}
+
+// rebind 按列 ID 计算索引列在新 Schema 中的下标，索引列被删除时返回 ErrInvalidSchema
+func (idx *Index) rebind(schema *Schema) ([]int, error) {
+	keyAttrs := make([]int, len(idx.keyIDs))
+	for i, id := range idx.keyIDs {
+		if keyAttrs[i] = schema.ColumnByID(id); keyAttrs[i] < 0 {
+			return nil, fmt.Errorf("%w: column %d is used by index %s", ErrInvalidSchema, id, idx.Name)
+		}
+	}
+
+	return keyAttrs, nil
+}
+
+// KeyAttrs 索引列在表 Schema 中的下标
+func (idx *Index) KeyAttrs() []int {
+	return idx.keyAttrs
+}
+
+// MetaPageID 底层 DiskTrie 的元数据页，用于 OpenIndex
+func (idx *Index) MetaPageID() int {
+	return idx.trie.MetaPageID()
+}
+
+// KeyOf 从表中的一行取出索引列的值
+func (idx *Index) KeyOf(row []Value) []Value {
+	key := make([]Value, len(idx.keyAttrs))
+	for i, attr := range idx.keyAttrs {
+		key[i] = row[attr]
+	}
+
+	return key
+}
+
+// encodeKey 将索引列的值转换为列的类型后编码，返回编码和是否含 NULL
+func (idx *Index) encodeKey(key []Value) (string, bool, error) {
+	if len(key) != len(idx.keyTypes) {
+		return "", false, fmt.Errorf("%w: index %s expects %d values, got %d", ErrTypeMismatch, idx.Name, len(idx.keyTypes), len(key))
+	}
+
+	var buf []byte
+	hasNull := false
+	for i, v := range key {
+		if v.IsNull() {
+			buf = append(buf, indexKeyNull)
+			hasNull = true
+			continue
+		}
+
+		v, err := v.CastAs(idx.keyTypes[i])
+		if err != nil {
+			return "", false, fmt.Errorf("index %s: %w", idx.Name, err)
+		}
+		buf = appendIndexKey(append(buf, indexKeyNotNull), v)
+	}
+
+	return string(buf), hasNull, nil
+}
+
+// entryKey 返回一行在 DiskTrie 中的键
+func (idx *Index) entryKey(row []Value, rid RID) (string, error) {
+	key, hasNull, err := idx.encodeKey(idx.KeyOf(row))
+	if err != nil {
+		return "", err
+	}
+	if idx.Unique && !hasNull {
+		return key, nil
+	}
+
+	return key + string(encodeIndexRID(rid)), nil
+}
+
+// InsertEntry 为 rid 处的一行添加索引项，唯一索引中键已存在时返回 ErrDuplicateKey
+func (idx *Index) InsertEntry(row []Value, rid RID) error {
+	key, err := idx.entryKey(row, rid)
+	if err != nil {
+		return err
+	}
+
+	err = idx.trie.Put(key, encodeIndexRID(rid))
+	if errors.Is(err, ErrKeyExists) {
+		return fmt.Errorf("%w: index %s", ErrDuplicateKey, idx.Name)
+	}
+	return err
+}
+
+// DeleteEntry 删除 rid 处的一行的索引项
+func (idx *Index) DeleteEntry(row []Value, rid RID) error {
+	key, err := idx.entryKey(row, rid)
+	if err != nil {
+		return err
+	}
+
+	return idx.trie.Delete(key)
+}
+
+// ScanKey 返回索引列等于 key 的所有行的 RID
+func (idx *Index) ScanKey(key []Value) ([]RID, error) {
+	prefix, _, err := idx.encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var rids []RID
+	err = idx.trie.WalkPrefix(prefix, func(_ string, val []byte) bool {
+		rids = append(rids, decodeIndexRID(val))
+		return true
+	})
+
+	return rids, err
+}
+
+// keyChanged 返回两行的索引键是否不同
+func (idx *Index) keyChanged(oldRow, newRow []Value) (bool, error) {
+	oldKey, _, err := idx.encodeKey(idx.KeyOf(oldRow))
+	if err != nil {
+		return false, err
+	}
+	newKey, _, err := idx.encodeKey(idx.KeyOf(newRow))
+	if err != nil {
+		return false, err
+	}
+
+	return oldKey != newKey, nil
+}
+
+// checkUnique 检查唯一索引中除 self 以外是否已有与 row 相同的键，含 NULL 的键不冲突
+func (idx *Index) checkUnique(row []Value, self RID) error {
+	if !idx.Unique {
+		return nil
+	}
+
+	key := idx.KeyOf(row)
+	for _, v := range key {
+		if v.IsNull() {
+			return nil
+		}
+	}
+
+	rids, err := idx.ScanKey(key)
+	if err != nil {
+		return err
+	}
+	for _, rid := range rids {
+		if rid != self {
+			return fmt.Errorf("%w: index %s, key %s", ErrDuplicateKey, idx.Name, formatIndexKey(key))
+		}
+	}
+
+	return nil
+}
+
+func formatIndexKey(key []Value) string {
+	parts := make([]string, len(key))
+	for i, v := range key {
+		parts[i] = v.String()
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// appendIndexKey 追加非 NULL 值按字节序可比较的编码
+func appendIndexKey(buf []byte, v Value) []byte {
+	switch v.TypeID() {
+	case TypeBoolean, TypeTinyInt, TypeSmallInt, TypeInteger, TypeBigInt, TypeDecimal, TypeDate, TypeTimestamp:
+		// 同一列的 DECIMAL 小数位数相同，直接比较未缩放的值
+		return binary.BigEndian.AppendUint64(buf, uint64(v.i)^1<<63)
+	case TypeDouble:
+		bits := math.Float64bits(v.f)
+		if v.f == 0 {
+			// -0 与 0 相等
+			bits = 0
+		}
+		if bits&(1<<63) != 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		return binary.BigEndian.AppendUint64(buf, bits)
+	}
+
+	for i := 0; i < len(v.s); i++ {
+		if v.s[i] == 0x00 {
+			buf = append(buf, 0x00, 0xFF)
+			continue
+		}
+		buf = append(buf, v.s[i])
+	}
+	return append(buf, 0x00, 0x01)
+}
+
+func encodeIndexRID(rid RID) []byte {
+	buf := binary.BigEndian.AppendUint32(make([]byte, 0, indexRIDSize), uint32(rid.PageID))
+	return binary.BigEndian.AppendUint16(buf, uint16(rid.Slot))
+}
+
+func decodeIndexRID(data []byte) RID {
+	return RID{
+		PageID: int(int32(binary.BigEndian.Uint32(data))),
+		Slot:   int(binary.BigEndian.Uint16(data[4:])),
+	}
+}
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIndex_KeyOrder(t *testing.T) {
+	cases := []struct {
+		name   string
+		typ    Type
+		values []Value
+	}{
+		{"integer", IntegerType(), []Value{NewInteger(math.MinInt32), NewInteger(-1), NewInteger(0), NewInteger(1), NewInteger(math.MaxInt32)}},
+		{"double", DoubleType(), []Value{NewDouble(math.Inf(-1)), NewDouble(-2.5), NewDouble(-0.5), NewDouble(0), NewDouble(0.5), NewDouble(3)}},
+		{"decimal", DecimalType(10, 2), []Value{mustDecimal(-1000, 2), mustDecimal(-1, 2), mustDecimal(0, 2), mustDecimal(250, 2)}},
+		{"varchar", VarcharType(10), []Value{NewVarchar(""), NewVarchar("\x00"), NewVarchar("\x00\x00"), NewVarchar("a"), NewVarchar("a\x00"), NewVarchar("ab"), NewVarchar("b")}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			schema, err := NewSchema(NewColumn("k", c.typ))
+			if err != nil {
+				t.Fatal(err)
+			}
+			idx, err := newIndex("idx", schema, []int{0}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			keys := make([]string, len(c.values))
+			for i, v := range c.values {
+				if keys[i], _, err = idx.encodeKey([]Value{v}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !slices.IsSorted(keys) {
+				t.Errorf("encoded keys are not in value order: %q", keys)
+			}
+			for i := 1; i < len(keys); i++ {
+				if strings.HasPrefix(keys[i], keys[i-1]) {
+					t.Errorf("key %q is a prefix of %q", keys[i-1], keys[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	schema, err := NewSchema(
+		NewColumn("id", IntegerType()),
+		NewColumn("name", VarcharType(20)),
+		NewColumn("city", VarcharType(20)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := func(id int32, name string, city Value) []Value {
+		return []Value{NewInteger(id), NewVarchar(name), city}
+	}
+
+	t.Run("non unique", func(t *testing.T) {
+		idx, err := NewIndex(bm, "by_city", schema, []int{2}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rids := []RID{{1, 0}, {1, 1}, {2, 0}}
+		if err := idx.InsertEntry(row(1, "a", NewVarchar("paris")), rids[0]); err != nil {
+			t.Fatal(err)
+		}
+		if err := idx.InsertEntry(row(2, "b", NewVarchar("paris")), rids[1]); err != nil {
+			t.Fatal(err)
+		}
+		if err := idx.InsertEntry(row(3, "c", NewVarchar("par")), rids[2]); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := idx.ScanKey([]Value{NewVarchar("paris")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, rids[:2]) {
+			t.Errorf("expected %v, got %v", rids[:2], got)
+		}
+
+		if err := idx.DeleteEntry(row(1, "a", NewVarchar("paris")), rids[0]); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := idx.ScanKey([]Value{NewVarchar("paris")}); !slices.Equal(got, rids[1:2]) {
+			t.Errorf("expected %v, got %v", rids[1:2], got)
+		}
+	})
+
+	t.Run("unique", func(t *testing.T) {
+		idx, err := NewIndex(bm, "by_name", schema, []int{1, 2}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := idx.InsertEntry(row(1, "a", NewVarchar("x")), RID{1, 0}); err != nil {
+			t.Fatal(err)
+		}
+		if err := idx.checkUnique(row(2, "a", NewVarchar("x")), RID{1, 1}); !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("expected ErrDuplicateKey, got %v", err)
+		}
+		if err := idx.checkUnique(row(1, "a", NewVarchar("x")), RID{1, 0}); err != nil {
+			t.Errorf("a row should not conflict with itself: %v", err)
+		}
+		if err := idx.InsertEntry(row(2, "a", NewVarchar("x")), RID{1, 1}); !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("expected ErrDuplicateKey, got %v", err)
+		}
+
+		// NULL 不与任何键冲突
+		for i := 0; i < 2; i++ {
+			r := row(int32(i), "a", NewNull(TypeVarchar))
+			if err := idx.checkUnique(r, RID{2, i}); err != nil {
+				t.Fatal(err)
+			}
+			if err := idx.InsertEntry(r, RID{2, i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := idx.ScanKey([]Value{NewVarchar("a"), NewNull(TypeVarchar)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 {
+			t.Errorf("expected 2 rows with a NULL key, got %v", got)
+		}
+
+		reopened, err := OpenIndex(bm, "by_name", schema, []int{1, 2}, true, idx.MetaPageID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := reopened.ScanKey([]Value{NewVarchar("a"), NewVarchar("x")}); !slices.Equal(got, []RID{{1, 0}}) {
+			t.Errorf("expected [(1, 0)], got %v", got)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := NewIndex(bm, "bad", schema, nil, false); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("expected ErrInvalidSchema, got %v", err)
+		}
+		if _, err := NewIndex(bm, "bad", schema, []int{3}, false); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("expected ErrInvalidSchema, got %v", err)
+		}
+		if _, err := NewIndex(bm, "bad", schema, []int{0, 0}, false); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("expected ErrInvalidSchema, got %v", err)
+		}
+	})
+}
@@ -6,11 +6,26 @@ import (
 	"time"
 )
 
+// AccessType tells the replacer how a page is accessed
+type AccessType int
+
+const (
+	AccessUnknown AccessType = iota
+	AccessLookup
+	// AccessScan is a sequential scan, it must not push hot pages out
+	AccessScan
+	AccessIndex
+)
+
 type LRUKNode struct {
 	history     *list.List
 	k           int
 	frameId     int
 	isEvictable bool
+
+	// scanOnly is set while the frame has only been accessed by scans,
+	// such frames are evicted before any other
+	scanOnly bool
 }
 
 func (l *LRUKNode) SetEvictable(setEvictable bool) {
@@ -53,16 +68,30 @@ func (lru *Replacer) Evict(frameId int) (int, error) {
 	lru.mu.Lock()
 	defer lru.mu.Unlock()
 
-	earliestTime := time.Now().Unix()
+	maxDistance := -1
+	earliestTime := 0
 	earliestFrameId := -1
 
-	// find the frame with largest kth backward distance
+	// find the frame with largest kth backward distance,
+	// frames with less than k accesses have +inf distance,
+	// frames only touched by scans go even before them,
+	// ties are broken by the earliest recorded access
 	for _, node := range lru.nodeStore {
-		if node.history.Len() < lru.k {
+		if !node.isEvictable {
 			continue
 		}
-		kthBackwardTime := node.history.Front().Value.(int64)
-		if node.isEvictable && kthBackwardTime < earliestTime {
+
+		kthBackwardTime := node.history.Front().Value.(int)
+		distance := inf
+		switch {
+		case node.scanOnly:
+			distance = inf + 1
+		case node.history.Len() >= lru.k:
+			distance = lru.timeStamp - kthBackwardTime
+		}
+
+		if distance > maxDistance || (distance == maxDistance && kthBackwardTime < earliestTime) {
+			maxDistance = distance
 			earliestTime = kthBackwardTime
 			earliestFrameId = node.frameId
 		}
@@ -78,7 +107,10 @@ func (lru *Replacer) Evict(frameId int) (int, error) {
 	return earliestFrameId, nil
 }
 
-func (lru *Replacer) RecordAccess(frameId int, accessType int) error {
+// RecordAccess records an access to the frame at the current timestamp.
+// A scan access only counts for a frame not seen before, so scanning
+// a table neither builds up history nor refreshes the hot set
+func (lru *Replacer) RecordAccess(frameId int, accessType AccessType) error {
 	// error handle
 	switch {
 	case lru.nodeStore == nil:
@@ -89,8 +121,8 @@ func (lru *Replacer) RecordAccess(frameId int, accessType int) error {
 		// frameId should not be greater than replacerSize
 
 		return ErrInvalidFrameId
-	case accessType < 0 || accessType > 3:
-		// accessType should be in [0,3], but it's not used here anyway
+	case accessType < AccessUnknown || accessType > AccessIndex:
+		// accessType should be in [0,3]
 
 		return ErrUnknownAccessType
 	default:
@@ -100,13 +132,22 @@ func (lru *Replacer) RecordAccess(frameId int, accessType int) error {
 	defer lru.mu.Unlock()
 
 	// if this frame is not seen.
-	if _, ok := lru.nodeStore[frameId]; !ok {
-		lru.nodeStore[frameId] = &LRUKNode{
+	node, ok := lru.nodeStore[frameId]
+	if !ok {
+		node = &LRUKNode{
 			history:     list.New(),
 			k:           lru.k,
 			frameId:     frameId,
 			isEvictable: false,
+			scanOnly:    accessType == AccessScan,
 		}
+		lru.nodeStore[frameId] = node
+		lru.curSize++
+	} else if accessType == AccessScan {
+		return nil
+	}
+	if accessType != AccessScan {
+		node.scanOnly = false
 	}
 
 	history := lru.nodeStore[frameId].history
@@ -115,8 +156,9 @@ func (lru *Replacer) RecordAccess(frameId int, accessType int) error {
 		_ = history.Remove(lru.nodeStore[frameId].history.Front())
 	}
 
-	_ = history.PushBack(time.Now().Unix())
-	lru.curSize++
+	// timeStamp works as a logical clock so that accesses are strictly ordered
+	lru.timeStamp++
+	_ = history.PushBack(lru.timeStamp)
 
 	return nil
 }
@@ -174,6 +216,100 @@ func (lru *Replacer) Remove(frameId int) error {
 	return nil
 }
 
+// Size returns the number of frames tracked by the replacer
 func (lru *Replacer) Size() int {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
 	return lru.curSize
 }
+
+// Resize changes the number of frames the replacer can track,
+// shrinking fails if a frame beyond the new size is still tracked
+func (lru *Replacer) Resize(numFrames int) error {
+	if lru.nodeStore == nil {
+		return ErrUnInitialized
+	}
+
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	for frameId := range lru.nodeStore {
+		if frameId >= numFrames {
+			return ErrInvalidFrameId
+		}
+	}
+
+	lru.replacerSize = numFrames
+	return nil
+}
+
+// move transfers the access history of frame from to frame to,
+// used by the buffer pool when it compacts frames on shrink
+func (lru *Replacer) move(from, to int) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	node, ok := lru.nodeStore[from]
+	if !ok {
+		return
+	}
+
+	delete(lru.nodeStore, from)
+	node.frameId = to
+	lru.nodeStore[to] = node
+}
+
+// ages returns how many ticks ago each recorded access of the frame happened,
+// oldest first, so that the history can be restored under a different clock
+func (lru *Replacer) ages(frameId int) []int {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	node, ok := lru.nodeStore[frameId]
+	if !ok {
+		return nil
+	}
+
+	ages := make([]int, 0, node.history.Len())
+	for e := node.history.Front(); e != nil; e = e.Next() {
+		ages = append(ages, lru.timeStamp-e.Value.(int))
+	}
+
+	return ages
+}
+
+// restore tracks the frame with the access history produced by ages
+func (lru *Replacer) restore(frameId int, ages []int, evictable bool) error {
+	if frameId < 0 || frameId >= lru.replacerSize {
+		return ErrInvalidFrameId
+	}
+
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	if _, ok := lru.nodeStore[frameId]; !ok {
+		lru.curSize++
+	}
+
+	node := &LRUKNode{
+		history:     list.New(),
+		k:           lru.k,
+		frameId:     frameId,
+		isEvictable: evictable,
+	}
+	// keep at most the latest k accesses
+	if len(ages) > lru.k {
+		ages = ages[len(ages)-lru.k:]
+	}
+	for _, age := range ages {
+		node.history.PushBack(lru.timeStamp - age)
+	}
+	if node.history.Len() == 0 {
+		lru.timeStamp++
+		node.history.PushBack(lru.timeStamp)
+	}
+	lru.nodeStore[frameId] = node
+
+	return nil
+}
@@ -88,6 +88,33 @@ func TestLRUKReplacer_Evict(t *testing.T) {
 			t.Error(err)
 		}
 	})
+
+	t.Run("less than k accesses", func(t *testing.T) {
+		lruKReplacer := NewReplacer(numFrames, k)
+
+		// frame 0 has k accesses, frames 1 and 2 only one,
+		// so frame 1 has +inf distance and the earliest access
+		for _, frameId := range []int{0, 1, 0, 2, 0} {
+			if err := lruKReplacer.RecordAccess(frameId, 0); err != nil {
+				t.Error(err)
+			}
+		}
+		for frameId := 0; frameId < 3; frameId++ {
+			if err := lruKReplacer.SetEvictable(frameId, true); err != nil {
+				t.Error(err)
+			}
+		}
+
+		for _, expected := range []int{1, 2, 0} {
+			frameId, err := lruKReplacer.Evict(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if frameId != expected {
+				t.Errorf("expected frame %d to be evicted, got %d", expected, frameId)
+			}
+		}
+	})
 }
 
 func TestLRUKReplacer_RecordAccess(t *testing.T) {
@@ -247,3 +274,90 @@ func TestLRUKReplacer_Size(t *testing.T) {
 		t.Error("lruKReplacer.Size() should be 1")
 	}
 }
+
+func TestLRUKReplacer_Resize(t *testing.T) {
+	numFrames, k := 5, 3
+
+	t.Run("uninitialized", func(t *testing.T) {
+		lruKReplacer := new(Replacer)
+		err := lruKReplacer.Resize(1)
+		if !errors.Is(err, ErrUnInitialized) {
+			t.Error("should return ErrUnInitialized")
+		}
+	})
+
+	t.Run("tracked frame out of range", func(t *testing.T) {
+		lruKReplacer := NewReplacer(numFrames, k)
+
+		err := lruKReplacer.RecordAccess(3, 0)
+		if err != nil {
+			t.Error(err)
+		}
+
+		err = lruKReplacer.Resize(3)
+		if !errors.Is(err, ErrInvalidFrameId) {
+			t.Error("should return ErrInvalidFrameId")
+		}
+	})
+
+	t.Run("normal case", func(t *testing.T) {
+		lruKReplacer := NewReplacer(numFrames, k)
+
+		err := lruKReplacer.Resize(numFrames * 2)
+		if err != nil {
+			t.Error(err)
+		}
+
+		err = lruKReplacer.RecordAccess(numFrames, 0)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestLRUKReplacer_ScanAccess(t *testing.T) {
+	numFrames, k := 5, 2
+
+	lruKReplacer := NewReplacer(numFrames, k)
+
+	// frame 0 has a single lookup, frames 1 and 2 are scanned repeatedly
+	if err := lruKReplacer.RecordAccess(0, AccessLookup); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < 3; i++ {
+		for frameId := 1; frameId <= 2; frameId++ {
+			if err := lruKReplacer.RecordAccess(frameId, AccessScan); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	for frameId := 0; frameId <= 2; frameId++ {
+		if err := lruKReplacer.SetEvictable(frameId, true); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// scanned frames go first, in scan order, even with more accesses
+	for _, expected := range []int{1, 2, 0} {
+		frameId, err := lruKReplacer.Evict(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frameId != expected {
+			t.Errorf("expected frame %d, got %d", expected, frameId)
+		}
+	}
+
+	t.Run("lookup after scan", func(t *testing.T) {
+		lruKReplacer := NewReplacer(numFrames, k)
+		lruKReplacer.RecordAccess(0, AccessScan)
+		lruKReplacer.RecordAccess(1, AccessScan)
+		lruKReplacer.RecordAccess(0, AccessLookup)
+		lruKReplacer.SetEvictable(0, true)
+		lruKReplacer.SetEvictable(1, true)
+
+		if frameId, _ := lruKReplacer.Evict(0); frameId != 1 {
+			t.Errorf("expected frame 1, got %d", frameId)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets I/O 延迟直方图的桶上界，单位秒
+var latencyBuckets = [...]float64{0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+
+// Histogram 固定桶的延迟直方图，零值可用，可并发写入
+type Histogram struct {
+	// counts 最后一个桶对应 +Inf
+	counts [len(latencyBuckets) + 1]atomic.Int64
+	sumNs  atomic.Int64
+}
+
+// Observe 记录一次耗时
+func (h *Histogram) Observe(d time.Duration) {
+	seconds := d.Seconds()
+	i := 0
+	for i < len(latencyBuckets) && seconds > latencyBuckets[i] {
+		i++
+	}
+
+	h.counts[i].Add(1)
+	h.sumNs.Add(int64(d))
+}
+
+// HistogramSnapshot 直方图某一时刻的快照，Buckets 为累计计数
+type HistogramSnapshot struct {
+	Bounds  []float64
+	Buckets []int64
+	Count   int64
+	Sum     float64
+}
+
+// Snapshot 返回直方图的快照
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		Bounds:  latencyBuckets[:],
+		Buckets: make([]int64, len(latencyBuckets)),
+	}
+
+	var cumulative int64
+	for i := range latencyBuckets {
+		cumulative += h.counts[i].Load()
+		s.Buckets[i] = cumulative
+	}
+	s.Count = cumulative + h.counts[len(latencyBuckets)].Load()
+	s.Sum = time.Duration(h.sumNs.Load()).Seconds()
+
+	return s
+}
+
+// DiskStats 磁盘读写统计，零值可用
+type DiskStats struct {
+	Reads        atomic.Int64
+	Writes       atomic.Int64
+	BytesRead    atomic.Int64
+	BytesWritten atomic.Int64
+	ReadLatency  Histogram
+	WriteLatency Histogram
+}
+
+// BufferPoolStats 缓冲池某一时刻的统计
+type BufferPoolStats struct {
+	Hits         int64
+	Misses       int64
+	Evictions    int64
+	PoolSize     int
+	DirtyPages   int
+	PinnedPages  int
+	ReplacerSize int
+
+	SecondaryCacheHits   int64
+	SecondaryCacheMisses int64
+	SecondaryCachePages  int
+	SecondaryCacheBytes  int
+
+	DiskReads        int64
+	DiskWrites       int64
+	DiskBytesRead    int64
+	DiskBytesWritten int64
+	DiskReadLatency  HistogramSnapshot
+	DiskWriteLatency HistogramSnapshot
+}
+
+// Stats 返回缓冲池和磁盘的统计
+func (m *BufferPoolManager) Stats() BufferPoolStats {
+	m.mu.Lock()
+	stats := BufferPoolStats{
+		Hits:         m.hits.Load(),
+		Misses:       m.misses.Load(),
+		Evictions:    m.evictions.Load(),
+		PoolSize:     m.PoolSize,
+		ReplacerSize: m.Replacer.Size(),
+	}
+	for _, p := range m.PageTable {
+		if p.IsDirty {
+			stats.DirtyPages++
+		}
+		if p.PinCount > 0 {
+			stats.PinnedPages++
+		}
+	}
+	cache := m.SecondaryCache
+	m.mu.Unlock()
+
+	if cache != nil {
+		stats.SecondaryCacheHits = cache.hits.Load()
+		stats.SecondaryCacheMisses = cache.misses.Load()
+		stats.SecondaryCachePages = cache.Len()
+		stats.SecondaryCacheBytes = cache.Size()
+	}
+
+	dm := m.DiskManager
+	stats.DiskReads = dm.Stats.Reads.Load()
+	stats.DiskWrites = dm.Stats.Writes.Load()
+	stats.DiskBytesRead = dm.Stats.BytesRead.Load()
+	stats.DiskBytesWritten = dm.Stats.BytesWritten.Load()
+	stats.DiskReadLatency = dm.Stats.ReadLatency.Snapshot()
+	stats.DiskWriteLatency = dm.Stats.WriteLatency.Snapshot()
+
+	return stats
+}
+
+// ExpvarMap 返回一个 expvar.Map，每次读取时重新计算统计
+// 需要调用方自行 expvar.Publish，或者使用 PublishExpvar
+func (m *BufferPoolManager) ExpvarMap() *expvar.Map {
+	stat := func(f func(s BufferPoolStats) any) expvar.Func {
+		return func() any {
+			return f(m.Stats())
+		}
+	}
+
+	vars := new(expvar.Map).Init()
+	vars.Set("hits", stat(func(s BufferPoolStats) any { return s.Hits }))
+	vars.Set("misses", stat(func(s BufferPoolStats) any { return s.Misses }))
+	vars.Set("evictions", stat(func(s BufferPoolStats) any { return s.Evictions }))
+	vars.Set("pool_size", stat(func(s BufferPoolStats) any { return s.PoolSize }))
+	vars.Set("dirty_pages", stat(func(s BufferPoolStats) any { return s.DirtyPages }))
+	vars.Set("pinned_pages", stat(func(s BufferPoolStats) any { return s.PinnedPages }))
+	vars.Set("replacer_size", stat(func(s BufferPoolStats) any { return s.ReplacerSize }))
+	vars.Set("secondary_cache_hits", stat(func(s BufferPoolStats) any { return s.SecondaryCacheHits }))
+	vars.Set("secondary_cache_misses", stat(func(s BufferPoolStats) any { return s.SecondaryCacheMisses }))
+	vars.Set("secondary_cache_pages", stat(func(s BufferPoolStats) any { return s.SecondaryCachePages }))
+	vars.Set("secondary_cache_bytes", stat(func(s BufferPoolStats) any { return s.SecondaryCacheBytes }))
+	vars.Set("disk_reads", stat(func(s BufferPoolStats) any { return s.DiskReads }))
+	vars.Set("disk_writes", stat(func(s BufferPoolStats) any { return s.DiskWrites }))
+	vars.Set("disk_bytes_read", stat(func(s BufferPoolStats) any { return s.DiskBytesRead }))
+	vars.Set("disk_bytes_written", stat(func(s BufferPoolStats) any { return s.DiskBytesWritten }))
+	vars.Set("disk_read_latency", stat(func(s BufferPoolStats) any { return s.DiskReadLatency }))
+	vars.Set("disk_write_latency", stat(func(s BufferPoolStats) any { return s.DiskWriteLatency }))
+
+	return vars
+}
+
+// PublishExpvar 以 name 发布缓冲池统计，name 重复时 expvar 会 panic
+func (m *BufferPoolManager) PublishExpvar(name string) *expvar.Map {
+	vars := m.ExpvarMap()
+	expvar.Publish(name, vars)
+	return vars
+}
+
+// MetricsHandler 返回以 Prometheus 文本格式输出统计的 http.Handler
+func (m *BufferPoolManager) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = m.WriteMetrics(w)
+	})
+}
+
+// WriteMetrics 以 Prometheus 文本格式写出统计
+func (m *BufferPoolManager) WriteMetrics(w io.Writer) error {
+	s := m.Stats()
+	bw := bufio.NewWriter(w)
+
+	writeMetric(bw, "godb_buffer_pool_hits_total", "counter", "Page fetches served from the buffer pool.", s.Hits)
+	writeMetric(bw, "godb_buffer_pool_misses_total", "counter", "Page fetches not served from the buffer pool.", s.Misses)
+	writeMetric(bw, "godb_buffer_pool_evictions_total", "counter", "Pages evicted from the buffer pool.", s.Evictions)
+	writeMetric(bw, "godb_buffer_pool_size", "gauge", "Number of frames in the buffer pool.", int64(s.PoolSize))
+	writeMetric(bw, "godb_buffer_pool_dirty_pages", "gauge", "Resident pages not yet written back.", int64(s.DirtyPages))
+	writeMetric(bw, "godb_buffer_pool_pinned_pages", "gauge", "Resident pages with a positive pin count.", int64(s.PinnedPages))
+	writeMetric(bw, "godb_buffer_pool_replacer_size", "gauge", "Frames tracked by the LRU-K replacer.", int64(s.ReplacerSize))
+	writeMetric(bw, "godb_secondary_cache_hits_total", "counter", "Buffer pool misses served from the compressed cache.", s.SecondaryCacheHits)
+	writeMetric(bw, "godb_secondary_cache_misses_total", "counter", "Buffer pool misses not found in the compressed cache.", s.SecondaryCacheMisses)
+	writeMetric(bw, "godb_secondary_cache_pages", "gauge", "Pages held by the compressed cache.", int64(s.SecondaryCachePages))
+	writeMetric(bw, "godb_secondary_cache_bytes", "gauge", "Compressed bytes held by the compressed cache.", int64(s.SecondaryCacheBytes))
+	writeMetric(bw, "godb_disk_reads_total", "counter", "Pages read from disk.", s.DiskReads)
+	writeMetric(bw, "godb_disk_writes_total", "counter", "Pages written to disk.", s.DiskWrites)
+	writeMetric(bw, "godb_disk_read_bytes_total", "counter", "Bytes read from disk.", s.DiskBytesRead)
+	writeMetric(bw, "godb_disk_written_bytes_total", "counter", "Bytes written to disk.", s.DiskBytesWritten)
+	writeHistogram(bw, "godb_disk_read_seconds", "Latency of page reads.", s.DiskReadLatency)
+	writeHistogram(bw, "godb_disk_write_seconds", "Latency of page writes.", s.DiskWriteLatency)
+
+	return bw.Flush()
+}
+
+func writeMetric(w *bufio.Writer, name, typ, help string, value int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, typ, name, value)
+}
+
+func writeHistogram(w *bufio.Writer, name, help string, h HistogramSnapshot) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for i, bound := range h.Bounds {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), h.Buckets[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.Sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.Count)
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	h := Histogram{}
+	h.Observe(5 * time.Microsecond)
+	h.Observe(2 * time.Millisecond)
+	h.Observe(2 * time.Second)
+
+	s := h.Snapshot()
+	if s.Count != 3 {
+		t.Errorf("expected count 3, got %d", s.Count)
+	}
+	if s.Buckets[0] != 1 {
+		t.Errorf("expected 1 observation in the first bucket, got %d", s.Buckets[0])
+	}
+	if last := s.Buckets[len(s.Buckets)-1]; last != 2 {
+		t.Errorf("expected 2 observations up to 1s, got %d", last)
+	}
+	if s.Sum < 2 {
+		t.Errorf("expected sum over 2s, got %f", s.Sum)
+	}
+}
+
+func TestBufferPool_Stats(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 1, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	// 一次未命中，一次命中
+	for i := 0; i < 2; i++ {
+		if _, err := bm.FetchPage(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := bm.Stats()
+	if s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got %d and %d", s.Hits, s.Misses)
+	}
+	if s.PinnedPages != 1 || s.ReplacerSize != 1 {
+		t.Errorf("expected 1 pinned page and replacer size 1, got %d and %d", s.PinnedPages, s.ReplacerSize)
+	}
+	if s.DiskReads != 1 || s.DiskBytesRead != PageSize || s.DiskReadLatency.Count != 1 {
+		t.Errorf("unexpected disk read stats: %+v", s)
+	}
+
+	if err := bm.UnpinPage(0, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := bm.UnpinPage(0, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// 驱逐页面 0
+	if _, err := bm.FetchPage(1); err != nil {
+		t.Fatal(err)
+	}
+	defer bm.UnpinPage(1, false)
+
+	s = bm.Stats()
+	if s.Evictions != 1 {
+		t.Errorf("expected 1 eviction, got %d", s.Evictions)
+	}
+	if s.DiskWrites != 1 || s.DiskBytesWritten != PageSize {
+		t.Errorf("unexpected disk write stats: %+v", s)
+	}
+}
+
+func TestBufferPool_ExpvarMap(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 1, PageSize, 2)
+	if _, err := bm.FetchPage(0); err != nil {
+		t.Fatal(err)
+	}
+	defer bm.UnpinPage(0, false)
+
+	vars := bm.ExpvarMap()
+	if vars.Get("misses").String() != "1" {
+		t.Errorf("expected misses 1, got %s", vars.Get("misses").String())
+	}
+
+	var latency HistogramSnapshot
+	if err := json.Unmarshal([]byte(vars.Get("disk_read_latency").String()), &latency); err != nil {
+		t.Fatal(err)
+	}
+	if latency.Count != 1 {
+		t.Errorf("expected 1 read latency observation, got %d", latency.Count)
+	}
+}
+
+func TestBufferPool_MetricsHandler(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 1, PageSize, 2)
+	if _, err := bm.FetchPage(0); err != nil {
+		t.Fatal(err)
+	}
+	defer bm.UnpinPage(0, false)
+
+	rec := httptest.NewRecorder()
+	bm.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE godb_buffer_pool_misses_total counter",
+		"godb_buffer_pool_misses_total 1",
+		"godb_buffer_pool_pinned_pages 1",
+		"godb_disk_read_bytes_total 4096",
+		`godb_disk_read_seconds_bucket{le="+Inf"} 1`,
+		"godb_disk_read_seconds_count 1",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected metrics to contain %q", line)
+		}
+	}
+}
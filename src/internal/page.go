@@ -4,11 +4,19 @@ import "sync"
 
 const DefaultPageSize = PageSize
 
+// InvalidPageID 不存在的页面，如链表的结尾
+const InvalidPageID = -1
+
 // Page 页面结构
 type Page struct {
 	PageID   int
 	Data     []byte
 	IsDirty  bool
 	PinCount int
-	mu       sync.Mutex
+	// mu 页面锁，保护 Data 的读写
+	mu sync.RWMutex
+
+	// frameID 页面在缓冲池中占用的 frame，只在页面位于 PageTable 中时有效，
+	// 页面被删除、淘汰或在 Resize 中移出缓冲池后保留原来的值，不应再使用
+	frameID int
 }
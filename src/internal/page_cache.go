@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"bytes"
+	"compress/flate"
+	"container/list"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// flateWriters 复用压缩器，flate.NewWriter 每次都要分配较大的内部状态
+var flateWriters = sync.Pool{
+	New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+// compressedPage 二级缓存中的一个页面镜像
+type compressedPage struct {
+	pageID int
+	data   []byte
+}
+
+// PageCache 位于缓冲池之后的二级缓存，保存被驱逐页面的压缩镜像
+// 缓存是独占的：页面被重新加载到缓冲池时会从这里移除，之后驱逐时再放回
+// 直接通过 DiskManager 写入的页面不会使这里的镜像失效
+type PageCache struct {
+	// budget 压缩后数据的总字节数上限
+	budget int
+	size   int
+
+	entries map[int]*list.Element
+	lru     *list.List
+	mu      sync.Mutex
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewPageCache 创建一个压缩后最多占用 budget 字节的二级缓存
+func NewPageCache(budget int) *PageCache {
+	return &PageCache{
+		budget:  budget,
+		entries: make(map[int]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Put 压缩并缓存页面数据，超出预算时淘汰最久未使用的镜像
+func (c *PageCache) Put(pageID int, data []byte) {
+	var buf bytes.Buffer
+	w := flateWriters.Get().(*flate.Writer)
+	w.Reset(&buf)
+	_, err := w.Write(data)
+	if err == nil {
+		err = w.Close()
+	}
+	flateWriters.Put(w)
+	if err != nil {
+		return
+	}
+
+	// 压缩后仍放不下的页面直接丢弃
+	compressed := bytes.Clone(buf.Bytes())
+	if len(compressed) > c.budget {
+		c.Remove(pageID)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(pageID)
+	for c.size+len(compressed) > c.budget {
+		c.remove(c.lru.Back().Value.(*compressedPage).pageID)
+	}
+
+	c.entries[pageID] = c.lru.PushFront(&compressedPage{pageID: pageID, data: compressed})
+	c.size += len(compressed)
+}
+
+// Get 将页面解压到 data 中并从缓存移除，不存在时返回 false
+func (c *PageCache) Get(pageID int, data []byte) bool {
+	c.mu.Lock()
+	e, ok := c.entries[pageID]
+	if !ok {
+		c.mu.Unlock()
+		c.misses.Add(1)
+		return false
+	}
+	compressed := e.Value.(*compressedPage).data
+	c.remove(pageID)
+	c.mu.Unlock()
+
+	r := flate.NewReader(bytes.NewReader(compressed))
+	defer r.Close()
+	if _, err := io.ReadFull(r, data); err != nil {
+		c.misses.Add(1)
+		return false
+	}
+
+	c.hits.Add(1)
+	return true
+}
+
+// Remove 丢弃页面的镜像
+func (c *PageCache) Remove(pageID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(pageID)
+}
+
+// Len 返回缓存的页面数
+func (c *PageCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Size 返回压缩后数据占用的字节数
+func (c *PageCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// remove 调用方需持有 c.mu
+func (c *PageCache) remove(pageID int) {
+	e, ok := c.entries[pageID]
+	if !ok {
+		return
+	}
+
+	c.size -= len(e.Value.(*compressedPage).data)
+	c.lru.Remove(e)
+	delete(c.entries, pageID)
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPageCache(t *testing.T) {
+	page := func(b byte) []byte {
+		return bytes.Repeat([]byte{b}, PageSize)
+	}
+
+	t.Run("get removes entry", func(t *testing.T) {
+		c := NewPageCache(PageSize)
+		c.Put(1, page(1))
+		if c.Len() != 1 || c.Size() == 0 || c.Size() >= PageSize {
+			t.Fatalf("expected one compressed page, got %d pages and %d bytes", c.Len(), c.Size())
+		}
+
+		data := make([]byte, PageSize)
+		if !c.Get(1, data) {
+			t.Fatal("expected cache hit")
+		}
+		if !bytes.Equal(data, page(1)) {
+			t.Error("page data mismatch")
+		}
+		if c.Len() != 0 || c.Size() != 0 {
+			t.Error("expected entry to be removed")
+		}
+		if c.Get(1, data) {
+			t.Error("expected cache miss")
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		c := NewPageCache(PageSize)
+		c.Put(1, page(1))
+		size := c.Size()
+
+		// 预算能放下两个页面，放不下三个
+		budget := size*2 + size/2
+		c = NewPageCache(budget)
+		for pageID := 1; pageID <= 3; pageID++ {
+			c.Put(pageID, page(1))
+		}
+		if c.Len() != 2 || c.Size() > budget {
+			t.Fatalf("expected 2 pages within budget, got %d pages and %d bytes", c.Len(), c.Size())
+		}
+
+		// 最久未使用的页面 1 被淘汰
+		data := make([]byte, PageSize)
+		if c.Get(1, data) {
+			t.Error("expected page 1 to be dropped")
+		}
+		if !c.Get(3, data) || !bytes.Equal(data, page(1)) {
+			t.Error("expected page 3 to be cached")
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		c := NewPageCache(1)
+		c.Put(1, page(1))
+		if c.Len() != 0 {
+			t.Error("expected page not to be cached")
+		}
+	})
+}
+
+func TestBufferPool_SecondaryCache(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 1, PageSize, 2)
+	bm.SecondaryCache = NewPageCache(PageSize * 4)
+	defer bm.AssertNoPinLeaks(t)
+
+	p, err := bm.FetchPage(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(p.Data, "hello")
+	if err := bm.UnpinPage(0, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// 驱逐页面 0 到二级缓存
+	if _, err := bm.FetchPage(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := bm.UnpinPage(1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	reads := dm.Stats.Reads.Load()
+	p, err = bm.FetchPage(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bm.UnpinPage(0, false)
+
+	if string(p.Data[:5]) != "hello" {
+		t.Error("page data mismatch")
+	}
+	if dm.Stats.Reads.Load() != reads {
+		t.Error("expected page 0 to be served without a disk read")
+	}
+	if s := bm.Stats(); s.SecondaryCacheHits != 1 || s.SecondaryCachePages != 1 {
+		t.Errorf("expected 1 secondary hit and page 1 cached, got %d and %d", s.SecondaryCacheHits, s.SecondaryCachePages)
+	}
+}
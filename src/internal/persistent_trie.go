@@ -0,0 +1,137 @@
+package internal
+
+// PersistentTrie is a copy-on-write trie, Put and Remove never modify
+// an existing trie but return a new one sharing the untouched subtrees,
+// so every returned trie stays valid as an immutable snapshot
+type PersistentTrie[V any] struct {
+	root *persistentTrieNode[V]
+}
+
+// persistentTrieNode is never modified once it is reachable from a trie
+type persistentTrieNode[V any] struct {
+	// hasVal tells whether the node is the end of a key
+	val    V
+	hasVal bool
+	childs map[byte]*persistentTrieNode[V]
+}
+
+// NewPersistentTrie returns an empty persistent trie
+func NewPersistentTrie[V any]() *PersistentTrie[V] {
+	return &PersistentTrie[V]{}
+}
+
+// clone returns a shallow copy of the node, children are shared
+func (n *persistentTrieNode[V]) clone() *persistentTrieNode[V] {
+	if n == nil {
+		return &persistentTrieNode[V]{
+			childs: make(map[byte]*persistentTrieNode[V]),
+		}
+	}
+
+	childs := make(map[byte]*persistentTrieNode[V], len(n.childs))
+	for k, child := range n.childs {
+		childs[k] = child
+	}
+
+	return &persistentTrieNode[V]{
+		val:    n.val,
+		hasVal: n.hasVal,
+		childs: childs,
+	}
+}
+
+// Get returns the value of the key in this snapshot and whether the key is present
+func (t *PersistentTrie[V]) Get(key string) (V, bool) {
+	var zero V
+	if len(key) == 0 {
+		return zero, false
+	}
+
+	cur := t.root
+	for i := 0; i < len(key) && cur != nil; i++ {
+		cur = cur.childs[key[i]]
+	}
+
+	if cur == nil || !cur.hasVal {
+		return zero, false
+	}
+
+	return cur.val, true
+}
+
+// Put returns a new trie with key set to val, an existing value is replaced
+func (t *PersistentTrie[V]) Put(key string, val V) (*PersistentTrie[V], error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+
+	// copy the nodes on the path from root to key, the rest is shared
+	root := t.root.clone()
+	cur := root
+	old := t.root
+	for i := 0; i < len(key); i++ {
+		var oldChild *persistentTrieNode[V]
+		if old != nil {
+			oldChild = old.childs[key[i]]
+		}
+
+		child := oldChild.clone()
+		cur.childs[key[i]] = child
+		cur, old = child, oldChild
+	}
+	cur.val = val
+	cur.hasVal = true
+
+	return &PersistentTrie[V]{root: root}, nil
+}
+
+// Remove returns a new trie without key, nodes left with neither
+// a value nor children are pruned
+func (t *PersistentTrie[V]) Remove(key string) (*PersistentTrie[V], error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+
+	root, err := t.root.remove(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PersistentTrie[V]{root: root}, nil
+}
+
+// remove returns a copy of the subtree without key, or nil if it becomes empty
+func (n *persistentTrieNode[V]) remove(key string) (*persistentTrieNode[V], error) {
+	if n == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	var node *persistentTrieNode[V]
+	if len(key) == 0 {
+		if !n.hasVal {
+			return nil, ErrKeyNotFound
+		}
+		var zero V
+		node = n.clone()
+		node.val = zero
+		node.hasVal = false
+	} else {
+		child, err := n.childs[key[0]].remove(key[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		node = n.clone()
+		if child == nil {
+			delete(node.childs, key[0])
+		} else {
+			node.childs[key[0]] = child
+		}
+	}
+
+	if !node.hasVal && len(node.childs) == 0 {
+		return nil, nil
+	}
+
+	return node, nil
+}
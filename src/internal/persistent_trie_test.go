@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPersistentTrie_Put(t *testing.T) {
+	t.Run("empty key", func(t *testing.T) {
+		trie := NewPersistentTrie[string]()
+		_, err := trie.Put("", "value")
+		if !errors.Is(err, ErrEmptyKey) {
+			t.Error("expected ErrEmptyKey")
+		}
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		trie, err := NewPersistentTrie[int]().Put("key", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val, ok := trie.Get("key"); !ok || val != 0 {
+			t.Error("expected zero value to be stored")
+		}
+	})
+
+	t.Run("snapshots", func(t *testing.T) {
+		v0 := NewPersistentTrie[string]()
+		v1, err := v0.Put("key", "value1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v2, err := v1.Put("key", "value2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := v0.Get("key"); ok {
+			t.Error("expected key not found in v0")
+		}
+		if val, ok := v1.Get("key"); !ok || val != "value1" {
+			t.Errorf("expected value1 in v1, got %v", val)
+		}
+		if val, ok := v2.Get("key"); !ok || val != "value2" {
+			t.Errorf("expected value2 in v2, got %v", val)
+		}
+	})
+
+	t.Run("shares untouched subtrees", func(t *testing.T) {
+		v1, err := NewPersistentTrie[int]().Put("abc", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v2, err := v1.Put("xyz", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v1.root == v2.root {
+			t.Error("expected a new root")
+		}
+		if v1.root.childs['a'] != v2.root.childs['a'] {
+			t.Error("expected subtree 'a' to be shared")
+		}
+	})
+}
+
+func TestPersistentTrie_Get(t *testing.T) {
+	trie, err := NewPersistentTrie[string]().Put("key1", "value1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := trie.Get(""); ok {
+		t.Error("expected empty key not found")
+	}
+	if _, ok := trie.Get("key"); ok {
+		t.Error("expected key not found")
+	}
+	if _, ok := trie.Get("key12"); ok {
+		t.Error("expected key not found")
+	}
+	if val, ok := trie.Get("key1"); !ok || val != "value1" {
+		t.Errorf("expected value1, got %v", val)
+	}
+}
+
+func TestPersistentTrie_Remove(t *testing.T) {
+	t.Run("non existing key", func(t *testing.T) {
+		trie, err := NewPersistentTrie[string]().Put("key1", "value1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := trie.Remove("key"); !errors.Is(err, ErrKeyNotFound) {
+			t.Error("expected ErrKeyNotFound")
+		}
+		if _, err := NewPersistentTrie[string]().Remove("key"); !errors.Is(err, ErrKeyNotFound) {
+			t.Error("expected ErrKeyNotFound")
+		}
+	})
+
+	t.Run("normal case", func(t *testing.T) {
+		v1, err := NewPersistentTrie[string]().Put("key", "value")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v2, err := v1.Put("key1", "value1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v3, err := v2.Remove("key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := v3.Get("key"); ok {
+			t.Error("expected key not found in v3")
+		}
+		if val, ok := v3.Get("key1"); !ok || val != "value1" {
+			t.Errorf("expected value1 in v3, got %v", val)
+		}
+		if val, ok := v2.Get("key"); !ok || val != "value" {
+			t.Errorf("expected value in v2, got %v", val)
+		}
+
+		// removing the last key prunes the whole path
+		v4, err := v3.Remove("key1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v4.root != nil {
+			t.Error("expected empty trie")
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PinRecord 一次 pin 操作的记录，用于排查忘记 UnpinPage 的调用方
+type PinRecord struct {
+	PageID int
+	Time   time.Time
+	Stack  string
+}
+
+// pinTracker 记录每个页面上尚未释放的 pin
+type pinTracker struct {
+	pins map[int][]PinRecord
+	mu   sync.Mutex
+}
+
+func newPinTracker() *pinTracker {
+	return &pinTracker{
+		pins: make(map[int][]PinRecord),
+	}
+}
+
+// pin 记录一次 pin 以及当前 goroutine 的调用栈
+func (pt *pinTracker) pin(pageID int) {
+	record := PinRecord{
+		PageID: pageID,
+		Time:   time.Now(),
+		Stack:  string(debug.Stack()),
+	}
+
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	pt.pins[pageID] = append(pt.pins[pageID], record)
+}
+
+// unpin 释放该页面最近的一次 pin
+func (pt *pinTracker) unpin(pageID int) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	records := pt.pins[pageID]
+	if len(records) == 0 {
+		return
+	}
+
+	if len(records) == 1 {
+		delete(pt.pins, pageID)
+		return
+	}
+	pt.pins[pageID] = records[:len(records)-1]
+}
+
+// forget 丢弃该页面的所有记录
+func (pt *pinTracker) forget(pageID int) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	delete(pt.pins, pageID)
+}
+
+// outstanding 按页面 id 和 pin 时间顺序返回所有未释放的 pin
+func (pt *pinTracker) outstanding() []PinRecord {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	records := make([]PinRecord, 0, len(pt.pins))
+	for _, rs := range pt.pins {
+		records = append(records, rs...)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].PageID != records[j].PageID {
+			return records[i].PageID < records[j].PageID
+		}
+		return records[i].Time.Before(records[j].Time)
+	})
+
+	return records
+}
+
+// EnablePinTracking 开启调试模式，记录之后每次 pin 的调用栈
+// 开启前已经存在的 pin 不会被记录
+func (m *BufferPoolManager) EnablePinTracking() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.pinTracker == nil {
+		m.pinTracker = newPinTracker()
+	}
+}
+
+// DisablePinTracking 关闭调试模式并丢弃已有的记录
+func (m *BufferPoolManager) DisablePinTracking() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pinTracker = nil
+}
+
+// LeakedPins 返回所有尚未 unpin 的记录，未开启调试模式时返回 nil
+func (m *BufferPoolManager) LeakedPins() []PinRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.pinTracker == nil {
+		return nil
+	}
+
+	return m.pinTracker.outstanding()
+}
+
+// DumpPins 将尚未 unpin 的记录写入 w
+func (m *BufferPoolManager) DumpPins(w io.Writer) error {
+	for _, r := range m.LeakedPins() {
+		_, err := fmt.Fprintf(w, "page %d pinned at %s\n%s\n", r.PageID, r.Time.Format(time.RFC3339Nano), r.Stack)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PinLeakReporter 是 *testing.T 的子集，避免在非测试代码中引入 testing 包
+type PinLeakReporter interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertNoPinLeaks 在还有页面被 pin 住时让测试失败
+// 通常在测试中 defer bm.AssertNoPinLeaks(t)
+func (m *BufferPoolManager) AssertNoPinLeaks(t PinLeakReporter) {
+	t.Helper()
+
+	if records := m.LeakedPins(); len(records) > 0 {
+		for _, r := range records {
+			t.Errorf("page %d is still pinned, pinned at:\n%s", r.PageID, r.Stack)
+		}
+		return
+	}
+
+	for _, pageID := range m.pinnedPageIDs() {
+		t.Errorf("page %d is still pinned", pageID)
+	}
+}
+
+// pinnedPageIDs 返回 pin 计数大于零的页面 id
+func (m *BufferPoolManager) pinnedPageIDs() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pageIDs := make([]int, 0)
+	for pageID, p := range m.PageTable {
+		if p.PinCount > 0 {
+			pageIDs = append(pageIDs, pageID)
+		}
+	}
+	sort.Ints(pageIDs)
+
+	return pageIDs
+}
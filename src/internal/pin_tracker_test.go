@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeReporter struct {
+	errors []string
+}
+
+func (r *fakeReporter) Helper() {}
+
+func (r *fakeReporter) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestBufferPool_PinTracking(t *testing.T) {
+	t.Run("balanced pins", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 3, PageSize, 2)
+		bm.EnablePinTracking()
+		defer bm.AssertNoPinLeaks(t)
+
+		for i := 0; i < 2; i++ {
+			if _, err := bm.FetchPage(1); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(bm.LeakedPins()) != 2 {
+			t.Errorf("expected 2 outstanding pins, got %d", len(bm.LeakedPins()))
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := bm.UnpinPage(1, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := bm.ShutDown(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("leaked pin", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 3, PageSize, 2)
+		bm.EnablePinTracking()
+
+		if _, err := bm.FetchPage(2); err != nil {
+			t.Fatal(err)
+		}
+
+		records := bm.LeakedPins()
+		if len(records) != 1 || records[0].PageID != 2 {
+			t.Fatalf("expected one leaked pin on page 2, got %v", records)
+		}
+		if !strings.Contains(records[0].Stack, "TestBufferPool_PinTracking") {
+			t.Error("expected stack to contain the caller")
+		}
+
+		var buf bytes.Buffer
+		if err := bm.DumpPins(&buf); err != nil {
+			t.Error(err)
+		}
+		if !strings.Contains(buf.String(), "page 2 pinned at") {
+			t.Error("expected dump to list page 2")
+		}
+
+		reporter := &fakeReporter{}
+		bm.AssertNoPinLeaks(reporter)
+		if len(reporter.errors) != 1 {
+			t.Errorf("expected 1 reported leak, got %d", len(reporter.errors))
+		}
+
+		if err := bm.ShutDown(); !errors.Is(err, ErrPinLeak) {
+			t.Error("expected ErrPinLeak")
+		}
+	})
+
+	t.Run("leaked pin without tracking", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 3, PageSize, 2)
+		if _, err := bm.FetchPage(0); err != nil {
+			t.Fatal(err)
+		}
+
+		if bm.LeakedPins() != nil {
+			t.Error("expected no records without tracking")
+		}
+
+		reporter := &fakeReporter{}
+		bm.AssertNoPinLeaks(reporter)
+		if len(reporter.errors) != 1 {
+			t.Errorf("expected 1 reported leak, got %d", len(reporter.errors))
+		}
+
+		if err := bm.ShutDown(); !errors.Is(err, ErrPinLeak) {
+			t.Error("expected ErrPinLeak")
+		}
+	})
+}
@@ -1 +1,189 @@
 package internal
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// 元组格式：
+//
+//	| 总长度 u16 | 列数 u16 | Schema 版本 u16 | NULL 位图 | 定长区 | 变长数据 |
+//
+// 定长区按列顺序排列，定长列直接存放编码后的值，变长列存放 4 字节的槽：
+// 变长数据相对元组起始处的偏移 u16 和长度 u16
+// NULL 列在定长区中仍占位，因此每列在定长区中的位置只由 Schema 决定
+// 版本记录写入时 Schema 的版本，ALTER TABLE 之后旧元组按它找到对应的 Schema 解码
+const (
+	tupleSizeOffset    = 0
+	tupleColumnsOffset = 2
+	tupleVersionOffset = 4
+	tupleHeaderSize    = 6
+	tupleVarSlotSize   = 4
+
+	// MaxTupleSize 元组的最大字节数
+	MaxTupleSize = 1<<16 - 1
+)
+
+// Tuple 按 Schema 序列化的一行数据
+type Tuple struct {
+	Data []byte
+}
+
+// NewTuple 将一行值转换为各列的类型后序列化，值的个数必须与列数相同
+func NewTuple(schema *Schema, values []Value) (*Tuple, error) {
+	values, err := schema.CastValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(schema.Columns)
+	offsets, fixedEnd := tupleLayout(schema)
+	if fixedEnd > MaxTupleSize {
+		return nil, fmt.Errorf("%w: tuple larger than %d bytes", ErrValueTooLong, MaxTupleSize)
+	}
+	data := make([]byte, fixedEnd, fixedEnd+64)
+	binary.LittleEndian.PutUint16(data[tupleColumnsOffset:], uint16(n))
+	binary.LittleEndian.PutUint16(data[tupleVersionOffset:], uint16(schema.Version))
+
+	for i, v := range values {
+		if v.IsNull() {
+			data[tupleHeaderSize+i/8] |= 1 << (i % 8)
+			continue
+		}
+
+		col := schema.Columns[i]
+		if _, ok := col.Type.FixedSize(); ok {
+			enc, err := v.AppendBinary(nil, col.Type)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", col.Name, err)
+			}
+			copy(data[offsets[i]:], enc)
+			continue
+		}
+
+		start := len(data)
+		if data, err = v.AppendBinary(data, col.Type); err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		if len(data) > MaxTupleSize {
+			return nil, fmt.Errorf("%w: tuple larger than %d bytes", ErrValueTooLong, MaxTupleSize)
+		}
+		binary.LittleEndian.PutUint16(data[offsets[i]:], uint16(start))
+		binary.LittleEndian.PutUint16(data[offsets[i]+2:], uint16(len(data)-start))
+	}
+
+	binary.LittleEndian.PutUint16(data[tupleSizeOffset:], uint16(len(data)))
+	return &Tuple{Data: data}, nil
+}
+
+// tupleLayout 返回每列在定长区中的偏移和定长区的结束位置
+func tupleLayout(schema *Schema) ([]int, int) {
+	n := len(schema.Columns)
+	offsets := make([]int, n)
+	off := tupleHeaderSize + (n+7)/8
+	for i, col := range schema.Columns {
+		offsets[i] = off
+		if size, ok := col.Type.FixedSize(); ok {
+			off += size
+		} else {
+			off += tupleVarSlotSize
+		}
+	}
+
+	return offsets, off
+}
+
+// Size 元组的字节数
+func (t *Tuple) Size() int {
+	return len(t.Data)
+}
+
+// Version 写入元组时 Schema 的版本
+func (t *Tuple) Version() int {
+	if len(t.Data) < tupleHeaderSize {
+		return -1
+	}
+
+	return int(binary.LittleEndian.Uint16(t.Data[tupleVersionOffset:]))
+}
+
+// validate 检查头部与 Schema 和数据长度是否一致
+func (t *Tuple) validate(schema *Schema) error {
+	if len(t.Data) < tupleHeaderSize {
+		return fmt.Errorf("%w: %d bytes", ErrInvalidTuple, len(t.Data))
+	}
+	if size := int(binary.LittleEndian.Uint16(t.Data[tupleSizeOffset:])); size != len(t.Data) {
+		return fmt.Errorf("%w: size %d, got %d bytes", ErrInvalidTuple, size, len(t.Data))
+	}
+	if n := int(binary.LittleEndian.Uint16(t.Data[tupleColumnsOffset:])); n != len(schema.Columns) {
+		return fmt.Errorf("%w: %d columns, schema has %d", ErrInvalidTuple, n, len(schema.Columns))
+	}
+	if v := t.Version(); v != schema.Version {
+		return fmt.Errorf("%w: written with schema version %d, got version %d", ErrInvalidTuple, v, schema.Version)
+	}
+
+	return nil
+}
+
+// IsNull 返回第 colIdx 列是否为 NULL
+func (t *Tuple) IsNull(colIdx int) bool {
+	i := tupleHeaderSize + colIdx/8
+	return i < len(t.Data) && t.Data[i]&(1<<(colIdx%8)) != 0
+}
+
+// GetValue 解码第 colIdx 列，只读取该列的数据
+func (t *Tuple) GetValue(schema *Schema, colIdx int) (Value, error) {
+	if err := t.validate(schema); err != nil {
+		return Value{}, err
+	}
+	if colIdx < 0 || colIdx >= len(schema.Columns) {
+		return Value{}, fmt.Errorf("%w: column %d out of range", ErrInvalidTuple, colIdx)
+	}
+
+	offsets, fixedEnd := tupleLayout(schema)
+	if fixedEnd > len(t.Data) {
+		return Value{}, fmt.Errorf("%w: truncated", ErrInvalidTuple)
+	}
+	return t.value(schema.Columns[colIdx].Type, colIdx, offsets[colIdx], fixedEnd)
+}
+
+// Values 解码所有列
+func (t *Tuple) Values(schema *Schema) ([]Value, error) {
+	if err := t.validate(schema); err != nil {
+		return nil, err
+	}
+
+	offsets, fixedEnd := tupleLayout(schema)
+	if fixedEnd > len(t.Data) {
+		return nil, fmt.Errorf("%w: truncated", ErrInvalidTuple)
+	}
+
+	values := make([]Value, len(schema.Columns))
+	for i, col := range schema.Columns {
+		var err error
+		if values[i], err = t.value(col.Type, i, offsets[i], fixedEnd); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// value 解码定长区中 off 处的列
+func (t *Tuple) value(typ Type, colIdx, off, fixedEnd int) (Value, error) {
+	if t.IsNull(colIdx) {
+		return NewNull(typ.ID), nil
+	}
+
+	if size, ok := typ.FixedSize(); ok {
+		return DecodeValue(typ, t.Data[off:off+size])
+	}
+
+	start := int(binary.LittleEndian.Uint16(t.Data[off:]))
+	length := int(binary.LittleEndian.Uint16(t.Data[off+2:]))
+	if start < fixedEnd || start+length > len(t.Data) {
+		return Value{}, fmt.Errorf("%w: bad slot for column %d", ErrInvalidTuple, colIdx)
+	}
+
+	return DecodeValue(typ, t.Data[start:start+length])
+}
@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSchema(t *testing.T) *Schema {
+	schema, err := NewSchema(
+		NewColumn("flag", BooleanType()),
+		NewColumn("tiny", TinyIntType()),
+		NewColumn("small", SmallIntType()),
+		NewColumn("id", IntegerType()),
+		NewColumn("big", BigIntType()),
+		NewColumn("ratio", DoubleType()),
+		NewColumn("price", DecimalType(10, 2)),
+		NewColumn("name", VarcharType(32)),
+		NewColumn("code", CharType(4)),
+		NewColumn("day", DateType()),
+		NewColumn("at", TimestampType()),
+		NewColumn("payload", BlobType()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestTuple_RoundTrip(t *testing.T) {
+	schema := newTestSchema(t)
+	ts := time.Date(2024, 2, 29, 10, 11, 12, 5000, time.UTC)
+	values := []Value{
+		NewBoolean(true),
+		NewTinyInt(-1),
+		NewSmallInt(math.MaxInt16),
+		NewInteger(42),
+		NewBigInt(math.MinInt64),
+		NewDouble(3.25),
+		mustDecimal(-1999, 2),
+		NewVarchar("héllo"),
+		NewChar("ab"),
+		NewDate(ts),
+		NewTimestamp(ts),
+		NewBlob([]byte{0, 0xff}),
+	}
+
+	tuple, err := NewTuple(schema, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := tuple.Values(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range decoded {
+		if v.TypeID() != schema.Columns[i].Type.ID || v.String() != values[i].String() {
+			t.Errorf("column %d: expected %v, got %v", i, values[i], v)
+		}
+	}
+
+	t.Run("single column", func(t *testing.T) {
+		v, err := tuple.GetValue(schema, schema.ColumnIndex("name"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Str() != "héllo" {
+			t.Errorf("expected héllo, got %s", v)
+		}
+
+		if _, err := tuple.GetValue(schema, len(schema.Columns)); !errors.Is(err, ErrInvalidTuple) {
+			t.Error("expected ErrInvalidTuple")
+		}
+	})
+
+	t.Run("nulls", func(t *testing.T) {
+		nulls := make([]Value, len(values))
+		for i, col := range schema.Columns {
+			nulls[i] = NewNull(col.Type.ID)
+		}
+		nulls[3] = NewInteger(7)
+
+		tuple, err := NewTuple(schema, nulls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := tuple.Values(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range decoded {
+			if v.IsNull() != (i != 3) || !tuple.IsNull(i) != (i == 3) {
+				t.Errorf("column %d: unexpected %v", i, v)
+			}
+		}
+		if decoded[3].Int() != 7 {
+			t.Errorf("expected 7, got %v", decoded[3])
+		}
+	})
+
+	t.Run("empty strings", func(t *testing.T) {
+		values := append([]Value(nil), values...)
+		values[7] = NewVarchar("")
+		values[11] = NewBlob(nil)
+
+		tuple, err := NewTuple(schema, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := tuple.GetValue(schema, 7)
+		if err != nil || v.IsNull() || v.Str() != "" {
+			t.Errorf("expected empty string, got %v", v)
+		}
+	})
+}
+
+func TestNewTuple_Invalid(t *testing.T) {
+	schema, err := NewSchema(
+		NewColumn("id", IntegerType()),
+		NewColumn("name", VarcharType(MaxVarcharLength)),
+		NewColumn("note", VarcharType(MaxVarcharLength)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewTuple(schema, []Value{NewInteger(1)}); !errors.Is(err, ErrTypeMismatch) {
+		t.Error("expected ErrTypeMismatch")
+	}
+
+	long := NewVarchar(strings.Repeat("x", 40000))
+	if _, err := NewTuple(schema, []Value{NewInteger(1), long, long}); !errors.Is(err, ErrValueTooLong) {
+		t.Error("expected ErrValueTooLong")
+	}
+
+	tuple, err := NewTuple(schema, []Value{NewInteger(1), NewVarchar("a"), NewVarchar("b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewSchema(NewColumn("id", IntegerType()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tuple.Values(other); !errors.Is(err, ErrInvalidTuple) {
+		t.Error("expected ErrInvalidTuple for another schema")
+	}
+
+	newer := &Schema{Columns: schema.Columns, Version: 1}
+	if tuple.Version() != 0 {
+		t.Errorf("expected version 0, got %d", tuple.Version())
+	}
+	if _, err := tuple.Values(newer); !errors.Is(err, ErrInvalidTuple) {
+		t.Error("expected ErrInvalidTuple for another schema version")
+	}
+
+	truncated := &Tuple{Data: tuple.Data[:tuple.Size()-1]}
+	if _, err := truncated.Values(schema); !errors.Is(err, ErrInvalidTuple) {
+		t.Error("expected ErrInvalidTuple for truncated data")
+	}
+}
@@ -1,5 +1,324 @@
 package internal
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Schema 表的列定义，列名不区分大小写且不能重复
 type Schema struct {
 	Columns []Column
+
+	// Constraints 表级约束，随表结构一起分版本
+	Constraints []Constraint
+
+	// Version 表结构的版本，每次 ALTER TABLE 加一，元组头部记录写入时的版本
+	Version int
+}
+
+// MaxSchemaVersion 元组头部能记录的最大版本
+const MaxSchemaVersion = 1<<16 - 1
+
+// NewSchema 校验列定义并返回 Schema
+func NewSchema(columns ...Column) (*Schema, error) {
+	s := &Schema{Columns: columns}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Validate 检查至少有一列、每列合法且列名不重复
+func (s *Schema) Validate() error {
+	if len(s.Columns) == 0 {
+		return fmt.Errorf("%w: no columns", ErrInvalidSchema)
+	}
+
+	if s.Version < 0 || s.Version > MaxSchemaVersion {
+		return fmt.Errorf("%w: version %d out of range", ErrInvalidSchema, s.Version)
+	}
+
+	names := make(map[string]struct{}, len(s.Columns))
+	ids := make(map[int]struct{}, len(s.Columns))
+	for _, c := range s.Columns {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+
+		name := strings.ToLower(c.Name)
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("%w: duplicate column %s", ErrInvalidSchema, c.Name)
+		}
+		names[name] = struct{}{}
+
+		if c.ID == 0 {
+			continue
+		}
+		if _, ok := ids[c.ID]; ok {
+			return fmt.Errorf("%w: duplicate column id %d", ErrInvalidSchema, c.ID)
+		}
+		ids[c.ID] = struct{}{}
+	}
+
+	return s.validateConstraints()
+}
+
+// validateConstraints 检查约束名不重复、最多一个主键，并绑定 CHECK 表达式
+func (s *Schema) validateConstraints() error {
+	names := make(map[string]struct{}, len(s.Constraints))
+	hasPrimaryKey := false
+	for i := range s.Constraints {
+		c := &s.Constraints[i]
+		if err := c.validate(s); err != nil {
+			return err
+		}
+
+		if c.Kind == ConstraintPrimaryKey {
+			if hasPrimaryKey {
+				return fmt.Errorf("%w: multiple primary keys", ErrInvalidSchema)
+			}
+			hasPrimaryKey = true
+		}
+
+		if c.Name == "" {
+			continue
+		}
+		name := strings.ToLower(c.Name)
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("%w: duplicate constraint %s", ErrInvalidSchema, c.Name)
+		}
+		names[name] = struct{}{}
+	}
+
+	return nil
+}
+
+// PrimaryKey 返回主键约束，没有主键时返回 nil
+func (s *Schema) PrimaryKey() *Constraint {
+	for i := range s.Constraints {
+		if s.Constraints[i].Kind == ConstraintPrimaryKey {
+			return &s.Constraints[i]
+		}
+	}
+
+	return nil
+}
+
+// IsNotNull 返回第 i 列是否不能为 NULL，主键的列都不能为 NULL
+func (s *Schema) IsNotNull(i int) bool {
+	if s.Columns[i].NotNull {
+		return true
+	}
+
+	pk := s.PrimaryKey()
+	return pk != nil && slices.ContainsFunc(pk.Columns, func(name string) bool {
+		return strings.EqualFold(name, s.Columns[i].Name)
+	})
+}
+
+// CheckRow 检查已转换为列类型的一行是否满足 NOT NULL 和 CHECK 约束
+// PRIMARY KEY 和 UNIQUE 需要索引，由 Table 检查
+func (s *Schema) CheckRow(values []Value) error {
+	for i, v := range values {
+		if v.IsNull() && s.IsNotNull(i) {
+			return &ConstraintError{Kind: ConstraintNotNull, Name: s.Columns[i].Name}
+		}
+	}
+
+	for _, c := range s.Constraints {
+		if c.Kind != ConstraintCheck {
+			continue
+		}
+
+		expr := c.expr
+		if expr == nil {
+			if err := c.validate(s); err != nil {
+				return err
+			}
+			expr = c.expr
+		}
+		if result, known := expr.eval(values); known && !result {
+			return &ConstraintError{Kind: ConstraintCheck, Name: c.Name, Detail: c.Check}
+		}
+	}
+
+	return nil
+}
+
+// FillDefaults 把只给出 columns 列的一行补全为完整的一行，没有给出的列取默认值，没有默认值时为 NULL
+func (s *Schema) FillDefaults(columns []string, values []Value) ([]Value, error) {
+	if len(columns) != len(values) {
+		return nil, fmt.Errorf("%w: %d columns, got %d values", ErrTypeMismatch, len(columns), len(values))
+	}
+
+	row := make([]Value, len(s.Columns))
+	given := make([]bool, len(s.Columns))
+	for i, name := range columns {
+		j := s.ColumnIndex(name)
+		if j < 0 {
+			return nil, fmt.Errorf("%w: no column %s", ErrInvalidSchema, name)
+		}
+		if given[j] {
+			return nil, fmt.Errorf("%w: column %s given twice", ErrInvalidSchema, name)
+		}
+		row[j], given[j] = values[i], true
+	}
+	for j, col := range s.Columns {
+		if !given[j] {
+			row[j] = col.DefaultValue()
+		}
+	}
+
+	return row, nil
+}
+
+// ColumnByID 返回 ID 为 id 的列的下标，列不存在时返回 -1
+func (s *Schema) ColumnByID(id int) int {
+	for i, c := range s.Columns {
+		if c.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// withColumnIDs 返回给未分配 ID 的列分配了 ID 的副本
+func (s *Schema) withColumnIDs() *Schema {
+	next := 1
+	for _, c := range s.Columns {
+		next = max(next, c.ID+1)
+	}
+
+	columns := slices.Clone(s.Columns)
+	for i := range columns {
+		if columns[i].ID == 0 {
+			columns[i].ID = next
+			next++
+		}
+	}
+
+	return &Schema{Columns: columns, Constraints: s.Constraints, Version: s.Version}
+}
+
+// alter 返回版本加一的副本，由 fn 修改其列和约束
+func (s *Schema) alter(fn func(columns []Column, constraints []Constraint) ([]Column, []Constraint, error)) (*Schema, error) {
+	if s.Version >= MaxSchemaVersion {
+		return nil, fmt.Errorf("%w: too many versions", ErrInvalidSchema)
+	}
+
+	columns, constraints, err := fn(slices.Clone(s.Columns), slices.Clone(s.Constraints))
+	if err != nil {
+		return nil, err
+	}
+
+	next := &Schema{Columns: columns, Constraints: constraints, Version: s.Version + 1}
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+	return next.withColumnIDs(), nil
+}
+
+// AddColumn 返回在末尾加上 col 的新版本，旧元组中该列取 col 的默认值
+// col.ID 为 0 时分配比现有列都大的 ID，调用方需要避免复用已删除的列的 ID
+// NOT NULL 的列必须有非 NULL 的默认值，否则旧元组中的该列为 NULL
+func (s *Schema) AddColumn(col Column) (*Schema, error) {
+	if col.NotNull && col.DefaultValue().IsNull() {
+		return nil, fmt.Errorf("%w: column %s is NOT NULL without a default", ErrInvalidSchema, col.Name)
+	}
+
+	return s.alter(func(columns []Column, constraints []Constraint) ([]Column, []Constraint, error) {
+		return append(columns, col), constraints, nil
+	})
+}
+
+// DropColumn 返回删除 name 列的新版本，不能删除约束引用的列
+func (s *Schema) DropColumn(name string) (*Schema, error) {
+	return s.alter(func(columns []Column, constraints []Constraint) ([]Column, []Constraint, error) {
+		i := s.ColumnIndex(name)
+		if i < 0 {
+			return nil, nil, fmt.Errorf("%w: no column %s", ErrInvalidSchema, name)
+		}
+		for _, c := range constraints {
+			if slices.ContainsFunc(c.referencedColumns(), func(col string) bool { return strings.EqualFold(col, name) }) {
+				return nil, nil, fmt.Errorf("%w: column %s is used by %s", ErrInvalidSchema, name, c)
+			}
+		}
+
+		return slices.Delete(columns, i, i+1), constraints, nil
+	})
+}
+
+// RenameColumn 返回把 oldName 列改名为 newName 的新版本，约束中引用的列名一起修改
+func (s *Schema) RenameColumn(oldName, newName string) (*Schema, error) {
+	return s.alter(func(columns []Column, constraints []Constraint) ([]Column, []Constraint, error) {
+		i := s.ColumnIndex(oldName)
+		if i < 0 {
+			return nil, nil, fmt.Errorf("%w: no column %s", ErrInvalidSchema, oldName)
+		}
+		columns[i].Name = newName
+
+		for j, c := range constraints {
+			if c.Kind == ConstraintCheck {
+				expr, err := parseCheckExpr(c.Check)
+				if err != nil {
+					return nil, nil, err
+				}
+				c.Check = expr.renameColumn(oldName, newName).String()
+			} else {
+				c.Columns = slices.Clone(c.Columns)
+				for k, col := range c.Columns {
+					if strings.EqualFold(col, oldName) {
+						c.Columns[k] = newName
+					}
+				}
+			}
+			c.expr = nil
+			constraints[j] = c
+		}
+
+		return columns, constraints, nil
+	})
+}
+
+// ColumnIndex 返回列的下标，列不存在时返回 -1
+func (s *Schema) ColumnIndex(name string) int {
+	for i, c := range s.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// CastValues 将一行值逐列转换为列的类型
+func (s *Schema) CastValues(values []Value) ([]Value, error) {
+	if len(values) != len(s.Columns) {
+		return nil, fmt.Errorf("%w: expected %d values, got %d", ErrTypeMismatch, len(s.Columns), len(values))
+	}
+
+	cast := make([]Value, len(values))
+	for i, v := range values {
+		var err error
+		if cast[i], err = v.CastAs(s.Columns[i].Type); err != nil {
+			return nil, fmt.Errorf("column %s: %w", s.Columns[i].Name, err)
+		}
+	}
+
+	return cast, nil
+}
+
+func (s *Schema) String() string {
+	parts := make([]string, 0, len(s.Columns)+len(s.Constraints))
+	for _, c := range s.Columns {
+		parts = append(parts, c.String())
+	}
+	for _, c := range s.Constraints {
+		parts = append(parts, c.String())
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
 }
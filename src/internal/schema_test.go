@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewSchema(t *testing.T) {
+	schema, err := NewSchema(
+		NewColumn("id", IntegerType()),
+		NewColumn("name", VarcharType(16)),
+		NewColumn("price", DecimalType(8, 2)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.ColumnIndex("NAME") != 1 || schema.ColumnIndex("missing") != -1 {
+		t.Error("unexpected column index")
+	}
+	if schema.String() != "(id INTEGER, name VARCHAR(16), price DECIMAL(8, 2))" {
+		t.Errorf("unexpected schema %s", schema)
+	}
+
+	cases := map[string][]Column{
+		"no columns":   nil,
+		"empty name":   {NewColumn(" ", IntegerType())},
+		"invalid type": {NewColumn("name", VarcharType(0))},
+		"duplicate":    {NewColumn("id", IntegerType()), NewColumn("ID", BigIntType())},
+	}
+	for name, columns := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewSchema(columns...); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSchema_CastValues(t *testing.T) {
+	schema, err := NewSchema(
+		NewColumn("id", IntegerType()),
+		NewColumn("name", VarcharType(4)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := schema.CastValues([]Value{NewBigInt(7), NewVarchar("abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0].TypeID() != TypeInteger || values[0].Int() != 7 {
+		t.Errorf("unexpected value %v", values[0])
+	}
+
+	if _, err := schema.CastValues([]Value{NewBigInt(7)}); !errors.Is(err, ErrTypeMismatch) {
+		t.Error("expected ErrTypeMismatch")
+	}
+	if _, err := schema.CastValues([]Value{NewBigInt(7), NewVarchar("abcde")}); !errors.Is(err, ErrValueTooLong) {
+		t.Error("expected ErrValueTooLong")
+	}
+	if _, err := schema.CastValues([]Value{NewBigInt(1 << 40), NewNull(TypeVarchar)}); !errors.Is(err, ErrOverflow) {
+		t.Error("expected ErrOverflow")
+	}
+}
+
+func TestSchema_Alter(t *testing.T) {
+	schema, err := NewSchema(
+		NewColumn("id", IntegerType()),
+		NewColumn("name", VarcharType(16)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema = schema.withColumnIDs()
+	if schema.Columns[0].ID != 1 || schema.Columns[1].ID != 2 {
+		t.Fatalf("unexpected column ids %v", schema.Columns)
+	}
+
+	added, err := schema.AddColumn(NewColumn("age", IntegerType()).WithDefault(NewInteger(18)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Version != 1 || added.Columns[2].ID != 3 || added.Columns[2].DefaultValue().Int() != 18 {
+		t.Errorf("unexpected schema %s version %d", added, added.Version)
+	}
+	if added.String() != "(id INTEGER, name VARCHAR(16), age INTEGER DEFAULT 18)" {
+		t.Errorf("unexpected schema %s", added)
+	}
+	if len(schema.Columns) != 2 || schema.Version != 0 {
+		t.Error("altering should not modify the old version")
+	}
+
+	dropped, err := added.DropColumn("NAME")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped.Version != 2 || len(dropped.Columns) != 2 || dropped.ColumnByID(3) != 1 || dropped.ColumnByID(2) != -1 {
+		t.Errorf("unexpected schema %s", dropped)
+	}
+
+	renamed, err := dropped.RenameColumn("age", "years")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Columns[1].Name != "years" || renamed.Columns[1].ID != 3 {
+		t.Errorf("unexpected schema %s", renamed)
+	}
+
+	single := &Schema{Columns: schema.Columns[:1]}
+	newest := &Schema{Columns: schema.Columns, Version: MaxSchemaVersion}
+	badDefault := NewColumn("n", IntegerType()).WithDefault(NewVarchar("x"))
+	cases := map[string]func() (*Schema, error){
+		"add duplicate":     func() (*Schema, error) { return schema.AddColumn(NewColumn("ID", IntegerType())) },
+		"bad default":       func() (*Schema, error) { return schema.AddColumn(badDefault) },
+		"drop missing":      func() (*Schema, error) { return schema.DropColumn("missing") },
+		"drop last":         func() (*Schema, error) { return single.DropColumn("id") },
+		"rename missing":    func() (*Schema, error) { return schema.RenameColumn("missing", "x") },
+		"rename duplicate":  func() (*Schema, error) { return schema.RenameColumn("id", "name") },
+		"too many versions": func() (*Schema, error) { return newest.RenameColumn("id", "x") },
+	}
+	for name, alter := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := alter(); !errors.Is(err, ErrInvalidSchema) {
+				t.Errorf("expected ErrInvalidSchema, got %v", err)
+			}
+		})
+	}
+}
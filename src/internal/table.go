@@ -1,13 +1,573 @@
 package internal
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type Table struct {
-	Name          string
+	Name string
+	// Schema 当前版本的表结构，ALTER TABLE 后会被替换，并发访问时通过 CurrentSchema 读取
 	Schema        *Schema
 	BufferPoolMgr *BufferPoolManager
 	DiskMgr       *DiskManager
-	mu            sync.Mutex
+
+	// mu 串行化表上的写操作，使唯一性检查与写入之间不会插入其他写入，
+	// 同时保护 Schema、versions 和 indexes
+	mu sync.RWMutex
+
+	// Heap 表中元组的存储
+	Heap *TableHeap
+
+	// versions 按版本号记录表结构的所有版本，用于解码旧版本写入的元组
+	versions map[int]*Schema
+
+	// indexes 表上的索引，写入元组时同步维护
+	indexes []*Index
+
+	// foreignKeys 本表引用其他表的外键，referencedBy 其他表引用本表的外键，由 Catalog 连接
+	foreignKeys  []*foreignKey
+	referencedBy []*foreignKey
+
+	// fkMu 连接到外键上之后为 Catalog 中涉及外键的表共用的锁，见 writeScope
+	fkMu atomic.Pointer[sync.Mutex]
+}
+
+// NewTable 创建一张空表
+func NewTable(name string, schema *Schema, bpm *BufferPoolManager) (*Table, error) {
+	heap, err := NewTableHeap(bpm)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTable(name, schema, bpm, heap), nil
+}
+
+// OpenTable 打开第一页为 firstPageID 的已有表
+// 表中有旧版本写入的元组时，需要通过 AddSchemaVersion 提供这些版本的表结构
+func OpenTable(name string, schema *Schema, bpm *BufferPoolManager, firstPageID int) (*Table, error) {
+	heap, err := OpenTableHeap(bpm, firstPageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTable(name, schema, bpm, heap), nil
+}
+
+func newTable(name string, schema *Schema, bpm *BufferPoolManager, heap *TableHeap) *Table {
+	return &Table{
+		Name:          name,
+		Schema:        schema,
+		BufferPoolMgr: bpm,
+		DiskMgr:       bpm.DiskManager,
+		Heap:          heap,
+		versions:      map[int]*Schema{schema.Version: schema},
+	}
+}
+
+// CurrentSchema 返回当前版本的表结构
+func (t *Table) CurrentSchema() *Schema {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.Schema
+}
+
+// AddSchemaVersion 记录一个旧版本的表结构，用于解码该版本写入的元组
+func (t *Table) AddSchemaVersion(schema *Schema) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.versions[schema.Version] = schema
+}
+
+// Alter 把表结构换成更新的版本，已有的元组不会被改写，读取时按写入时的版本解码
+// 新旧版本之间按列 ID 对应，新加的列在旧元组中取默认值
+// 删除索引使用的列时返回 ErrInvalidSchema
+func (t *Table) Alter(schema *Schema) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if schema.Version <= t.Schema.Version {
+		return fmt.Errorf("%w: version %d is not newer than %d", ErrInvalidSchema, schema.Version, t.Schema.Version)
+	}
+
+	keyAttrs := make([][]int, len(t.indexes))
+	for i, idx := range t.indexes {
+		for _, attr := range idx.keyAttrs {
+			col := t.Schema.Columns[attr]
+			if schema.ColumnByID(col.ID) < 0 {
+				return fmt.Errorf("%w: column %s is used by index %s", ErrInvalidSchema, col.Name, idx.Name)
+			}
+		}
+
+		var err error
+		if keyAttrs[i], err = idx.rebind(schema); err != nil {
+			return err
+		}
+	}
+
+	for i, idx := range t.indexes {
+		idx.keyAttrs = keyAttrs[i]
+	}
+	t.versions[schema.Version] = schema
+	t.Schema = schema
+
+	return nil
+}
+
+// decode 按元组写入时的版本解码，再转换为当前版本的一行
+func (t *Table) decode(tuple *Tuple) ([]Value, error) {
+	version := tuple.Version()
+	if version == t.Schema.Version {
+		return tuple.Values(t.Schema)
+	}
+
+	schema, ok := t.versions[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown schema version %d", ErrInvalidTuple, version)
+	}
+	old, err := tuple.Values(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]Value, len(t.Schema.Columns))
+	for i, col := range t.Schema.Columns {
+		if j := schema.ColumnByID(col.ID); j >= 0 {
+			values[i] = old[j]
+		} else {
+			values[i] = col.DefaultValue()
+		}
+	}
+
+	return values, nil
+}
+
+// DecodeTuple 把迭代器返回的元组解码为当前版本的一行
+func (t *Table) DecodeTuple(tuple *Tuple) ([]Value, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.decode(tuple)
+}
+
+// InsertTuple 按 Schema 序列化一行并插入，返回其位置
+// 违反约束时返回 *ConstraintError，违反不属于约束的唯一索引时返回 ErrDuplicateKey，表不会被修改
+func (t *Table) InsertTuple(values []Value) (RID, error) {
+	s := t.lockWrite()
+	defer s.unlock()
+
+	return t.insert(s, values)
+}
+
+// InsertColumns 插入只给出 columns 列的一行，其余的列取默认值，没有默认值时为 NULL
+func (t *Table) InsertColumns(columns []string, values []Value) (RID, error) {
+	s := t.lockWrite()
+	defer s.unlock()
+
+	row, err := t.Schema.FillDefaults(columns, values)
+	if err != nil {
+		return RID{}, err
+	}
+
+	return t.insert(s, row)
+}
+
+func (t *Table) insert(s *writeScope, values []Value) (RID, error) {
+	values, err := t.Schema.CastValues(values)
+	if err != nil {
+		return RID{}, err
+	}
+	tuple, err := NewTuple(t.Schema, values)
+	if err != nil {
+		return RID{}, err
+	}
+	if err := t.checkConstraints(values, RID{PageID: InvalidPageID}); err != nil {
+		return RID{}, err
+	}
+	if err := t.checkReferences(s, nil, values); err != nil {
+		return RID{}, err
+	}
+
+	rid, err := t.Heap.InsertTuple(tuple)
+	if err != nil {
+		return RID{}, err
+	}
+	for i, idx := range t.indexes {
+		if err := idx.InsertEntry(values, rid); err != nil {
+			// 撤销已经加入的索引项和元组
+			for _, idx := range t.indexes[:i] {
+				_ = idx.DeleteEntry(values, rid)
+			}
+			_ = t.Heap.ApplyDelete(rid)
+			return RID{}, err
+		}
+	}
+
+	return rid, nil
+}
+
+// checkConstraints 检查一行是否满足表上的约束和唯一索引，self 为这一行原来的位置
+func (t *Table) checkConstraints(values []Value, self RID) error {
+	if err := t.Schema.CheckRow(values); err != nil {
+		return err
+	}
+
+	for _, idx := range t.indexes {
+		if err := idx.checkUnique(values, self); err != nil {
+			return t.uniqueViolation(idx, values, err)
+		}
+	}
+
+	return nil
+}
+
+// checkRow 检查 rid 处的一行能否更新为 values，返回转换为列类型后的一行，不修改表
+func (t *Table) checkRow(values []Value, rid RID) ([]Value, error) {
+	values, err := t.Schema.CastValues(values)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := NewTuple(t.Schema, values); err != nil {
+		return nil, err
+	}
+	if err := t.checkConstraints(values, rid); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// uniqueViolation 把 idx 上的重复键错误 err 转换为实现 idx 的约束的 *ConstraintError，
+// err 不是重复键或者 idx 不属于 PRIMARY KEY 或 UNIQUE 约束时原样返回 err
+func (t *Table) uniqueViolation(idx *Index, values []Value, err error) error {
+	if !errors.Is(err, ErrDuplicateKey) {
+		return err
+	}
+
+	// PRIMARY KEY 和 UNIQUE 约束由同名的索引实现
+	for _, c := range t.Schema.Constraints {
+		if (c.Kind == ConstraintPrimaryKey || c.Kind == ConstraintUnique) && strings.EqualFold(c.Name, idx.Name) {
+			return &ConstraintError{Kind: c.Kind, Name: c.Name, Detail: formatIndexKey(idx.KeyOf(values)), Err: ErrDuplicateKey}
+		}
+	}
+
+	return err
+}
+
+// GetTuple 读取 rid 处的一行
+func (t *Table) GetTuple(rid RID) ([]Value, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.getTuple(rid)
+}
+
+func (t *Table) getTuple(rid RID) ([]Value, error) {
+	tuple, err := t.Heap.GetTuple(rid)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.decode(tuple)
+}
+
+// UpdateTuple 更新 rid 处的一行并返回其新位置
+// 所在页放不下新的一行时删除旧行并重新插入，位置会改变
+// 违反约束或唯一索引时与 InsertTuple 返回同样的错误，表不会被修改
+// 被其他表的外键引用的列变化时，按外键的 ON UPDATE 处理引用它的行
+func (t *Table) UpdateTuple(rid RID, values []Value) (RID, error) {
+	s := t.lockWrite()
+	defer s.unlock()
+
+	old, err := t.getTuple(rid)
+	if err != nil {
+		return rid, err
+	}
+
+	return t.update(s, rid, old, values)
+}
+
+func (t *Table) update(s *writeScope, rid RID, old, values []Value) (RID, error) {
+	values, err := t.Schema.CastValues(values)
+	if err != nil {
+		return rid, err
+	}
+	tuple, err := NewTuple(t.Schema, values)
+	if err != nil {
+		return rid, err
+	}
+
+	if err := t.checkConstraints(values, rid); err != nil {
+		return rid, err
+	}
+	if err := t.checkReferences(s, old, values); err != nil {
+		return rid, err
+	}
+	if err := t.checkReferencedBy(s, rid, old, values, newCascadeCheck()); err != nil {
+		return rid, err
+	}
+
+	newRID, err := t.updateRow(rid, old, values, tuple)
+	if err != nil {
+		return rid, err
+	}
+
+	return newRID, t.cascade(s, rid, old, values)
+}
+
+// updateRow 把 rid 处的元组换成 tuple，并把索引项从 old 换到 values，返回元组的新位置
+// 任何一步失败时元组和索引都恢复原样
+func (t *Table) updateRow(rid RID, old, values []Value, tuple *Tuple) (RID, error) {
+	prev, err := t.Heap.GetTuple(rid)
+	if err != nil {
+		return rid, err
+	}
+
+	newRID, err := t.updateHeap(rid, tuple)
+	if err != nil {
+		return rid, err
+	}
+	if err := t.updateIndexes(old, values, rid, newRID); err != nil {
+		if newRID == rid {
+			_ = t.Heap.UpdateTuple(rid, prev)
+		} else {
+			_ = t.Heap.ApplyDelete(newRID)
+			_ = t.Heap.RollbackDelete(rid)
+		}
+		return rid, err
+	}
+
+	if newRID != rid {
+		if err := t.Heap.ApplyDelete(rid); err != nil {
+			return newRID, err
+		}
+	}
+	return newRID, nil
+}
+
+// updateHeap 原地更新元组，放不下时把旧元组标记删除后重新插入，旧元组由调用方 ApplyDelete
+// 重新插入失败时撤销标记删除
+func (t *Table) updateHeap(rid RID, tuple *Tuple) (RID, error) {
+	err := t.Heap.UpdateTuple(rid, tuple)
+	if !errors.Is(err, ErrNotEnoughSpace) {
+		return rid, err
+	}
+
+	if err := t.Heap.MarkDelete(rid); err != nil {
+		return rid, err
+	}
+	newRID, err := t.Heap.InsertTuple(tuple)
+	if err != nil {
+		_ = t.Heap.RollbackDelete(rid)
+		return rid, err
+	}
+	return newRID, nil
+}
+
+// updateIndexes 把索引项从旧的一行和位置换到新的一行和位置，键和位置都没变的索引不修改
+// 失败时已经换过的索引项恢复为旧的一行和位置
+func (t *Table) updateIndexes(old, values []Value, rid, newRID RID) error {
+	var moved []*Index
+	for _, idx := range t.indexes {
+		changed, err := idx.keyChanged(old, values)
+		if err == nil && !changed && newRID == rid {
+			continue
+		}
+		if err == nil {
+			err = moveEntry(idx, old, rid, values, newRID)
+		}
+		if err != nil {
+			for _, idx := range moved {
+				_ = moveEntry(idx, values, newRID, old, rid)
+			}
+			return err
+		}
+		moved = append(moved, idx)
+	}
+
+	return nil
+}
+
+// moveEntry 把 idx 中 from 在 fromRID 处的索引项换成 to 在 toRID 处的，失败时 idx 不变
+func moveEntry(idx *Index, from []Value, fromRID RID, to []Value, toRID RID) error {
+	if err := idx.DeleteEntry(from, fromRID); err != nil {
+		return err
+	}
+	if err := idx.InsertEntry(to, toRID); err != nil {
+		_ = idx.InsertEntry(from, fromRID)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteTuple 删除 rid 处的一行
+// 这一行被其他表的外键引用时，按外键的 ON DELETE 处理引用它的行
+func (t *Table) DeleteTuple(rid RID) error {
+	s := t.lockWrite()
+	defer s.unlock()
+
+	old, err := t.getTuple(rid)
+	if err != nil {
+		return err
+	}
+
+	return t.delete(s, rid, old)
+}
+
+func (t *Table) delete(s *writeScope, rid RID, old []Value) error {
+	if err := t.checkReferencedBy(s, rid, old, nil, newCascadeCheck()); err != nil {
+		return err
+	}
+
+	if err := t.Heap.MarkDelete(rid); err != nil {
+		return err
+	}
+	if err := t.Heap.ApplyDelete(rid); err != nil {
+		return err
+	}
+
+	for _, idx := range t.indexes {
+		if err := idx.DeleteEntry(old, rid); err != nil {
+			return err
+		}
+	}
+
+	return t.cascade(s, rid, old, nil)
+}
+
+// StartRewrite 在后台把旧版本写入的元组改写为当前版本，改写期间表可以正常读写
+// 改写不是必需的，旧元组在读取时总能按写入时的版本解码，改写之后读取不再需要转换
+// 返回的 channel 在改写结束后收到一个结果并关闭
+func (t *Table) StartRewrite(ctx context.Context) <-chan error {
+	done := make(chan error, 1)
+
+	go func() {
+		defer close(done)
+
+		done <- t.rewrite(ctx)
+	}()
+
+	return done
+}
+
+func (t *Table) rewrite(ctx context.Context) error {
+	it := t.Iterator()
+	defer it.Close()
+
+	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if it.Tuple().Version() == t.CurrentSchema().Version {
+			continue
+		}
+		if err := t.rewriteTuple(it.RID()); err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}
+
+// rewriteTuple 把 rid 处的元组改写为当前版本，元组已被删除或已是当前版本时什么都不做
+// 改写后的元组可能被移到表的末尾，迭代器再次遇到它时它已是当前版本
+func (t *Table) rewriteTuple(rid RID) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tuple, err := t.Heap.GetTuple(rid)
+	if errors.Is(err, ErrTupleDeleted) || errors.Is(err, ErrInvalidSlot) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if tuple.Version() == t.Schema.Version {
+		return nil
+	}
+
+	values, err := t.decode(tuple)
+	if err != nil {
+		return err
+	}
+	if tuple, err = NewTuple(t.Schema, values); err != nil {
+		return err
+	}
+
+	_, err = t.updateRow(rid, values, values, tuple)
+	return err
+}
+
+// AddIndex 用表中已有的行填充 idx 并在之后的写入中维护它
+// 已有的行违反唯一索引时返回 ErrDuplicateKey，出错时已经加入 idx 的索引项会被删除，索引不会被加到表上
+func (t *Table) AddIndex(idx *Index) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rows, rids, err := t.fillIndex(idx)
+	if err != nil {
+		for i, row := range rows {
+			_ = idx.DeleteEntry(row, rids[i])
+		}
+		return err
+	}
+
+	t.indexes = append(t.indexes, idx)
+	return nil
+}
+
+// fillIndex 把表中的每一行加入 idx，返回已经加入的行和位置，出错时也返回出错之前加入的
+func (t *Table) fillIndex(idx *Index) ([][]Value, []RID, error) {
+	var (
+		rows [][]Value
+		rids []RID
+	)
+
+	it := t.Iterator()
+	defer it.Close()
+	for it.Next() {
+		row, err := t.decode(it.Tuple())
+		if err != nil {
+			return rows, rids, err
+		}
+		if err := idx.checkUnique(row, it.RID()); err != nil {
+			return rows, rids, err
+		}
+		if err := idx.InsertEntry(row, it.RID()); err != nil {
+			return rows, rids, err
+		}
+		rows, rids = append(rows, row), append(rids, it.RID())
+	}
+
+	return rows, rids, it.Err()
+}
+
+// attachIndex 挂上已经包含所有行的索引，用于重新打开表
+func (t *Table) attachIndex(idx *Index) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.indexes = append(t.indexes, idx)
+}
+
+// Indexes 返回表上的索引
+func (t *Table) Indexes() []*Index {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return slices.Clone(t.indexes)
+}
+
+// Iterator 返回全表扫描的迭代器，使用完毕后需要 Close
+// 迭代器返回元组的原始编码，通过 DecodeTuple 得到当前版本的一行
+func (t *Table) Iterator() *TableIterator {
+	return t.Heap.Iterator()
 }
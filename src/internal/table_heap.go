@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RID 元组在表中的位置：页面 id 和槽号
+type RID struct {
+	PageID int
+	Slot   int
+}
+
+func (r RID) String() string {
+	return fmt.Sprintf("(%d, %d)", r.PageID, r.Slot)
+}
+
+// TableHeap 表的存储，由 TablePage 通过前后页指针串成的双向链表
+// 新元组追加到最后一页，放不下时分配新页接到链表末尾
+// 读写元组时持有页面锁，不同页上的操作可以并发进行
+type TableHeap struct {
+	bpm         *BufferPoolManager
+	firstPageID int
+
+	// mu 保护 lastPageID，并串行化插入时的分配新页
+	mu         sync.Mutex
+	lastPageID int
+}
+
+// NewTableHeap 分配第一页并返回空表
+func NewTableHeap(bpm *BufferPoolManager) (*TableHeap, error) {
+	p, err := bpm.NewPage()
+	if err != nil {
+		return nil, err
+	}
+	InitTablePage(p, InvalidPageID)
+	if err := bpm.UnpinPage(p.PageID, true); err != nil {
+		return nil, err
+	}
+
+	return &TableHeap{
+		bpm:         bpm,
+		firstPageID: p.PageID,
+		lastPageID:  p.PageID,
+	}, nil
+}
+
+// OpenTableHeap 打开第一页为 firstPageID 的已有表，沿链表找到最后一页
+func OpenTableHeap(bpm *BufferPoolManager, firstPageID int) (*TableHeap, error) {
+	h := &TableHeap{
+		bpm:         bpm,
+		firstPageID: firstPageID,
+	}
+
+	pageID := firstPageID
+	for {
+		next := InvalidPageID
+		err := h.withPage(pageID, false, func(tp *TablePage) error {
+			next = tp.NextPageID()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if next == InvalidPageID {
+			break
+		}
+		pageID = next
+	}
+	h.lastPageID = pageID
+
+	return h, nil
+}
+
+// FirstPageID 第一页的页面 id，用于重新打开表
+func (h *TableHeap) FirstPageID() int {
+	return h.firstPageID
+}
+
+// withPage pin 住页面并持有页面锁调用 fn，write 为 true 时持有写锁并在 fn 成功后标记为脏页
+func (h *TableHeap) withPage(pageID int, write bool, fn func(tp *TablePage) error) error {
+	p, err := h.bpm.FetchPage(pageID)
+	if err != nil {
+		return err
+	}
+
+	if write {
+		p.mu.Lock()
+	} else {
+		p.mu.RLock()
+	}
+	tp, err := NewTablePage(p)
+	if err == nil {
+		err = fn(tp)
+	}
+	if write {
+		p.mu.Unlock()
+	} else {
+		p.mu.RUnlock()
+	}
+
+	if unpinErr := h.bpm.UnpinPage(pageID, write && err == nil); unpinErr != nil && err == nil {
+		err = unpinErr
+	}
+
+	return err
+}
+
+// InsertTuple 插入元组并返回其位置，最后一页放不下时分配新页
+func (h *TableHeap) InsertTuple(t *Tuple) (RID, error) {
+	if t.Size() == 0 || t.Size() > MaxTupleSizeInPage(PageSize) {
+		return RID{}, fmt.Errorf("%w: tuple of %d bytes", ErrValueTooLong, t.Size())
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	rid := RID{PageID: h.lastPageID}
+	err := h.withPage(h.lastPageID, true, func(tp *TablePage) error {
+		var err error
+		rid.Slot, err = tp.InsertTuple(t)
+		return err
+	})
+	if !errors.Is(err, ErrNotEnoughSpace) {
+		return rid, err
+	}
+
+	// 最后一页已满，分配新页接到链表末尾
+	p, err := h.bpm.NewPage()
+	if err != nil {
+		return RID{}, err
+	}
+	p.mu.Lock()
+	tp := InitTablePage(p, h.lastPageID)
+	rid = RID{PageID: p.PageID}
+	rid.Slot, err = tp.InsertTuple(t)
+	p.mu.Unlock()
+	if unpinErr := h.bpm.UnpinPage(p.PageID, true); err == nil {
+		err = unpinErr
+	}
+	if err != nil {
+		return RID{}, err
+	}
+
+	err = h.withPage(h.lastPageID, true, func(tp *TablePage) error {
+		tp.SetNextPageID(p.PageID)
+		return nil
+	})
+	if err != nil {
+		return RID{}, err
+	}
+	h.lastPageID = p.PageID
+
+	return rid, nil
+}
+
+// GetTuple 读取 rid 处的元组，已标记删除的元组返回 ErrTupleDeleted
+func (h *TableHeap) GetTuple(rid RID) (*Tuple, error) {
+	var t *Tuple
+	err := h.withPage(rid.PageID, false, func(tp *TablePage) error {
+		var err error
+		t, err = tp.GetTuple(rid.Slot)
+		return err
+	})
+
+	return t, err
+}
+
+// UpdateTuple 原地更新 rid 处的元组，元组位置不变
+// 所在页放不下新元组时返回 ErrNotEnoughSpace，由调用方删除后重新插入
+func (h *TableHeap) UpdateTuple(rid RID, t *Tuple) error {
+	return h.withPage(rid.PageID, true, func(tp *TablePage) error {
+		return tp.UpdateTuple(rid.Slot, t)
+	})
+}
+
+// MarkDelete 标记删除 rid 处的元组，在 ApplyDelete 之前可以通过 RollbackDelete 恢复
+func (h *TableHeap) MarkDelete(rid RID) error {
+	return h.withPage(rid.PageID, true, func(tp *TablePage) error {
+		return tp.MarkDelete(rid.Slot)
+	})
+}
+
+// RollbackDelete 撤销 MarkDelete
+func (h *TableHeap) RollbackDelete(rid RID) error {
+	return h.withPage(rid.PageID, true, func(tp *TablePage) error {
+		return tp.RollbackDelete(rid.Slot)
+	})
+}
+
+// ApplyDelete 真正删除 rid 处的元组，槽位之后可以被新元组复用
+func (h *TableHeap) ApplyDelete(rid RID) error {
+	return h.withPage(rid.PageID, true, func(tp *TablePage) error {
+		return tp.ApplyDelete(rid.Slot)
+	})
+}
@@ -0,0 +1,503 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTableHeap(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 4, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	schema := newTablePageSchema(t)
+	heap, err := NewTableHeap(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// enough rows to span several pages
+	rids := make([]RID, 200)
+	pages := make(map[int]bool)
+	for i := range rids {
+		rids[i], err = heap.InsertTuple(newTestTuple(t, schema, int32(i), strings.Repeat("x", 50)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages[rids[i].PageID] = true
+	}
+	if len(pages) < 3 {
+		t.Errorf("expected several pages, got %d", len(pages))
+	}
+
+	for i, rid := range rids {
+		tuple, err := heap.GetTuple(rid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := tuple.GetValue(schema, 0); v.Int() != int64(i) {
+			t.Errorf("%v: expected %d, got %v", rid, i, v)
+		}
+	}
+
+	t.Run("update", func(t *testing.T) {
+		if err := heap.UpdateTuple(rids[5], newTestTuple(t, schema, -5, "short")); err != nil {
+			t.Fatal(err)
+		}
+		tuple, err := heap.GetTuple(rids[5])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := tuple.GetValue(schema, 0); v.Int() != -5 {
+			t.Errorf("expected -5, got %v", v)
+		}
+
+		huge := newTestTuple(t, schema, 0, strings.Repeat("x", 3000))
+		if err := heap.UpdateTuple(rids[6], huge); !errors.Is(err, ErrNotEnoughSpace) {
+			t.Errorf("expected ErrNotEnoughSpace, got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := heap.MarkDelete(rids[7]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := heap.GetTuple(rids[7]); !errors.Is(err, ErrTupleDeleted) {
+			t.Error("expected ErrTupleDeleted")
+		}
+		if err := heap.RollbackDelete(rids[7]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := heap.GetTuple(rids[7]); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := heap.MarkDelete(rids[7]); err != nil {
+			t.Fatal(err)
+		}
+		if err := heap.ApplyDelete(rids[7]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := heap.GetTuple(rids[7]); !errors.Is(err, ErrInvalidSlot) {
+			t.Error("expected ErrInvalidSlot")
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		if _, err := heap.InsertTuple(&Tuple{Data: make([]byte, PageSize)}); !errors.Is(err, ErrValueTooLong) {
+			t.Error("expected ErrValueTooLong")
+		}
+	})
+
+	t.Run("reopen", func(t *testing.T) {
+		reopened, err := OpenTableHeap(bm, heap.FirstPageID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reopened.lastPageID != heap.lastPageID {
+			t.Errorf("expected last page %d, got %d", heap.lastPageID, reopened.lastPageID)
+		}
+
+		rid, err := reopened.InsertTuple(newTestTuple(t, schema, 1000, "appended"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := heap.GetTuple(rid); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestTableHeap_Concurrent(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	schema := newTablePageSchema(t)
+	heap, err := NewTableHeap(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				tuple, err := NewTuple(schema, []Value{NewInteger(int32(w*1000 + i)), NewVarchar(fmt.Sprintf("worker %d", w))})
+				if err != nil {
+					errs <- err
+					return
+				}
+				rid, err := heap.InsertTuple(tuple)
+				if err != nil {
+					errs <- err
+					return
+				}
+				got, err := heap.GetTuple(rid)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if v, _ := got.GetValue(schema, 0); v.Int() != int64(w*1000+i) {
+					errs <- fmt.Errorf("%v: unexpected %v", rid, v)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestTable(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 4, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	schema := newTablePageSchema(t)
+	table, err := NewTable("users", schema, bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rid, err := table.InsertTuple([]Value{NewInteger(1), NewVarchar("alice")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fill the rest of the page so a larger row has to move
+	for i := 0; i < 100; i++ {
+		if _, err := table.InsertTuple([]Value{NewInteger(int32(i)), NewVarchar(strings.Repeat("y", 30))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved, err := table.UpdateTuple(rid, []Value{NewInteger(1), NewVarchar(strings.Repeat("alice", 100))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved == rid {
+		t.Error("expected the row to move")
+	}
+	values, err := table.GetTuple(moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[1].Str() != strings.Repeat("alice", 100) {
+		t.Errorf("unexpected value %v", values[1])
+	}
+	if _, err := table.GetTuple(rid); err == nil {
+		t.Error("expected the old row to be gone")
+	}
+
+	if err := table.DeleteTuple(moved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.GetTuple(moved); err == nil {
+		t.Error("expected the row to be deleted")
+	}
+
+	if _, err := table.InsertTuple([]Value{NewVarchar("x")}); !errors.Is(err, ErrTypeMismatch) {
+		t.Error("expected ErrTypeMismatch")
+	}
+}
+
+func TestTable_IndexFailure(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	schema := newTablePageSchema(t).withColumnIDs()
+	table, err := NewTable("users", schema, bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID, err := NewIndex(bm, "users_id", schema, []int{0}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName, err := NewIndex(bm, "users_name", schema, []int{1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, idx := range []*Index{byID, byName} {
+		if err := table.AddIndex(idx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rid, err := table.InsertTuple([]Value{NewInteger(1), NewVarchar("alice")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 填满这一页，更大的一行需要移到别处
+	for i := 0; i < 100; i++ {
+		if _, err := table.InsertTuple([]Value{NewInteger(int32(i + 10)), NewVarchar(strings.Repeat("y", 30))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 让 byName 的根节点指向不存在的槽，之后对它的读写都会失败
+	root := byName.trie.root
+	byName.trie.root.slot = math.MaxUint16
+	if _, err := table.InsertTuple([]Value{NewInteger(2), NewVarchar("bob")}); !errors.Is(err, ErrInvalidTriePage) {
+		t.Errorf("expected ErrInvalidTriePage on insert, got %v", err)
+	}
+	for _, name := range []string{"carol", strings.Repeat("carol", 100)} {
+		if _, err := table.UpdateTuple(rid, []Value{NewInteger(3), NewVarchar(name)}); !errors.Is(err, ErrInvalidTriePage) {
+			t.Errorf("expected ErrInvalidTriePage on update, got %v", err)
+		}
+	}
+	byName.trie.root = root
+
+	// 失败的写入没有留下元组和索引项
+	if values, err := table.GetTuple(rid); err != nil || values[0].Int() != 1 || values[1].Str() != "alice" {
+		t.Errorf("unexpected row %v, %v", values, err)
+	}
+	for id, expected := range map[int32][]RID{1: {rid}, 2: nil, 3: nil} {
+		if got, err := byID.ScanKey([]Value{NewInteger(id)}); err != nil || !slices.Equal(got, expected) {
+			t.Errorf("id %d: expected %v, got %v, %v", id, expected, got, err)
+		}
+	}
+	if got, err := byName.ScanKey([]Value{NewVarchar("alice")}); err != nil || !slices.Equal(got, []RID{rid}) {
+		t.Errorf("expected %v, got %v, %v", []RID{rid}, got, err)
+	}
+	count := 0
+	err = scanTable(table, func(RID, []Value) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 101 {
+		t.Errorf("expected 101 rows, got %d", count)
+	}
+
+	if _, err := table.InsertTuple([]Value{NewInteger(2), NewVarchar("bob")}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// 填充唯一索引时遇到重复的键，已经加入的索引项被删除
+	dup, err := NewIndex(bm, "users_name_key", schema, []int{1}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.AddIndex(dup); !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+	if got, err := dup.ScanKey([]Value{NewVarchar("alice")}); err != nil || len(got) != 0 {
+		t.Errorf("expected no entries, got %v, %v", got, err)
+	}
+	if len(table.Indexes()) != 2 {
+		t.Errorf("expected 2 indexes, got %d", len(table.Indexes()))
+	}
+}
+
+func TestTable_Alter(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	v0 := newTablePageSchema(t).withColumnIDs()
+	table, err := NewTable("users", v0, bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rids []RID
+	for i := 0; i < 50; i++ {
+		rid, err := table.InsertTuple([]Value{NewInteger(int32(i)), NewVarchar(strings.Repeat("x", 60))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rids = append(rids, rid)
+	}
+	idx, err := NewIndex(bm, "users_id", v0, []int{0}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.AddIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+
+	v1, err := v0.AddColumn(NewColumn("score", BigIntType()).WithDefault(NewInteger(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := v1.DropColumn("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Alter(v1); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Alter(v2); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Alter(v1); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema for an older version, got %v", err)
+	}
+
+	// 旧元组按写入时的版本解码，新加的列取默认值
+	values, err := table.GetTuple(rids[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[0].Int() != 3 || values[1].TypeID() != TypeBigInt || values[1].Int() != 10 {
+		t.Errorf("unexpected row %v", values)
+	}
+
+	newRID, err := table.InsertTuple([]Value{NewInteger(100), NewBigInt(7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.InsertTuple([]Value{NewInteger(3), NewBigInt(7)}); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected the index to survive the alter, got %v", err)
+	}
+
+	dropID, err := v2.DropColumn("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Alter(dropID); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("expected ErrInvalidSchema when dropping an indexed column, got %v", err)
+	}
+
+	if err := <-table.StartRewrite(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	it := table.Iterator()
+	count := 0
+	for it.Next() {
+		count++
+		if it.Tuple().Version() != v2.Version {
+			t.Errorf("tuple %v still has version %d", it.RID(), it.Tuple().Version())
+		}
+		values, err := table.DecodeTuple(it.Tuple())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rids, _ := idx.ScanKey(values[:1]); len(rids) != 1 || rids[0] != it.RID() {
+			t.Errorf("index points to %v for row at %v", rids, it.RID())
+		}
+	}
+	it.Close()
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 51 {
+		t.Errorf("expected 51 rows, got %d", count)
+	}
+	if values, err := table.GetTuple(newRID); err != nil || values[1].Int() != 7 {
+		t.Errorf("unexpected row %v, %v", values, err)
+	}
+}
+
+func TestTable_Constraints(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	schema := newConstraintSchema(t).withColumnIDs()
+	table, err := NewTable("items", schema, bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range schema.Constraints[:2] {
+		idx, err := NewIndex(bm, c.Name, schema, []int{schema.ColumnIndex(c.Columns[0])}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := table.AddIndex(idx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rid, err := table.InsertColumns([]string{"id", "email"}, []Value{NewInteger(1), NewVarchar("a@x.org")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values, _ := table.GetTuple(rid); values[2].String() != "1.00" {
+		t.Errorf("expected the default price, got %v", values)
+	}
+	other, err := table.InsertTuple([]Value{NewInteger(2), NewVarchar("b@x.org"), mustDecimal(500, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		values []Value
+		target error
+		cName  string
+	}{
+		{"primary key", []Value{NewInteger(1), NewVarchar("c@x.org"), mustDecimal(100, 2)}, ErrPrimaryKeyViolation, "items_pkey"},
+		{"unique", []Value{NewInteger(3), NewVarchar("a@x.org"), mustDecimal(100, 2)}, ErrUniqueViolation, "items_email_key"},
+		{"not null", []Value{NewInteger(3), NewNull(TypeVarchar), mustDecimal(100, 2)}, ErrNotNullViolation, "email"},
+		{"check", []Value{NewInteger(3), NewVarchar("c@x.org"), mustDecimal(0, 2)}, ErrCheckViolation, "positive_price"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := table.InsertTuple(c.values)
+			if !errors.Is(err, c.target) {
+				t.Fatalf("expected %v, got %v", c.target, err)
+			}
+			var cErr *ConstraintError
+			if !errors.As(err, &cErr) || cErr.Name != c.cName {
+				t.Errorf("expected a violation of %s, got %v", c.cName, err)
+			}
+
+			if _, err := table.UpdateTuple(other, c.values); !errors.Is(err, c.target) {
+				t.Errorf("expected %v on update, got %v", c.target, err)
+			}
+		})
+	}
+	if _, err := table.InsertTuple(cases[0].values); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected a key violation to also match ErrDuplicateKey, got %v", err)
+	}
+
+	// 失败的写入不修改表
+	if values, err := table.GetTuple(other); err != nil || values[0].Int() != 2 || values[1].Str() != "b@x.org" {
+		t.Errorf("unexpected row %v, %v", values, err)
+	}
+	count := 0
+	err = scanTable(table, func(RID, []Value) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 rows, got %d", count)
+	}
+
+	// 一行与自己不冲突，CHECK 的结果未知时可以写入
+	if _, err := table.UpdateTuple(other, []Value{NewInteger(2), NewVarchar("b@x.org"), NewNull(TypeDecimal)}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"bytes"
+)
+
+// TableIterator 按链表顺序顺序扫描 TableHeap，跳过已删除的元组
+// 迭代器 pin 住当前所在的页，离开该页时 unpin，读取槽时只短暂持有页面读锁，
+// 因此扫描期间表可以被并发修改：已扫过的位置上的修改不可见，尚未扫到的位置上的修改可见
+// 页面以 AccessScan 方式读取，全表扫描不会把热点页面挤出缓冲池
+//
+//	it := heap.Iterator()
+//	defer it.Close()
+//	for it.Next() {
+//		rid, tuple := it.RID(), it.Tuple()
+//	}
+//	return it.Err()
+type TableIterator struct {
+	heap *TableHeap
+
+	// page 当前 pin 住的页，为 nil 时下一次 Next 读取 pageID
+	page   *Page
+	pageID int
+	slot   int
+
+	rid   RID
+	tuple *Tuple
+	err   error
+}
+
+// Iterator 返回从第一页开始的迭代器，使用完毕后需要 Close
+func (h *TableHeap) Iterator() *TableIterator {
+	return &TableIterator{
+		heap:   h,
+		pageID: h.firstPageID,
+		slot:   -1,
+	}
+}
+
+// Next 前进到下一个未删除的元组，没有更多元组或出错时返回 false
+func (it *TableIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	for {
+		if it.page == nil {
+			if it.pageID == InvalidPageID {
+				return false
+			}
+
+			p, err := it.heap.bpm.FetchPageWithAccess(it.pageID, AccessScan)
+			if err != nil {
+				it.err = err
+				return false
+			}
+			it.page = p
+		}
+
+		found, next, err := it.advance()
+		if err != nil {
+			it.err = err
+			it.Close()
+			return false
+		}
+		if found {
+			return true
+		}
+
+		// 当前页扫完了，unpin 后进入下一页
+		it.Close()
+		it.pageID = next
+		it.slot = -1
+	}
+}
+
+// advance 在当前页中找下一个未删除的元组，找不到时返回下一页的页面 id
+func (it *TableIterator) advance() (bool, int, error) {
+	it.page.mu.RLock()
+	defer it.page.mu.RUnlock()
+
+	tp, err := NewTablePage(it.page)
+	if err != nil {
+		return false, InvalidPageID, err
+	}
+
+	for it.slot+1 < tp.SlotCount() {
+		it.slot++
+		offset, size, deleted := tp.slot(it.slot)
+		if size == 0 || deleted {
+			continue
+		}
+
+		it.rid = RID{PageID: it.pageID, Slot: it.slot}
+		it.tuple = &Tuple{Data: bytes.Clone(it.page.Data[offset : offset+size])}
+		return true, InvalidPageID, nil
+	}
+
+	return false, tp.NextPageID(), nil
+}
+
+// RID 当前元组的位置
+func (it *TableIterator) RID() RID {
+	return it.rid
+}
+
+// Tuple 当前元组的副本
+func (it *TableIterator) Tuple() *Tuple {
+	return it.tuple
+}
+
+// Err 返回迭代中遇到的错误
+func (it *TableIterator) Err() error {
+	return it.err
+}
+
+// Close unpin 当前所在的页，可以重复调用
+func (it *TableIterator) Close() {
+	if it.page == nil {
+		return
+	}
+
+	if err := it.heap.bpm.UnpinPage(it.pageID, false); err != nil && it.err == nil {
+		it.err = err
+	}
+	it.page = nil
+}
@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// scanIDs collects the id column of every tuple returned by the iterator
+func scanIDs(t *testing.T, it *TableIterator, schema *Schema) []int64 {
+	defer it.Close()
+
+	var ids []int64
+	for it.Next() {
+		v, err := it.Tuple().GetValue(schema, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, v.Int())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestTableIterator(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 4, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	schema := newTablePageSchema(t)
+	heap, err := NewTableHeap(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		if ids := scanIDs(t, heap.Iterator(), schema); len(ids) != 0 {
+			t.Errorf("expected no tuples, got %v", ids)
+		}
+	})
+
+	var expected []int64
+	rids := make(map[int64]RID)
+	for i := int64(0); i < 300; i++ {
+		rid, err := heap.InsertTuple(newTestTuple(t, schema, int32(i), strings.Repeat("z", 40)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rids[i] = rid
+		expected = append(expected, i)
+	}
+
+	t.Run("chain order", func(t *testing.T) {
+		if ids := scanIDs(t, heap.Iterator(), schema); !slices.Equal(ids, expected) {
+			t.Errorf("expected %d tuples in insertion order, got %d", len(expected), len(ids))
+		}
+	})
+
+	t.Run("skip deleted", func(t *testing.T) {
+		var remaining []int64
+		for _, id := range expected {
+			switch {
+			case id%3 == 0:
+				if err := heap.MarkDelete(rids[id]); err != nil {
+					t.Fatal(err)
+				}
+			case id%3 == 1:
+				if err := heap.MarkDelete(rids[id]); err != nil {
+					t.Fatal(err)
+				}
+				if err := heap.ApplyDelete(rids[id]); err != nil {
+					t.Fatal(err)
+				}
+			default:
+				remaining = append(remaining, id)
+			}
+		}
+
+		if ids := scanIDs(t, heap.Iterator(), schema); !slices.Equal(ids, remaining) {
+			t.Errorf("expected %v, got %v", remaining, ids)
+		}
+	})
+
+	t.Run("early close", func(t *testing.T) {
+		it := heap.Iterator()
+		if !it.Next() {
+			t.Fatal("expected a tuple")
+		}
+		it.Close()
+		it.Close()
+	})
+}
+
+func TestTableIterator_Concurrent(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 8, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	schema := newTablePageSchema(t)
+	heap, err := NewTableHeap(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		if _, err := heap.InsertTuple(newTestTuple(t, schema, int32(i), "before")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	errs := make(chan error, 1)
+	go func() {
+		defer wg.Done()
+		for i := 200; i < 400; i++ {
+			tuple, err := NewTuple(schema, []Value{NewInteger(int32(i)), NewVarchar("during")})
+			if err == nil {
+				_, err = heap.InsertTuple(tuple)
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	// every tuple inserted before the scan is seen exactly once
+	ids := scanIDs(t, heap.Iterator(), schema)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	seen := make(map[int64]int)
+	for _, id := range ids {
+		seen[id]++
+	}
+	for i := int64(0); i < 200; i++ {
+		if seen[i] != 1 {
+			t.Errorf("expected tuple %d once, got %d", i, seen[i])
+		}
+	}
+}
+
+func TestTableIterator_ScanResistance(t *testing.T) {
+	dm := setupDiskManager(t)
+	defer cleanupDiskManager(dm)
+
+	bm := NewBufferPoolManager(dm, 4, PageSize, 2)
+	defer bm.AssertNoPinLeaks(t)
+
+	schema := newTablePageSchema(t)
+	heap, err := NewTableHeap(bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// two table pages, both stay resident
+	for i := 0; i < 80; i++ {
+		if _, err := heap.InsertTuple(newTestTuple(t, schema, int32(i), strings.Repeat("s", 60))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if heap.lastPageID == heap.firstPageID {
+		t.Fatal("expected two table pages")
+	}
+
+	// a hot page accessed k times after the inserts
+	hot, err := bm.NewPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hotID := hot.PageID
+	if err := bm.UnpinPage(hotID, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.FetchPage(hotID); err != nil {
+		t.Fatal(err)
+	}
+	if err := bm.UnpinPage(hotID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// repeated scans must not make the table pages look hotter than the hot page
+	for i := 0; i < 3; i++ {
+		if ids := scanIDs(t, heap.Iterator(), schema); len(ids) != 80 {
+			t.Fatalf("expected 80 tuples, got %d", len(ids))
+		}
+	}
+
+	// two new pages need one eviction among the hot and the table pages
+	var pinned []int
+	for i := 0; i < 2; i++ {
+		p, err := bm.NewPage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pinned = append(pinned, p.PageID)
+	}
+
+	bm.mu.Lock()
+	_, resident := bm.PageTable[hotID]
+	bm.mu.Unlock()
+	if !resident {
+		t.Error("expected the hot page to survive the scans")
+	}
+
+	for _, pageID := range pinned {
+		if err := bm.UnpinPage(pageID, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
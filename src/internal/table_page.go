@@ -0,0 +1,334 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// PageTypeTable 表数据页
+const PageTypeTable = 1
+
+// 表数据页格式：
+//
+//	| 页面类型 u8 | 保留 3 字节 | LSN u64 | 页面 id i32 | 前一页 i32 | 后一页 i32 | 槽数 u16 | 空闲空间指针 u16 |
+//	| 槽目录，从前往后增长 ... 空闲空间 ... 元组，从后往前增长 |
+//
+// 每个槽为元组的偏移 u16 和长度 u16，长度的最高位为删除标记
+// 长度为 0 的槽是空槽，插入时会被复用，槽号因此在元组的生命周期内保持不变
+const (
+	tablePageTypeOffset      = 0
+	tablePageLSNOffset       = 4
+	tablePageIDOffset        = 12
+	tablePagePrevOffset      = 16
+	tablePageNextOffset      = 20
+	tablePageSlotCountOffset = 24
+	tablePageFreePtrOffset   = 26
+	tablePageHeaderSize      = 28
+	tablePageSlotSize        = 4
+
+	tupleDeletedFlag = 1 << 15
+)
+
+// TablePage 以表数据页格式读写 Page.Data
+// 调用方负责 pin 住页面并持有页面锁，修改页面后以 isDirty 为 true 调用 UnpinPage
+type TablePage struct {
+	page *Page
+}
+
+// NewTablePage 将已初始化的表数据页包装为 TablePage
+func NewTablePage(p *Page) (*TablePage, error) {
+	if p.Data[tablePageTypeOffset] != PageTypeTable {
+		return nil, fmt.Errorf("%w: page %d is not a table page", ErrInvalidTablePage, p.PageID)
+	}
+
+	return &TablePage{page: p}, nil
+}
+
+// InitTablePage 将页面初始化为空的表数据页
+func InitTablePage(p *Page, prevPageID int) *TablePage {
+	clear(p.Data)
+	tp := &TablePage{page: p}
+	p.Data[tablePageTypeOffset] = PageTypeTable
+	tp.putInt32(tablePageIDOffset, p.PageID)
+	tp.putInt32(tablePagePrevOffset, prevPageID)
+	tp.putInt32(tablePageNextOffset, InvalidPageID)
+	tp.putUint16(tablePageFreePtrOffset, len(p.Data))
+	return tp
+}
+
+func (tp *TablePage) getUint16(off int) int {
+	return int(binary.LittleEndian.Uint16(tp.page.Data[off:]))
+}
+
+func (tp *TablePage) putUint16(off, v int) {
+	binary.LittleEndian.PutUint16(tp.page.Data[off:], uint16(v))
+}
+
+func (tp *TablePage) getInt32(off int) int {
+	return int(int32(binary.LittleEndian.Uint32(tp.page.Data[off:])))
+}
+
+func (tp *TablePage) putInt32(off, v int) {
+	binary.LittleEndian.PutUint32(tp.page.Data[off:], uint32(int32(v)))
+}
+
+// Page 返回底层页面
+func (tp *TablePage) Page() *Page {
+	return tp.page
+}
+
+func (tp *TablePage) PageID() int {
+	return tp.getInt32(tablePageIDOffset)
+}
+
+func (tp *TablePage) PrevPageID() int {
+	return tp.getInt32(tablePagePrevOffset)
+}
+
+func (tp *TablePage) NextPageID() int {
+	return tp.getInt32(tablePageNextOffset)
+}
+
+func (tp *TablePage) SetPrevPageID(pageID int) {
+	tp.putInt32(tablePagePrevOffset, pageID)
+}
+
+func (tp *TablePage) SetNextPageID(pageID int) {
+	tp.putInt32(tablePageNextOffset, pageID)
+}
+
+// LSN 最后一次修改页面的日志序列号
+func (tp *TablePage) LSN() uint64 {
+	return binary.LittleEndian.Uint64(tp.page.Data[tablePageLSNOffset:])
+}
+
+func (tp *TablePage) SetLSN(lsn uint64) {
+	binary.LittleEndian.PutUint64(tp.page.Data[tablePageLSNOffset:], lsn)
+}
+
+// SlotCount 槽目录的长度，包括已删除和空的槽
+func (tp *TablePage) SlotCount() int {
+	return tp.getUint16(tablePageSlotCountOffset)
+}
+
+func (tp *TablePage) slot(slot int) (offset, size int, deleted bool) {
+	pos := tablePageHeaderSize + slot*tablePageSlotSize
+	size = tp.getUint16(pos + 2)
+	return tp.getUint16(pos), size &^ tupleDeletedFlag, size&tupleDeletedFlag != 0
+}
+
+func (tp *TablePage) setSlot(slot, offset, size int, deleted bool) {
+	pos := tablePageHeaderSize + slot*tablePageSlotSize
+	if deleted {
+		size |= tupleDeletedFlag
+	}
+	tp.putUint16(pos, offset)
+	tp.putUint16(pos+2, size)
+}
+
+// FreeSpace 槽目录和元组之间连续的空闲字节数
+func (tp *TablePage) FreeSpace() int {
+	return tp.getUint16(tablePageFreePtrOffset) - tablePageHeaderSize - tp.SlotCount()*tablePageSlotSize
+}
+
+// reclaimableSpace 整理页面后可用的空闲字节数
+func (tp *TablePage) reclaimableSpace() int {
+	used := 0
+	for i := 0; i < tp.SlotCount(); i++ {
+		_, size, _ := tp.slot(i)
+		used += size
+	}
+
+	return len(tp.page.Data) - tablePageHeaderSize - tp.SlotCount()*tablePageSlotSize - used
+}
+
+// MaxTupleSizeInPage 一个空页能放下的最大元组
+func MaxTupleSizeInPage(pageSize int) int {
+	return pageSize - tablePageHeaderSize - tablePageSlotSize
+}
+
+// checkSlot 检查槽号是否存在且槽不为空
+func (tp *TablePage) checkSlot(slot int) error {
+	if slot < 0 || slot >= tp.SlotCount() {
+		return fmt.Errorf("%w: slot %d of page %d", ErrInvalidSlot, slot, tp.page.PageID)
+	}
+	if _, size, _ := tp.slot(slot); size == 0 {
+		return fmt.Errorf("%w: slot %d of page %d is empty", ErrInvalidSlot, slot, tp.page.PageID)
+	}
+
+	return nil
+}
+
+// InsertTuple 插入元组并返回槽号，优先复用空槽，连续空间不够时先整理页面
+// 页面放不下时返回 ErrNotEnoughSpace
+func (tp *TablePage) InsertTuple(t *Tuple) (int, error) {
+	if t.Size() == 0 || t.Size() > MaxTupleSizeInPage(len(tp.page.Data)) {
+		return -1, fmt.Errorf("%w: tuple of %d bytes", ErrValueTooLong, t.Size())
+	}
+
+	numSlots := tp.SlotCount()
+	slot := numSlots
+	for i := 0; i < numSlots; i++ {
+		if _, size, _ := tp.slot(i); size == 0 {
+			slot = i
+			break
+		}
+	}
+
+	need := t.Size()
+	if slot == numSlots {
+		need += tablePageSlotSize
+	}
+	if !tp.reserve(need) {
+		return -1, ErrNotEnoughSpace
+	}
+
+	if slot == numSlots {
+		tp.putUint16(tablePageSlotCountOffset, numSlots+1)
+	}
+	tp.write(slot, t.Data)
+
+	return slot, nil
+}
+
+// GetTuple 返回槽中元组的副本，已标记删除的元组返回 ErrTupleDeleted
+func (tp *TablePage) GetTuple(slot int) (*Tuple, error) {
+	if err := tp.checkSlot(slot); err != nil {
+		return nil, err
+	}
+
+	offset, size, deleted := tp.slot(slot)
+	if deleted {
+		return nil, fmt.Errorf("%w: slot %d of page %d", ErrTupleDeleted, slot, tp.page.PageID)
+	}
+
+	return &Tuple{Data: bytes.Clone(tp.page.Data[offset : offset+size])}, nil
+}
+
+// IsDeleted 返回槽中的元组是否已标记删除
+func (tp *TablePage) IsDeleted(slot int) bool {
+	if tp.checkSlot(slot) != nil {
+		return false
+	}
+
+	_, _, deleted := tp.slot(slot)
+	return deleted
+}
+
+// MarkDelete 标记删除元组，元组仍占用空间，可以通过 RollbackDelete 恢复
+func (tp *TablePage) MarkDelete(slot int) error {
+	if err := tp.checkSlot(slot); err != nil {
+		return err
+	}
+
+	offset, size, deleted := tp.slot(slot)
+	if deleted {
+		return fmt.Errorf("%w: slot %d of page %d", ErrTupleDeleted, slot, tp.page.PageID)
+	}
+	tp.setSlot(slot, offset, size, true)
+
+	return nil
+}
+
+// RollbackDelete 撤销 MarkDelete
+func (tp *TablePage) RollbackDelete(slot int) error {
+	if err := tp.checkSlot(slot); err != nil {
+		return err
+	}
+
+	offset, size, _ := tp.slot(slot)
+	tp.setSlot(slot, offset, size, false)
+
+	return nil
+}
+
+// ApplyDelete 真正删除元组，槽变为空槽，空间在下次整理时回收
+func (tp *TablePage) ApplyDelete(slot int) error {
+	if err := tp.checkSlot(slot); err != nil {
+		return err
+	}
+
+	tp.setSlot(slot, 0, 0, false)
+
+	return nil
+}
+
+// UpdateTuple 原地更新元组，槽号不变
+// 新元组不大于旧元组时直接覆盖，否则写到空闲空间中，页面放不下时返回 ErrNotEnoughSpace 且不修改页面
+func (tp *TablePage) UpdateTuple(slot int, t *Tuple) error {
+	if err := tp.checkSlot(slot); err != nil {
+		return err
+	}
+
+	offset, size, deleted := tp.slot(slot)
+	if deleted {
+		return fmt.Errorf("%w: slot %d of page %d", ErrTupleDeleted, slot, tp.page.PageID)
+	}
+	if t.Size() == 0 {
+		return fmt.Errorf("%w: empty tuple", ErrInvalidTuple)
+	}
+
+	if t.Size() <= size {
+		copy(tp.page.Data[offset:], t.Data)
+		tp.setSlot(slot, offset, t.Size(), false)
+		return nil
+	}
+
+	// 旧元组的空间在整理后可以复用
+	if tp.reclaimableSpace()+size < t.Size() {
+		return ErrNotEnoughSpace
+	}
+	tp.setSlot(slot, 0, 0, false)
+	tp.reserve(t.Size())
+	tp.write(slot, t.Data)
+
+	return nil
+}
+
+// Compact 把所有元组紧凑地移到页尾，回收已删除元组和更新留下的空间，槽号不变
+func (tp *TablePage) Compact() {
+	numSlots := tp.SlotCount()
+
+	type entry struct {
+		data    []byte
+		deleted bool
+	}
+	entries := make([]entry, numSlots)
+	for i := range entries {
+		offset, size, deleted := tp.slot(i)
+		if size > 0 {
+			entries[i] = entry{bytes.Clone(tp.page.Data[offset : offset+size]), deleted}
+		}
+	}
+
+	tp.putUint16(tablePageFreePtrOffset, len(tp.page.Data))
+	for i, e := range entries {
+		if e.data == nil {
+			tp.setSlot(i, 0, 0, false)
+			continue
+		}
+		tp.write(i, e.data)
+		if e.deleted {
+			tp.setSlot(i, tp.getUint16(tablePageFreePtrOffset), len(e.data), true)
+		}
+	}
+}
+
+// reserve 确保有 size 字节的连续空闲空间，必要时整理页面
+func (tp *TablePage) reserve(size int) bool {
+	if tp.FreeSpace() >= size {
+		return true
+	}
+
+	tp.Compact()
+	return tp.FreeSpace() >= size
+}
+
+// write 将数据写到空闲空间的末尾，调用方需确保空间足够
+func (tp *TablePage) write(slot int, data []byte) {
+	freePtr := tp.getUint16(tablePageFreePtrOffset) - len(data)
+	copy(tp.page.Data[freePtr:], data)
+	tp.putUint16(tablePageFreePtrOffset, freePtr)
+	tp.setSlot(slot, freePtr, len(data), false)
+}
@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestTuple(t *testing.T, schema *Schema, id int32, name string) *Tuple {
+	tuple, err := NewTuple(schema, []Value{NewInteger(id), NewVarchar(name)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tuple
+}
+
+func newTablePageSchema(t *testing.T) *Schema {
+	schema, err := NewSchema(NewColumn("id", IntegerType()), NewColumn("name", VarcharType(PageSize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestTablePage(t *testing.T) {
+	schema := newTablePageSchema(t)
+	p := &Page{PageID: 3, Data: make([]byte, PageSize)}
+
+	if _, err := NewTablePage(p); !errors.Is(err, ErrInvalidTablePage) {
+		t.Error("expected ErrInvalidTablePage")
+	}
+
+	tp := InitTablePage(p, 2)
+	if tp.PageID() != 3 || tp.PrevPageID() != 2 || tp.NextPageID() != InvalidPageID {
+		t.Errorf("unexpected header: %d %d %d", tp.PageID(), tp.PrevPageID(), tp.NextPageID())
+	}
+	tp.SetNextPageID(4)
+	tp.SetLSN(99)
+	if tp, err := NewTablePage(p); err != nil || tp.NextPageID() != 4 || tp.LSN() != 99 {
+		t.Errorf("unexpected header after reopen: %v", err)
+	}
+
+	t.Run("insert and get", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			slot, err := tp.InsertTuple(newTestTuple(t, schema, int32(i), "row"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if slot != i {
+				t.Errorf("expected slot %d, got %d", i, slot)
+			}
+		}
+
+		tuple, err := tp.GetTuple(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := tuple.GetValue(schema, 0); v.Int() != 1 {
+			t.Errorf("expected 1, got %v", v)
+		}
+
+		if _, err := tp.GetTuple(3); !errors.Is(err, ErrInvalidSlot) {
+			t.Error("expected ErrInvalidSlot")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := tp.MarkDelete(1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tp.GetTuple(1); !errors.Is(err, ErrTupleDeleted) || !tp.IsDeleted(1) {
+			t.Error("expected ErrTupleDeleted")
+		}
+		if err := tp.RollbackDelete(1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tp.GetTuple(1); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tp.MarkDelete(1); err != nil {
+			t.Fatal(err)
+		}
+		if err := tp.ApplyDelete(1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tp.GetTuple(1); !errors.Is(err, ErrInvalidSlot) {
+			t.Error("expected ErrInvalidSlot after ApplyDelete")
+		}
+
+		// the empty slot is reused
+		slot, err := tp.InsertTuple(newTestTuple(t, schema, 10, "reused"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slot != 1 {
+			t.Errorf("expected slot 1, got %d", slot)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		if err := tp.UpdateTuple(0, newTestTuple(t, schema, 0, "r")); err != nil {
+			t.Fatal(err)
+		}
+		if err := tp.UpdateTuple(0, newTestTuple(t, schema, 0, strings.Repeat("grown", 10))); err != nil {
+			t.Fatal(err)
+		}
+
+		tuple, err := tp.GetTuple(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := tuple.GetValue(schema, 1); v.Str() != strings.Repeat("grown", 10) {
+			t.Errorf("unexpected value %v", v)
+		}
+
+		huge := newTestTuple(t, schema, 0, strings.Repeat("x", 4000))
+		if err := tp.UpdateTuple(2, huge); !errors.Is(err, ErrNotEnoughSpace) {
+			t.Errorf("expected ErrNotEnoughSpace, got %v", err)
+		}
+		if tuple, err := tp.GetTuple(2); err != nil || tuple.Size() == huge.Size() {
+			t.Error("expected tuple unchanged after failed update")
+		}
+	})
+}
+
+func TestTablePage_Compact(t *testing.T) {
+	schema := newTablePageSchema(t)
+	tp := InitTablePage(&Page{PageID: 0, Data: make([]byte, PageSize)}, InvalidPageID)
+
+	// fill the page
+	var slots []int
+	for {
+		slot, err := tp.InsertTuple(newTestTuple(t, schema, int32(len(slots)), strings.Repeat("a", 100)))
+		if errors.Is(err, ErrNotEnoughSpace) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		slots = append(slots, slot)
+	}
+	if len(slots) < 10 {
+		t.Fatalf("expected a full page, got %d tuples", len(slots))
+	}
+
+	// free every other tuple and keep one marked deleted
+	for i := 0; i < len(slots); i += 2 {
+		if err := tp.MarkDelete(slots[i]); err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 {
+			if err := tp.ApplyDelete(slots[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	before := tp.FreeSpace()
+
+	// a large tuple only fits after compaction
+	big := newTestTuple(t, schema, -1, strings.Repeat("b", 300))
+	slot, err := tp.InsertTuple(big)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp.FreeSpace() <= before {
+		t.Errorf("expected compaction to free space, before %d after %d", before, tp.FreeSpace())
+	}
+
+	for i, s := range slots {
+		tuple, err := tp.GetTuple(s)
+		switch {
+		case i == 0:
+			if !errors.Is(err, ErrTupleDeleted) {
+				t.Errorf("slot %d: expected ErrTupleDeleted, got %v", s, err)
+			}
+		case i%2 == 0:
+			if s != slot && !errors.Is(err, ErrInvalidSlot) {
+				t.Errorf("slot %d: expected ErrInvalidSlot, got %v", s, err)
+			}
+		default:
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v, _ := tuple.GetValue(schema, 0); v.Int() != int64(i) {
+				t.Errorf("slot %d: expected %d, got %v", s, i, v)
+			}
+		}
+	}
+
+	// the marked tuple survives compaction and can still be restored
+	if err := tp.RollbackDelete(slots[0]); err != nil {
+		t.Fatal(err)
+	}
+	if tuple, err := tp.GetTuple(slots[0]); err != nil {
+		t.Fatal(err)
+	} else if v, _ := tuple.GetValue(schema, 0); v.Int() != 0 {
+		t.Errorf("expected 0, got %v", v)
+	}
+
+	if _, err := tp.InsertTuple(&Tuple{Data: make([]byte, PageSize)}); !errors.Is(err, ErrValueTooLong) {
+		t.Error("expected ErrValueTooLong")
+	}
+}
@@ -1,33 +1,102 @@
 package internal
 
-// Trie class
-type Trie struct {
-	root *TrieNode
+import (
+	"iter"
+	"strings"
+)
+
+// Trie class, a radix tree: chains of single-child nodes are compressed
+// into one node holding the whole edge, and children live in adaptive
+// containers, see trieChildren
+type Trie[V any] struct {
+	root *TrieNode[V]
+
+	// codec encodes the values in snapshots, BinaryCodec if nil
+	codec ValueCodec[V]
 }
 
 // TrieNode is a Trie Node
-type TrieNode struct {
-	key byte
+type TrieNode[V any] struct {
+	// prefix is the compressed edge from the parent, it is never empty
+	// except for the root, and prefix[0] is the key in the parent's childs
+	prefix string
+
+	// hasVal tells whether the node is the end of a key,
+	// so the zero value of V can be stored as well
+	val    V
+	hasVal bool
+	childs trieChildren[V]
 
-	// if val is nil, we consider it's not the end of the key
-	// in someways, this can replace isEnd attribute
-	val    any
-	childs map[byte]*TrieNode
+	// count is the number of keys in the subtree, including the node itself
+	count int
 }
 
 // NewTrie returns an initialized trie
-func NewTrie() *Trie {
-	return &Trie{
-		root: &TrieNode{
-			key:    ' ',
-			val:    nil,
-			childs: make(map[byte]*TrieNode),
-		},
+func NewTrie[V any]() *Trie[V] {
+	return &Trie[V]{
+		root: &TrieNode[V]{},
+	}
+}
+
+// child returns the child whose prefix starts with b, or nil
+func (n *TrieNode[V]) child(b byte) *TrieNode[V] {
+	if n.childs == nil {
+		return nil
+	}
+
+	return n.childs.get(b)
+}
+
+// setChild adds or replaces the child keyed by the first byte of its prefix
+func (n *TrieNode[V]) setChild(child *TrieNode[V]) {
+	if n.childs == nil {
+		n.childs = &trieNode4[V]{}
+	}
+
+	n.childs = n.childs.set(child.prefix[0], child)
+}
+
+func (n *TrieNode[V]) removeChild(b byte) {
+	if n.childs != nil {
+		n.childs = n.childs.remove(b)
+	}
+}
+
+func (n *TrieNode[V]) numChilds() int {
+	if n.childs == nil {
+		return 0
+	}
+
+	return n.childs.len()
+}
+
+// mergeChild absorbs the only child of a valueless node, so the tree
+// stays path compressed, the node keeps its place in the parent
+func (n *TrieNode[V]) mergeChild() {
+	var only *TrieNode[V]
+	n.childs.each(func(b byte, child *TrieNode[V]) bool {
+		only = child
+		return false
+	})
+
+	n.prefix += only.prefix
+	n.val, n.hasVal = only.val, only.hasVal
+	n.childs = only.childs
+	n.count = only.count
+}
+
+// commonPrefixLen returns the length of the longest common prefix of a and b
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
 	}
+
+	return i
 }
 
 // Put does insert a kv into trie
-func (t *Trie) Put(key string, val any) error {
+func (t *Trie[V]) Put(key string, val V) error {
 	switch {
 	case len(key) == 0:
 		return ErrEmptyKey
@@ -36,54 +105,77 @@ func (t *Trie) Put(key string, val any) error {
 	}
 
 	cur := t.root
-	i := 0
-	for i < len(key) {
-		if _, ok := cur.childs[key[i]]; !ok {
-			cur.childs[key[i]] = &TrieNode{
-				key:    key[i],
-				val:    nil,
-				childs: make(map[byte]*TrieNode),
+	path := []*TrieNode[V]{cur}
+	rest := key
+	for len(rest) > 0 {
+		child := cur.child(rest[0])
+		if child == nil {
+			// no edge shares a byte with the rest, hang a new leaf
+			child = &TrieNode[V]{prefix: rest}
+			cur.setChild(child)
+			cur = child
+			path = append(path, cur)
+			break
+		}
+
+		n := commonPrefixLen(child.prefix, rest)
+		if n < len(child.prefix) {
+			// split the edge, the existing child goes below the new node
+			split := &TrieNode[V]{
+				prefix: child.prefix[:n],
+				count:  child.count,
 			}
+			child.prefix = child.prefix[n:]
+			split.setChild(child)
+			cur.setChild(split)
+			child = split
 		}
-		cur = cur.childs[key[i]]
-		i++
+
+		cur = child
+		path = append(path, cur)
+		rest = rest[n:]
 	}
 
-	if cur.val != nil {
+	if cur.hasVal {
 		return ErrKeyExists
 	}
 
 	cur.val = val
+	cur.hasVal = true
+	for _, node := range path {
+		node.count++
+	}
 	return nil
 }
 
-func (t *Trie) Get(key string) (any, error) {
-	switch {
-	case len(key) == 0:
-		return "", ErrEmptyKey
-	case t.root == nil:
-		return "", ErrEmptyTrie
+// Get returns the value of the key and whether the key is present
+func (t *Trie[V]) Get(key string) (V, bool) {
+	var zero V
+	if len(key) == 0 || t.root == nil {
+		return zero, false
 	}
 
 	cur := t.root
-	i := 0
-	for i < len(key) {
-		if _, ok := cur.childs[key[i]]; ok {
-			cur = cur.childs[key[i]]
-		} else {
-			return "", ErrKeyNotFound
+	rest := key
+	for len(rest) > 0 {
+		child := cur.child(rest[0])
+		if child == nil || !strings.HasPrefix(rest, child.prefix) {
+			return zero, false
 		}
-		i++
+		cur = child
+		rest = rest[len(child.prefix):]
 	}
 
-	if cur.val == nil {
-		return "", ErrKeyNotFound
+	if !cur.hasVal {
+		return zero, false
 	}
 
-	return cur.val, nil
+	return cur.val, true
 }
 
-func (t *Trie) Delete(key string) error {
+// Delete removes the key, branches left empty are released
+// and single-child chains are compressed again
+func (t *Trie[V]) Delete(key string) error {
 	switch {
 	case len(key) == 0:
 		return ErrEmptyKey
@@ -92,18 +184,182 @@ func (t *Trie) Delete(key string) error {
 	}
 
 	cur := t.root
-	i := 0
-	for i < len(key) {
-		if _, ok := cur.childs[key[i]]; ok {
-			cur = cur.childs[key[i]]
-		} else {
+	path := []*TrieNode[V]{cur}
+	rest := key
+	for len(rest) > 0 {
+		child := cur.child(rest[0])
+		if child == nil || !strings.HasPrefix(rest, child.prefix) {
 			return ErrKeyNotFound
 		}
-		i++
+		cur = child
+		path = append(path, cur)
+		rest = rest[len(child.prefix):]
 	}
-	if cur.val == nil {
+	if !cur.hasVal {
 		return ErrKeyNotFound
 	}
-	cur.val = nil
+
+	var zero V
+	cur.val = zero
+	cur.hasVal = false
+	for _, node := range path {
+		node.count--
+	}
+
+	// the root is never pruned or merged
+	if cur == t.root {
+		return nil
+	}
+
+	parent := path[len(path)-2]
+	switch cur.numChilds() {
+	case 0:
+		parent.removeChild(cur.prefix[0])
+		if parent != t.root && !parent.hasVal && parent.numChilds() == 1 {
+			parent.mergeChild()
+		}
+	case 1:
+		cur.mergeChild()
+	}
+
 	return nil
 }
+
+// LongestPrefixOf returns the longest stored key that is a prefix of key,
+// together with its value
+func (t *Trie[V]) LongestPrefixOf(key string) (string, V, bool) {
+	var (
+		val   V
+		found bool
+		n     int
+	)
+	if t.root == nil {
+		return "", val, false
+	}
+
+	cur := t.root
+	depth := 0
+	for depth < len(key) {
+		child := cur.child(key[depth])
+		if child == nil || !strings.HasPrefix(key[depth:], child.prefix) {
+			break
+		}
+		cur = child
+		depth += len(child.prefix)
+		if cur.hasVal {
+			val, found, n = cur.val, true, depth
+		}
+	}
+
+	return key[:n], val, found
+}
+
+// findPrefix returns the highest node whose keys all start with prefix,
+// together with the full key of that node
+func (t *Trie[V]) findPrefix(prefix string) (*TrieNode[V], string) {
+	if t.root == nil {
+		return nil, ""
+	}
+
+	cur := t.root
+	depth := 0
+	for depth < len(prefix) {
+		child := cur.child(prefix[depth])
+		if child == nil {
+			return nil, ""
+		}
+
+		rest := prefix[depth:]
+		switch {
+		case strings.HasPrefix(rest, child.prefix):
+			depth += len(child.prefix)
+		case strings.HasPrefix(child.prefix, rest):
+			// prefix ends in the middle of the edge
+			return child, prefix[:depth] + child.prefix
+		default:
+			return nil, ""
+		}
+		cur = child
+	}
+
+	return cur, prefix
+}
+
+// CountWithPrefix returns the number of keys starting with prefix,
+// it only walks down the prefix thanks to the subtree counts
+func (t *Trie[V]) CountWithPrefix(prefix string) int {
+	node, _ := t.findPrefix(prefix)
+	if node == nil {
+		return 0
+	}
+
+	return node.count
+}
+
+// Len returns the number of keys in the trie
+func (t *Trie[V]) Len() int {
+	return t.CountWithPrefix("")
+}
+
+// WalkPrefix calls fn for every key starting with prefix in lexicographic
+// byte order, walking stops as soon as fn returns false
+func (t *Trie[V]) WalkPrefix(prefix string, fn func(key string, val V) bool) {
+	node, path := t.findPrefix(prefix)
+	if node == nil {
+		return
+	}
+
+	node.walk(path, "", "", fn)
+}
+
+// Range returns the keys in [start, end) in lexicographic byte order,
+// an empty end means there is no upper bound
+func (t *Trie[V]) Range(start, end string) iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		if t.root == nil {
+			return
+		}
+
+		t.root.walk("", start, end, yield)
+	}
+}
+
+// Keys returns all keys in lexicographic byte order
+func (t *Trie[V]) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for key := range t.Range("", "") {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// walk visits the subtree in preorder with children in byte order, so keys
+// come out in lexicographic order, path is the key of the node itself.
+// Subtrees entirely before start are skipped, and walking stops at the first
+// key not before end. It returns false if the walk should stop
+func (n *TrieNode[V]) walk(path string, start, end string, fn func(key string, val V) bool) bool {
+	if end != "" && path >= end {
+		return false
+	}
+	if n.hasVal && path >= start {
+		if !fn(path, n.val) {
+			return false
+		}
+	}
+	if n.childs == nil {
+		return true
+	}
+
+	return n.childs.each(func(b byte, child *TrieNode[V]) bool {
+		childPath := path + child.prefix
+		// every key below the child starts with childPath,
+		// so they are all before start unless childPath is a prefix of start
+		if childPath < start && !strings.HasPrefix(start, childPath) {
+			return true
+		}
+
+		return child.walk(childPath, start, end, fn)
+	})
+}
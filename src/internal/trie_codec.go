@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+)
+
+// ValueCodec encodes the values of a Trie snapshot
+type ValueCodec[V any] interface {
+	// AppendValue appends the encoding of val to buf
+	AppendValue(buf []byte, val V) ([]byte, error)
+	// DecodeValue decodes a value produced by AppendValue
+	DecodeValue(data []byte) (V, error)
+}
+
+// BinaryCodec is the default codec, it handles strings, byte slices,
+// fixed-size values such as integers, floats and structs of them in
+// little endian, and types implementing encoding.BinaryMarshaler.
+// int and uint are encoded as 64 bits
+type BinaryCodec[V any] struct{}
+
+func (BinaryCodec[V]) AppendValue(buf []byte, val V) ([]byte, error) {
+	switch v := any(val).(type) {
+	case string:
+		return append(buf, v...), nil
+	case []byte:
+		return append(buf, v...), nil
+	case int:
+		return binary.LittleEndian.AppendUint64(buf, uint64(v)), nil
+	case uint:
+		return binary.LittleEndian.AppendUint64(buf, uint64(v)), nil
+	case encoding.BinaryMarshaler:
+		data, err := v.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return append(buf, data...), nil
+	}
+
+	return binary.Append(buf, binary.LittleEndian, val)
+}
+
+func (BinaryCodec[V]) DecodeValue(data []byte) (V, error) {
+	var val V
+	switch v := any(&val).(type) {
+	case *string:
+		*v = string(data)
+		return val, nil
+	case *[]byte:
+		*v = bytes.Clone(data)
+		return val, nil
+	case *int, *uint:
+		if len(data) != 8 {
+			return val, fmt.Errorf("%w: integer of %d bytes", ErrInvalidTrieSnapshot, len(data))
+		}
+		if p, ok := v.(*int); ok {
+			*p = int(binary.LittleEndian.Uint64(data))
+		} else {
+			*v.(*uint) = uint(binary.LittleEndian.Uint64(data))
+		}
+		return val, nil
+	case encoding.BinaryUnmarshaler:
+		return val, v.UnmarshalBinary(data)
+	}
+
+	n, err := binary.Decode(data, binary.LittleEndian, &val)
+	if err != nil {
+		return val, err
+	}
+	if n != len(data) {
+		return val, fmt.Errorf("%w: %d trailing value bytes", ErrInvalidTrieSnapshot, len(data)-n)
+	}
+
+	return val, nil
+}
+
+// GobCodec encodes any value gob can handle, such as maps and
+// variable-length structs, at the cost of a larger snapshot
+type GobCodec[V any] struct{}
+
+func (GobCodec[V]) AppendValue(buf []byte, val V) ([]byte, error) {
+	b := bytes.NewBuffer(buf)
+	if err := gob.NewEncoder(b).Encode(val); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+func (GobCodec[V]) DecodeValue(data []byte) (V, error) {
+	var val V
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&val)
+
+	return val, err
+}
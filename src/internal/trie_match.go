@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"iter"
+	"strings"
+)
+
+// FuzzyFind returns the keys within maxEdits Levenshtein distance of key,
+// in lexicographic byte order. One row of the edit distance matrix is kept
+// per trie depth, a subtree is skipped as soon as every cell of the row
+// exceeds maxEdits since the distance can only grow below it
+func (t *Trie[V]) FuzzyFind(key string, maxEdits int) iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		if t.root == nil || maxEdits < 0 {
+			return
+		}
+
+		// row[j] is the distance between the current path and key[:j]
+		row := make([]int, len(key)+1)
+		for j := range row {
+			row[j] = j
+		}
+
+		t.root.fuzzy("", key, row, maxEdits, yield)
+	}
+}
+
+// fuzzy visits the subtree of n, row is the edit distance row of path.
+// It returns false if the walk should stop
+func (n *TrieNode[V]) fuzzy(path, key string, row []int, maxEdits int, fn func(key string, val V) bool) bool {
+	if n.hasVal && path != "" && row[len(key)] <= maxEdits {
+		if !fn(path, n.val) {
+			return false
+		}
+	}
+	if n.childs == nil {
+		return true
+	}
+
+	return n.childs.each(func(b byte, child *TrieNode[V]) bool {
+		cur := row
+		for i := 0; i < len(child.prefix); i++ {
+			cur = nextEditRow(cur, key, child.prefix[i])
+			if minOf(cur) > maxEdits {
+				return true
+			}
+		}
+
+		return child.fuzzy(path+child.prefix, key, cur, maxEdits, fn)
+	})
+}
+
+// nextEditRow returns the edit distance row after appending c to the path
+func nextEditRow(prev []int, key string, c byte) []int {
+	row := make([]int, len(prev))
+	row[0] = prev[0] + 1
+	for j := 1; j < len(row); j++ {
+		cost := 1
+		if key[j-1] == c {
+			cost = 0
+		}
+		row[j] = min(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+	}
+
+	return row
+}
+
+func minOf(row []int) int {
+	m := row[0]
+	for _, v := range row[1:] {
+		m = min(m, v)
+	}
+
+	return m
+}
+
+// Match returns the keys matching the glob pattern in lexicographic byte
+// order, '?' matches exactly one byte and '*' matches any number of bytes.
+// The literal part before the first wildcard is looked up directly, and a
+// subtree is skipped once no position of the pattern can be reached
+func (t *Trie[V]) Match(pattern string) iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		literal := pattern
+		if i := strings.IndexAny(pattern, "?*"); i >= 0 {
+			literal = pattern[:i]
+		}
+
+		node, path := t.findPrefix(literal)
+		if node == nil {
+			return
+		}
+
+		// the literal prefix may end in the middle of an edge,
+		// consume the bytes of path beyond it against the pattern
+		states := globClosure(pattern, []int{0})
+		for i := 0; i < len(path); i++ {
+			states = globStep(pattern, states, path[i])
+		}
+		if len(states) == 0 {
+			return
+		}
+
+		node.glob(path, pattern, states, yield)
+	}
+}
+
+// glob visits the subtree of n, states are the pattern positions
+// reachable after path. It returns false if the walk should stop
+func (n *TrieNode[V]) glob(path, pattern string, states []int, fn func(key string, val V) bool) bool {
+	if n.hasVal && path != "" && globAccepts(pattern, states) {
+		if !fn(path, n.val) {
+			return false
+		}
+	}
+	if n.childs == nil {
+		return true
+	}
+
+	return n.childs.each(func(b byte, child *TrieNode[V]) bool {
+		cur := states
+		for i := 0; i < len(child.prefix) && len(cur) > 0; i++ {
+			cur = globStep(pattern, cur, child.prefix[i])
+		}
+		if len(cur) == 0 {
+			return true
+		}
+
+		return child.glob(path+child.prefix, pattern, cur, fn)
+	})
+}
+
+// globClosure adds the positions reachable by letting '*' match nothing,
+// states stay sorted without duplicates
+func globClosure(pattern string, states []int) []int {
+	closed := make([]int, 0, len(states))
+	for _, s := range states {
+		for {
+			if len(closed) == 0 || closed[len(closed)-1] < s {
+				closed = append(closed, s)
+			}
+			if s >= len(pattern) || pattern[s] != '*' {
+				break
+			}
+			s++
+		}
+	}
+
+	return closed
+}
+
+// globStep returns the positions reachable after matching c
+func globStep(pattern string, states []int, c byte) []int {
+	next := make([]int, 0, len(states))
+	for _, s := range states {
+		if s >= len(pattern) {
+			continue
+		}
+
+		switch pattern[s] {
+		case '*':
+			next = append(next, s)
+		case '?', c:
+			next = append(next, s+1)
+		}
+	}
+
+	return globClosure(pattern, next)
+}
+
+func globAccepts(pattern string, states []int) bool {
+	return len(states) > 0 && states[len(states)-1] == len(pattern)
+}
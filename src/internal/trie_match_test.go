@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func collectKeys[V any](seq func(yield func(string, V) bool)) []string {
+	var keys []string
+	for key := range seq {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestTrie_FuzzyFind(t *testing.T) {
+	trie := newTestTrie(t, "table", "tables", "cable", "tablet", "tab", "stable", "apple")
+
+	cases := []struct {
+		key      string
+		maxEdits int
+		expected []string
+	}{
+		{"table", 0, []string{"table"}},
+		{"table", 1, []string{"cable", "stable", "table", "tables", "tablet"}},
+		{"tabel", 1, nil},
+		{"tabel", 2, []string{"tab", "table", "tables", "tablet"}},
+		{"xyz", 2, nil},
+		{"", 3, []string{"tab"}},
+		{"table", -1, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s/%d", c.key, c.maxEdits), func(t *testing.T) {
+			keys := collectKeys(trie.FuzzyFind(c.key, c.maxEdits))
+			if !slices.Equal(keys, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, keys)
+			}
+		})
+	}
+
+	t.Run("values", func(t *testing.T) {
+		for key, val := range trie.FuzzyFind("apple", 0) {
+			if key != "apple" || val != 6 {
+				t.Errorf("unexpected %s = %d", key, val)
+			}
+		}
+	})
+}
+
+func TestTrie_Match(t *testing.T) {
+	trie := newTestTrie(t, "table", "tables", "cable", "tablet", "tab", "stable", "apple")
+
+	cases := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"table", []string{"table"}},
+		{"tab*", []string{"tab", "table", "tables", "tablet"}},
+		{"tabl?", []string{"table"}},
+		{"*able", []string{"cable", "stable", "table"}},
+		{"?able*", []string{"cable", "table", "tables", "tablet"}},
+		{"*a*e*", []string{"apple", "cable", "stable", "table", "tables", "tablet"}},
+		{"t**b", []string{"tab"}},
+		{"ta", nil},
+		{"x*", nil},
+		{"*", []string{"apple", "cable", "stable", "tab", "table", "tables", "tablet"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern, func(t *testing.T) {
+			keys := collectKeys(trie.Match(c.pattern))
+			if !slices.Equal(keys, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, keys)
+			}
+		})
+	}
+
+	t.Run("early termination", func(t *testing.T) {
+		var keys []string
+		for key := range trie.Match("*") {
+			keys = append(keys, key)
+			if len(keys) == 2 {
+				break
+			}
+		}
+		if !slices.Equal(keys, []string{"apple", "cable"}) {
+			t.Errorf("unexpected keys: %v", keys)
+		}
+	})
+}
+
+// levenshtein and globMatch are the brute force baselines
+func levenshtein(a, b string) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 0; i < len(a); i++ {
+		row = nextEditRow(row, b, a[i])
+	}
+	return row[len(b)]
+}
+
+func globMatch(pattern, s string) bool {
+	if pattern == "" {
+		return s == ""
+	}
+	switch pattern[0] {
+	case '*':
+		return globMatch(pattern[1:], s) || (s != "" && globMatch(pattern, s[1:]))
+	case '?':
+		return s != "" && globMatch(pattern[1:], s[1:])
+	default:
+		return s != "" && s[0] == pattern[0] && globMatch(pattern[1:], s[1:])
+	}
+}
+
+func TestTrie_MatchRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randString := func(alphabet string, n int) string {
+		b := make([]byte, rnd.Intn(n)+1)
+		for i := range b {
+			b[i] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		return string(b)
+	}
+
+	trie := NewTrie[int]()
+	var all []string
+	for i := 0; i < 500; i++ {
+		key := randString("abc", 8)
+		if trie.Put(key, i) == nil {
+			all = append(all, key)
+		}
+	}
+	slices.Sort(all)
+
+	for i := 0; i < 100; i++ {
+		key := randString("abc", 8)
+		maxEdits := rnd.Intn(3)
+		var expected []string
+		for _, k := range all {
+			if levenshtein(k, key) <= maxEdits {
+				expected = append(expected, k)
+			}
+		}
+		if keys := collectKeys(trie.FuzzyFind(key, maxEdits)); !slices.Equal(keys, expected) {
+			t.Fatalf("FuzzyFind(%s, %d): expected %v, got %v", key, maxEdits, expected, keys)
+		}
+
+		pattern := randString("abc?*", 6)
+		expected = nil
+		for _, k := range all {
+			if globMatch(pattern, k) {
+				expected = append(expected, k)
+			}
+		}
+		if keys := collectKeys(trie.Match(pattern)); !slices.Equal(keys, expected) {
+			t.Fatalf("Match(%s): expected %v, got %v", pattern, expected, keys)
+		}
+	}
+}
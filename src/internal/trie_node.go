@@ -0,0 +1,292 @@
+package internal
+
+// trieChildren is the child container of a TrieNode, like an adaptive radix
+// tree it grows from 4 to 16, 48 and 256 slots and shrinks back on removal,
+// so small nodes don't pay for a full fan-out
+type trieChildren[V any] interface {
+	// get returns the child whose prefix starts with b, or nil
+	get(b byte) *TrieNode[V]
+	// set adds or replaces a child and returns the container to use from now on
+	set(b byte, child *TrieNode[V]) trieChildren[V]
+	// remove drops a child and returns the container to use from now on
+	remove(b byte) trieChildren[V]
+	len() int
+	// each visits the children in ascending byte order until fn returns false
+	each(fn func(b byte, child *TrieNode[V]) bool) bool
+}
+
+// trieNode4 and trieNode16 keep the keys sorted in a small array
+type trieNode4[V any] struct {
+	n      uint8
+	keys   [4]byte
+	childs [4]*TrieNode[V]
+}
+
+type trieNode16[V any] struct {
+	n      uint8
+	keys   [16]byte
+	childs [16]*TrieNode[V]
+}
+
+// trieNode48 maps a byte to a slot through index, 0 means no child
+type trieNode48[V any] struct {
+	n      uint8
+	index  [256]uint8
+	childs [48]*TrieNode[V]
+}
+
+type trieNode256[V any] struct {
+	n      int
+	childs [256]*TrieNode[V]
+}
+
+// sortedGet, sortedSet and sortedRemove implement trieNode4 and trieNode16
+func sortedGet[V any](keys []byte, childs []*TrieNode[V], b byte) *TrieNode[V] {
+	for i, k := range keys {
+		if k == b {
+			return childs[i]
+		}
+	}
+
+	return nil
+}
+
+// sortedSet inserts b keeping keys sorted, it returns false if keys are full
+func sortedSet[V any](keys []byte, childs []*TrieNode[V], n *uint8, b byte, child *TrieNode[V]) bool {
+	i := 0
+	for i < int(*n) && keys[i] < b {
+		i++
+	}
+	if i < int(*n) && keys[i] == b {
+		childs[i] = child
+		return true
+	}
+	if int(*n) == len(keys) {
+		return false
+	}
+
+	copy(keys[i+1:*n+1], keys[i:*n])
+	copy(childs[i+1:*n+1], childs[i:*n])
+	keys[i] = b
+	childs[i] = child
+	*n++
+
+	return true
+}
+
+func sortedRemove[V any](keys []byte, childs []*TrieNode[V], n *uint8, b byte) {
+	for i := 0; i < int(*n); i++ {
+		if keys[i] != b {
+			continue
+		}
+
+		copy(keys[i:], keys[i+1:*n])
+		copy(childs[i:], childs[i+1:*n])
+		*n--
+		childs[*n] = nil
+		return
+	}
+}
+
+func sortedEach[V any](keys []byte, childs []*TrieNode[V], fn func(b byte, child *TrieNode[V]) bool) bool {
+	for i, k := range keys {
+		if !fn(k, childs[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *trieNode4[V]) get(b byte) *TrieNode[V] {
+	return sortedGet(c.keys[:c.n], c.childs[:c.n], b)
+}
+
+func (c *trieNode4[V]) set(b byte, child *TrieNode[V]) trieChildren[V] {
+	if sortedSet(c.keys[:], c.childs[:], &c.n, b, child) {
+		return c
+	}
+
+	grown := &trieNode16[V]{n: c.n}
+	copy(grown.keys[:], c.keys[:])
+	copy(grown.childs[:], c.childs[:])
+	return grown.set(b, child)
+}
+
+func (c *trieNode4[V]) remove(b byte) trieChildren[V] {
+	sortedRemove(c.keys[:], c.childs[:], &c.n, b)
+	if c.n == 0 {
+		return nil
+	}
+
+	return c
+}
+
+func (c *trieNode4[V]) len() int {
+	return int(c.n)
+}
+
+func (c *trieNode4[V]) each(fn func(b byte, child *TrieNode[V]) bool) bool {
+	return sortedEach(c.keys[:c.n], c.childs[:c.n], fn)
+}
+
+func (c *trieNode16[V]) get(b byte) *TrieNode[V] {
+	return sortedGet(c.keys[:c.n], c.childs[:c.n], b)
+}
+
+func (c *trieNode16[V]) set(b byte, child *TrieNode[V]) trieChildren[V] {
+	if sortedSet(c.keys[:], c.childs[:], &c.n, b, child) {
+		return c
+	}
+
+	grown := &trieNode48[V]{}
+	for i := 0; i < int(c.n); i++ {
+		grown.childs[i] = c.childs[i]
+		grown.index[c.keys[i]] = uint8(i + 1)
+	}
+	grown.n = c.n
+	return grown.set(b, child)
+}
+
+func (c *trieNode16[V]) remove(b byte) trieChildren[V] {
+	sortedRemove(c.keys[:], c.childs[:], &c.n, b)
+	if c.n > 3 {
+		return c
+	}
+
+	shrunk := &trieNode4[V]{n: c.n}
+	copy(shrunk.keys[:], c.keys[:c.n])
+	copy(shrunk.childs[:], c.childs[:c.n])
+	return shrunk
+}
+
+func (c *trieNode16[V]) len() int {
+	return int(c.n)
+}
+
+func (c *trieNode16[V]) each(fn func(b byte, child *TrieNode[V]) bool) bool {
+	return sortedEach(c.keys[:c.n], c.childs[:c.n], fn)
+}
+
+func (c *trieNode48[V]) get(b byte) *TrieNode[V] {
+	if i := c.index[b]; i > 0 {
+		return c.childs[i-1]
+	}
+
+	return nil
+}
+
+func (c *trieNode48[V]) set(b byte, child *TrieNode[V]) trieChildren[V] {
+	if i := c.index[b]; i > 0 {
+		c.childs[i-1] = child
+		return c
+	}
+
+	if int(c.n) < len(c.childs) {
+		for i := range c.childs {
+			if c.childs[i] == nil {
+				c.childs[i] = child
+				c.index[b] = uint8(i + 1)
+				c.n++
+				return c
+			}
+		}
+	}
+
+	grown := &trieNode256[V]{}
+	c.each(func(k byte, child *TrieNode[V]) bool {
+		grown.childs[k] = child
+		grown.n++
+		return true
+	})
+	return grown.set(b, child)
+}
+
+func (c *trieNode48[V]) remove(b byte) trieChildren[V] {
+	i := c.index[b]
+	if i == 0 {
+		return c
+	}
+
+	c.childs[i-1] = nil
+	c.index[b] = 0
+	c.n--
+	if c.n > 12 {
+		return c
+	}
+
+	shrunk := &trieNode16[V]{}
+	c.each(func(k byte, child *TrieNode[V]) bool {
+		shrunk.keys[shrunk.n] = k
+		shrunk.childs[shrunk.n] = child
+		shrunk.n++
+		return true
+	})
+	return shrunk
+}
+
+func (c *trieNode48[V]) len() int {
+	return int(c.n)
+}
+
+func (c *trieNode48[V]) each(fn func(b byte, child *TrieNode[V]) bool) bool {
+	for k := 0; k < len(c.index); k++ {
+		if i := c.index[k]; i > 0 {
+			if !fn(byte(k), c.childs[i-1]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (c *trieNode256[V]) get(b byte) *TrieNode[V] {
+	return c.childs[b]
+}
+
+func (c *trieNode256[V]) set(b byte, child *TrieNode[V]) trieChildren[V] {
+	if c.childs[b] == nil {
+		c.n++
+	}
+	c.childs[b] = child
+
+	return c
+}
+
+func (c *trieNode256[V]) remove(b byte) trieChildren[V] {
+	if c.childs[b] == nil {
+		return c
+	}
+
+	c.childs[b] = nil
+	c.n--
+	if c.n > 37 {
+		return c
+	}
+
+	shrunk := &trieNode48[V]{}
+	c.each(func(k byte, child *TrieNode[V]) bool {
+		shrunk.childs[shrunk.n] = child
+		shrunk.index[k] = shrunk.n + 1
+		shrunk.n++
+		return true
+	})
+	return shrunk
+}
+
+func (c *trieNode256[V]) len() int {
+	return c.n
+}
+
+func (c *trieNode256[V]) each(fn func(b byte, child *TrieNode[V]) bool) bool {
+	for k, child := range c.childs {
+		if child != nil {
+			if !fn(byte(k), child) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTrieChildren_Grow(t *testing.T) {
+	var childs trieChildren[int] = &trieNode4[int]{}
+
+	// insert in descending order so every container has to keep keys sorted
+	for i := 255; i >= 0; i-- {
+		childs = childs.set(byte(i), &TrieNode[int]{prefix: string(rune(i)), val: i})
+
+		n := 256 - i
+		var expected string
+		switch {
+		case n <= 4:
+			expected = "*internal.trieNode4[int]"
+		case n <= 16:
+			expected = "*internal.trieNode16[int]"
+		case n <= 48:
+			expected = "*internal.trieNode48[int]"
+		default:
+			expected = "*internal.trieNode256[int]"
+		}
+		if got := fmt.Sprintf("%T", childs); got != expected {
+			t.Fatalf("expected %s with %d children, got %s", expected, n, got)
+		}
+		if childs.len() != n {
+			t.Fatalf("expected %d children, got %d", n, childs.len())
+		}
+	}
+
+	prev := -1
+	childs.each(func(b byte, child *TrieNode[int]) bool {
+		if int(b) <= prev || child.val != int(b) {
+			t.Errorf("unexpected child %d after %d", b, prev)
+		}
+		prev = int(b)
+		return true
+	})
+
+	// replacing a child doesn't change the size
+	childs = childs.set(7, &TrieNode[int]{val: -7})
+	if childs.len() != 256 || childs.get(7).val != -7 {
+		t.Error("expected child 7 to be replaced")
+	}
+}
+
+func TestTrieChildren_Shrink(t *testing.T) {
+	var childs trieChildren[int] = &trieNode4[int]{}
+	for i := 0; i < 256; i++ {
+		childs = childs.set(byte(i), &TrieNode[int]{val: i})
+	}
+
+	for i := 0; i < 256; i++ {
+		childs = childs.remove(byte(i))
+		if i == 255 {
+			break
+		}
+
+		if childs.get(byte(i)) != nil {
+			t.Fatalf("expected child %d to be removed", i)
+		}
+		if child := childs.get(255); child == nil || child.val != 255 {
+			t.Fatalf("expected child 255 to be kept after removing %d", i)
+		}
+		if childs.len() != 255-i {
+			t.Fatalf("expected %d children, got %d", 255-i, childs.len())
+		}
+	}
+
+	if childs != nil {
+		t.Errorf("expected no container left, got %T", childs)
+	}
+}
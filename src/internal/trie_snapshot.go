@@ -0,0 +1,310 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// trieSnapshotMagic is "GDTS" in little endian
+	trieSnapshotMagic = 0x53544447
+	// trieSnapshotVersion is bumped on every incompatible format change
+	trieSnapshotVersion = 1
+
+	// maxTrieSnapshotField bounds a single prefix or value,
+	// so a corrupt length does not allocate unbounded memory
+	maxTrieSnapshotField = 1 << 28
+
+	trieSnapshotHasVal = 1
+)
+
+// A snapshot is the magic, the version byte and the number of keys
+// followed by the nodes in preorder, each node being
+//
+//	uvarint prefix length, prefix
+//	flags byte, if it has a value: uvarint value length, value
+//	uvarint number of children, then the children in byte order
+//
+// Lengths are uvarints so small tries stay compact
+
+// SetCodec sets the codec used by snapshots to encode values
+func (t *Trie[V]) SetCodec(codec ValueCodec[V]) {
+	t.codec = codec
+}
+
+func (t *Trie[V]) valueCodec() ValueCodec[V] {
+	if t.codec == nil {
+		return BinaryCodec[V]{}
+	}
+
+	return t.codec
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (t *Trie[V]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := t.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler,
+// the trie is replaced by the snapshot and unchanged on error
+func (t *Trie[V]) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	restored := &Trie[V]{codec: t.codec}
+	if _, err := restored.ReadFrom(r); err != nil {
+		return err
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidTrieSnapshot, r.Len())
+	}
+
+	t.root = restored.root
+	return nil
+}
+
+// WriteTo streams a snapshot of the trie to w and returns the bytes written
+func (t *Trie[V]) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	e := &trieEncoder[V]{w: bw, codec: t.valueCodec()}
+
+	root := t.root
+	if root == nil {
+		root = &TrieNode[V]{}
+	}
+
+	var header [4]byte
+	binary.LittleEndian.PutUint32(header[:], trieSnapshotMagic)
+	bw.Write(header[:])
+	bw.WriteByte(trieSnapshotVersion)
+	e.uvarint(uint64(root.count))
+	if err := e.node(root); err != nil {
+		return cw.n, err
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ReadFrom replaces the trie with a snapshot read from r and returns the
+// bytes read. Unless r is an io.ByteReader it is buffered, so r may be
+// read past the end of the snapshot. The trie is unchanged on error
+func (t *Trie[V]) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	if br, ok := r.(io.ByteReader); ok {
+		cr.br = br
+	} else {
+		b := bufio.NewReader(r)
+		cr.r, cr.br = b, b
+	}
+	d := &trieDecoder[V]{r: cr, codec: t.valueCodec()}
+
+	var header [5]byte
+	if _, err := io.ReadFull(d.r, header[:]); err != nil {
+		return d.r.n, d.wrap(err)
+	}
+	if binary.LittleEndian.Uint32(header[:4]) != trieSnapshotMagic {
+		return d.r.n, fmt.Errorf("%w: bad magic", ErrInvalidTrieSnapshot)
+	}
+	if header[4] != trieSnapshotVersion {
+		return d.r.n, fmt.Errorf("%w: unsupported version %d", ErrInvalidTrieSnapshot, header[4])
+	}
+
+	count, err := d.uvarint()
+	if err != nil {
+		return d.r.n, err
+	}
+	root, err := d.node(true)
+	if err != nil {
+		return d.r.n, err
+	}
+	if uint64(root.count) != count {
+		return d.r.n, fmt.Errorf("%w: expected %d keys, got %d", ErrInvalidTrieSnapshot, count, root.count)
+	}
+
+	t.root = root
+	return d.r.n, nil
+}
+
+type trieEncoder[V any] struct {
+	w     *bufio.Writer
+	codec ValueCodec[V]
+	buf   []byte
+}
+
+func (e *trieEncoder[V]) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	e.w.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+// node writes are buffered, write errors surface on Flush
+func (e *trieEncoder[V]) node(n *TrieNode[V]) error {
+	e.uvarint(uint64(len(n.prefix)))
+	e.w.WriteString(n.prefix)
+
+	if n.hasVal {
+		var err error
+		e.buf, err = e.codec.AppendValue(e.buf[:0], n.val)
+		if err != nil {
+			return fmt.Errorf("failed to encode value: %w", err)
+		}
+
+		e.w.WriteByte(trieSnapshotHasVal)
+		e.uvarint(uint64(len(e.buf)))
+		e.w.Write(e.buf)
+	} else {
+		e.w.WriteByte(0)
+	}
+
+	e.uvarint(uint64(n.numChilds()))
+	if n.childs == nil {
+		return nil
+	}
+
+	var err error
+	n.childs.each(func(b byte, child *TrieNode[V]) bool {
+		err = e.node(child)
+		return err == nil
+	})
+	return err
+}
+
+type trieDecoder[V any] struct {
+	r     *countingReader
+	codec ValueCodec[V]
+}
+
+// wrap reports a truncated snapshot as invalid
+func (d *trieDecoder[V]) wrap(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: unexpected end of snapshot", ErrInvalidTrieSnapshot)
+	}
+
+	return err
+}
+
+func (d *trieDecoder[V]) uvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return 0, d.wrap(err)
+	}
+
+	return v, nil
+}
+
+func (d *trieDecoder[V]) field() ([]byte, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > maxTrieSnapshotField {
+		return nil, fmt.Errorf("%w: field of %d bytes", ErrInvalidTrieSnapshot, n)
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(d.r, data); err != nil {
+		return nil, d.wrap(err)
+	}
+
+	return data, nil
+}
+
+// node reads a subtree and checks it is a well formed radix tree:
+// only the root has an empty prefix, children come in ascending byte order
+// and a valueless node other than the root has at least two children
+func (d *trieDecoder[V]) node(isRoot bool) (*TrieNode[V], error) {
+	prefix, err := d.field()
+	if err != nil {
+		return nil, err
+	}
+	if isRoot != (len(prefix) == 0) {
+		return nil, fmt.Errorf("%w: bad node prefix", ErrInvalidTrieSnapshot)
+	}
+	n := &TrieNode[V]{prefix: string(prefix)}
+
+	flags, err := d.r.ReadByte()
+	if err != nil {
+		return nil, d.wrap(err)
+	}
+	if flags&trieSnapshotHasVal != 0 {
+		if isRoot {
+			return nil, fmt.Errorf("%w: value for the empty key", ErrInvalidTrieSnapshot)
+		}
+		data, err := d.field()
+		if err != nil {
+			return nil, err
+		}
+		n.val, err = d.codec.DecodeValue(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode value: %w", err)
+		}
+		n.hasVal = true
+		n.count = 1
+	}
+
+	numChilds, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if numChilds > 256 {
+		return nil, fmt.Errorf("%w: %d children", ErrInvalidTrieSnapshot, numChilds)
+	}
+	if !isRoot && !n.hasVal && numChilds < 2 {
+		return nil, fmt.Errorf("%w: uncompressed node", ErrInvalidTrieSnapshot)
+	}
+
+	last := -1
+	for i := uint64(0); i < numChilds; i++ {
+		child, err := d.node(false)
+		if err != nil {
+			return nil, err
+		}
+		if int(child.prefix[0]) <= last {
+			return nil, fmt.Errorf("%w: unordered children", ErrInvalidTrieSnapshot)
+		}
+		last = int(child.prefix[0])
+
+		n.setChild(child)
+		n.count += child.count
+	}
+
+	return n, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+type countingReader struct {
+	r  io.Reader
+	br io.ByteReader
+	n  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReader) ReadByte() (byte, error) {
+	b, err := r.br.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestTrie_MarshalBinary(t *testing.T) {
+	trie := newTestTrie(t, "table", "tab", "tables", "apple", "tablet", "tac")
+
+	data, err := trie.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewTrie[int]()
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != trie.Len() {
+		t.Errorf("expected %d keys, got %d", trie.Len(), restored.Len())
+	}
+	for key, val := range trie.Range("", "") {
+		if got, ok := restored.Get(key); !ok || got != val {
+			t.Errorf("expected %s = %d, got %d", key, val, got)
+		}
+	}
+	if restored.CountWithPrefix("tab") != 4 {
+		t.Errorf("expected 4 keys under tab, got %d", restored.CountWithPrefix("tab"))
+	}
+
+	// the restored trie stays usable
+	if err := restored.Put("ta", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Delete("tables"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("empty trie", func(t *testing.T) {
+		data, err := NewTrie[int]().MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restored := newTestTrie(t, "stale")
+		if err := restored.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if restored.Len() != 0 {
+			t.Errorf("expected empty trie, got %d keys", restored.Len())
+		}
+	})
+}
+
+func TestTrie_UnmarshalBinaryInvalid(t *testing.T) {
+	data, err := newTestTrie(t, "table", "tab", "apple").MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte{'X'}, data[1:]...),
+		"version":   append(append(slices.Clone(data[:4]), 2), data[5:]...),
+		"truncated": data[:len(data)-1],
+		"trailing":  append(slices.Clone(data), 0),
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			trie := newTestTrie(t, "keep")
+			if err := trie.UnmarshalBinary(c); !errors.Is(err, ErrInvalidTrieSnapshot) {
+				t.Errorf("expected ErrInvalidTrieSnapshot, got %v", err)
+			}
+			if _, ok := trie.Get("keep"); !ok {
+				t.Error("expected trie unchanged")
+			}
+		})
+	}
+}
+
+func TestTrie_WriteTo(t *testing.T) {
+	trie := NewTrie[string]()
+	for i := 0; i < 1000; i++ {
+		if err := trie.Put(fmt.Sprintf("key_%d", i), fmt.Sprintf("value_%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// two snapshots back to back in one stream
+	var buf bytes.Buffer
+	n, err := trie.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("expected %d bytes written, got %d", buf.Len(), n)
+	}
+	if _, err := newTestTrie(t, "other").WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	first := NewTrie[string]()
+	read, err := first.ReadFrom(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != n {
+		t.Errorf("expected %d bytes read, got %d", n, read)
+	}
+	if !slices.Equal(slices.Collect(first.Keys()), slices.Collect(trie.Keys())) {
+		t.Error("expected same keys")
+	}
+	if val, _ := first.Get("key_42"); val != "value_42" {
+		t.Errorf("expected value_42, got %s", val)
+	}
+
+	second := NewTrie[int]()
+	if _, err := second.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := second.Get("other"); !ok || second.Len() != 1 {
+		t.Error("expected second snapshot")
+	}
+}
+
+type point struct {
+	X, Y int32
+}
+
+func TestTrie_Codec(t *testing.T) {
+	t.Run("fixed size", func(t *testing.T) {
+		trie := NewTrie[point]()
+		trie.Put("a", point{1, 2})
+		trie.Put("b", point{-3, 4})
+
+		data, err := trie.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored := NewTrie[point]()
+		if err := restored.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if val, _ := restored.Get("b"); val != (point{-3, 4}) {
+			t.Errorf("unexpected value %v", val)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		trie := NewTrie[map[string]int]()
+		trie.Put("a", map[string]int{"x": 1})
+		if _, err := trie.MarshalBinary(); err == nil {
+			t.Error("expected error for map values")
+		}
+	})
+
+	t.Run("gob", func(t *testing.T) {
+		trie := NewTrie[map[string]int]()
+		trie.SetCodec(GobCodec[map[string]int]{})
+		trie.Put("a", map[string]int{"x": 1, "y": 2})
+
+		var buf bytes.Buffer
+		if _, err := trie.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		restored := NewTrie[map[string]int]()
+		restored.SetCodec(GobCodec[map[string]int]{})
+		if _, err := restored.ReadFrom(io.MultiReader(&buf)); err != nil {
+			t.Fatal(err)
+		}
+		if val, _ := restored.Get("a"); !maps.Equal(val, map[string]int{"x": 1, "y": 2}) {
+			t.Errorf("unexpected value %v", val)
+		}
+	})
+}
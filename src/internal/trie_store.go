@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// TrieStore is a concurrent key-value store on top of PersistentTrie,
+// readers load the current root without locking while writers are
+// serialized and publish a new root after each modification
+type TrieStore[V any] struct {
+	root    atomic.Pointer[PersistentTrie[V]]
+	writeMu sync.Mutex
+}
+
+// ValueGuard holds the snapshot a value was read from, so the value
+// stays valid no matter how the store is modified afterwards
+type ValueGuard[V any] struct {
+	snapshot *PersistentTrie[V]
+	val      V
+}
+
+// Value returns the guarded value
+func (g *ValueGuard[V]) Value() V {
+	return g.val
+}
+
+// Snapshot returns the trie the value was read from
+func (g *ValueGuard[V]) Snapshot() *PersistentTrie[V] {
+	return g.snapshot
+}
+
+// NewTrieStore returns an empty trie store
+func NewTrieStore[V any]() *TrieStore[V] {
+	s := &TrieStore[V]{}
+	s.root.Store(NewPersistentTrie[V]())
+	return s
+}
+
+// Snapshot returns the current root, it is never modified by later writes
+func (s *TrieStore[V]) Snapshot() *PersistentTrie[V] {
+	if root := s.root.Load(); root != nil {
+		return root
+	}
+
+	return NewPersistentTrie[V]()
+}
+
+// Get looks up the key in the current snapshot without blocking writers,
+// the guard is nil if the key is not present
+func (s *TrieStore[V]) Get(key string) (*ValueGuard[V], bool) {
+	snapshot := s.Snapshot()
+
+	val, ok := snapshot.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	return &ValueGuard[V]{
+		snapshot: snapshot,
+		val:      val,
+	}, true
+}
+
+// Put sets key to val, an existing value is replaced
+func (s *TrieStore[V]) Put(key string, val V) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	root, err := s.Snapshot().Put(key, val)
+	if err != nil {
+		return err
+	}
+
+	s.root.Store(root)
+	return nil
+}
+
+// Remove deletes the key
+func (s *TrieStore[V]) Remove(key string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	root, err := s.Snapshot().Remove(key)
+	if err != nil {
+		return err
+	}
+
+	s.root.Store(root)
+	return nil
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestTrieStore_Get(t *testing.T) {
+	t.Run("zero value store", func(t *testing.T) {
+		store := new(TrieStore[string])
+		if _, ok := store.Get("key"); ok {
+			t.Error("expected key not found")
+		}
+	})
+
+	t.Run("guard outlives writes", func(t *testing.T) {
+		store := NewTrieStore[string]()
+		if err := store.Put("key", "value1"); err != nil {
+			t.Fatal(err)
+		}
+
+		guard, ok := store.Get("key")
+		if !ok {
+			t.Fatal("expected key found")
+		}
+
+		if err := store.Put("key", "value2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Remove("key"); err != nil {
+			t.Fatal(err)
+		}
+
+		if guard.Value() != "value1" {
+			t.Errorf("expected value1, got %v", guard.Value())
+		}
+		if val, ok := guard.Snapshot().Get("key"); !ok || val != "value1" {
+			t.Errorf("expected snapshot to keep value1, got %v", val)
+		}
+		if _, ok := store.Get("key"); ok {
+			t.Error("expected key not found")
+		}
+	})
+}
+
+func TestTrieStore_Put(t *testing.T) {
+	store := NewTrieStore[string]()
+	if err := store.Put("", "value"); !errors.Is(err, ErrEmptyKey) {
+		t.Error("expected ErrEmptyKey")
+	}
+	if err := store.Remove("key"); !errors.Is(err, ErrKeyNotFound) {
+		t.Error("expected ErrKeyNotFound")
+	}
+}
+
+func TestTrieStore_Concurrent(t *testing.T) {
+	store := NewTrieStore[int]()
+	numKeys := 200
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < numKeys; i++ {
+			if err := store.Put(fmt.Sprintf("key%d", i), i); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < numKeys; i++ {
+				guard, ok := store.Get(fmt.Sprintf("key%d", i))
+				if !ok {
+					continue
+				}
+				if guard.Value() != i {
+					t.Errorf("expected %d, got %v", i, guard.Value())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < numKeys; i++ {
+		if _, ok := store.Get(fmt.Sprintf("key%d", i)); !ok {
+			t.Errorf("expected key%d found", i)
+		}
+	}
+}
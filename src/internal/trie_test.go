@@ -2,16 +2,20 @@ package internal
 
 import (
 	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
 	"testing"
 )
 
 func TestNewTrie(t *testing.T) {
-	_ = NewTrie()
+	_ = NewTrie[string]()
 }
 
 func TestTrie_Put(t *testing.T) {
 	t.Run("nil root", func(t *testing.T) {
-		nilRootTrie := new(Trie)
+		nilRootTrie := new(Trie[string])
 		err := nilRootTrie.Put("key", "value")
 		if !errors.Is(err, ErrEmptyTrie) {
 			t.Error("expected ErrEmptyTrie")
@@ -19,7 +23,7 @@ func TestTrie_Put(t *testing.T) {
 	})
 
 	t.Run("empty key", func(t *testing.T) {
-		trie := NewTrie()
+		trie := NewTrie[string]()
 		err := trie.Put("", "value")
 		if !errors.Is(err, ErrEmptyKey) {
 			t.Error("expected ErrEmptyKey")
@@ -27,7 +31,7 @@ func TestTrie_Put(t *testing.T) {
 	})
 
 	t.Run("existing key", func(t *testing.T) {
-		trie := NewTrie()
+		trie := NewTrie[string]()
 		err := trie.Put("key", "value1")
 		if err != nil {
 			t.Error(err)
@@ -40,7 +44,7 @@ func TestTrie_Put(t *testing.T) {
 	})
 
 	t.Run("normal case", func(t *testing.T) {
-		trie := NewTrie()
+		trie := NewTrie[string]()
 		err := trie.Put("key1", "value1")
 		if err != nil {
 			t.Error(err)
@@ -55,59 +59,86 @@ func TestTrie_Put(t *testing.T) {
 
 func TestTrie_Get(t *testing.T) {
 	t.Run("nil root", func(t *testing.T) {
-		nilRootTrie := new(Trie)
-		_, err := nilRootTrie.Get("key")
-		if !errors.Is(err, ErrEmptyTrie) {
-			t.Error("expected ErrEmptyTrie")
+		nilRootTrie := new(Trie[string])
+		_, ok := nilRootTrie.Get("key")
+		if ok {
+			t.Error("expected key not found")
 		}
 	})
 
 	t.Run("empty key", func(t *testing.T) {
-		trie := NewTrie()
-		_, err := trie.Get("")
-		if !errors.Is(err, ErrEmptyKey) {
-			t.Error("expected ErrEmptyKey")
+		trie := NewTrie[string]()
+		_, ok := trie.Get("")
+		if ok {
+			t.Error("expected key not found")
 		}
 	})
 
 	t.Run("non existing key", func(t *testing.T) {
-		trie := NewTrie()
-		_, err := trie.Get("key")
-		if !errors.Is(err, ErrKeyNotFound) {
-			t.Error("expected ErrKeyNotFound")
+		trie := NewTrie[string]()
+		_, ok := trie.Get("key")
+		if ok {
+			t.Error("expected key not found")
 		}
 
-		err = trie.Put("key1", "value1")
+		err := trie.Put("key1", "value1")
 		if err != nil {
 			t.Error(err)
 		}
 
-		_, err = trie.Get("key")
-		if !errors.Is(err, ErrKeyNotFound) {
-			t.Error("expected ErrKeyNotFound")
+		_, ok = trie.Get("key")
+		if ok {
+			t.Error("expected key not found")
 		}
 	})
 
 	t.Run("normal case", func(t *testing.T) {
-		trie := NewTrie()
+		trie := NewTrie[string]()
 		err := trie.Put("key", "value")
 		if err != nil {
 			t.Error(err)
 		}
 
-		val, err := trie.Get("key")
-		if err != nil {
-			t.Error(err)
+		val, ok := trie.Get("key")
+		if !ok {
+			t.Error("expected key found")
 		}
 		if val != "value" {
 			t.Error("expected value")
 		}
 	})
+
+	t.Run("zero value", func(t *testing.T) {
+		trie := NewTrie[*int]()
+		err := trie.Put("key", nil)
+		if err != nil {
+			t.Error(err)
+		}
+
+		val, ok := trie.Get("key")
+		if !ok || val != nil {
+			t.Error("expected nil value to be stored")
+		}
+
+		err = trie.Put("key", new(int))
+		if !errors.Is(err, ErrKeyExists) {
+			t.Error("expected ErrKeyExists")
+		}
+
+		err = trie.Delete("key")
+		if err != nil {
+			t.Error(err)
+		}
+		_, ok = trie.Get("key")
+		if ok {
+			t.Error("expected key not found")
+		}
+	})
 }
 
 func TestTrie_Delete(t *testing.T) {
 	t.Run("nil root", func(t *testing.T) {
-		nilRootTrie := new(Trie)
+		nilRootTrie := new(Trie[string])
 		err := nilRootTrie.Delete("key")
 		if !errors.Is(err, ErrEmptyTrie) {
 			t.Error("expected ErrEmptyTrie")
@@ -115,7 +146,7 @@ func TestTrie_Delete(t *testing.T) {
 	})
 
 	t.Run("empty key", func(t *testing.T) {
-		trie := NewTrie()
+		trie := NewTrie[string]()
 		err := trie.Delete("")
 		if !errors.Is(err, ErrEmptyKey) {
 			t.Error("expected ErrEmptyKey")
@@ -123,7 +154,7 @@ func TestTrie_Delete(t *testing.T) {
 	})
 
 	t.Run("non existing key", func(t *testing.T) {
-		trie := NewTrie()
+		trie := NewTrie[string]()
 		err := trie.Delete("key")
 		if !errors.Is(err, ErrKeyNotFound) {
 			t.Error("expected ErrKeyNotFound")
@@ -140,7 +171,7 @@ func TestTrie_Delete(t *testing.T) {
 	})
 
 	t.Run("normal case", func(t *testing.T) {
-		trie := NewTrie()
+		trie := NewTrie[string]()
 		err := trie.Put("key", "value")
 		if err != nil {
 			t.Error(err)
@@ -151,3 +182,367 @@ func TestTrie_Delete(t *testing.T) {
 		}
 	})
 }
+
+func newTestTrie(t *testing.T, keys ...string) *Trie[int] {
+	trie := NewTrie[int]()
+	for i, key := range keys {
+		if err := trie.Put(key, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return trie
+}
+
+func TestTrie_WalkPrefix(t *testing.T) {
+	trie := newTestTrie(t, "table", "tab", "tables", "apple", "tablet", "tac")
+
+	t.Run("prefix", func(t *testing.T) {
+		var keys []string
+		trie.WalkPrefix("tab", func(key string, val int) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if !slices.Equal(keys, []string{"tab", "table", "tables", "tablet"}) {
+			t.Errorf("unexpected keys: %v", keys)
+		}
+	})
+
+	t.Run("early termination", func(t *testing.T) {
+		var keys []string
+		trie.WalkPrefix("", func(key string, val int) bool {
+			keys = append(keys, key)
+			return len(keys) < 2
+		})
+		if !slices.Equal(keys, []string{"apple", "tab"}) {
+			t.Errorf("unexpected keys: %v", keys)
+		}
+	})
+
+	t.Run("missing prefix", func(t *testing.T) {
+		trie.WalkPrefix("x", func(key string, val int) bool {
+			t.Errorf("unexpected key %s", key)
+			return true
+		})
+	})
+
+	t.Run("nil root", func(t *testing.T) {
+		new(Trie[int]).WalkPrefix("", func(key string, val int) bool {
+			t.Errorf("unexpected key %s", key)
+			return true
+		})
+	})
+}
+
+func TestTrie_Range(t *testing.T) {
+	trie := newTestTrie(t, "b", "ab", "abc", "ba", "c", "a")
+
+	cases := []struct {
+		start, end string
+		expected   []string
+	}{
+		{"", "", []string{"a", "ab", "abc", "b", "ba", "c"}},
+		{"ab", "b", []string{"ab", "abc"}},
+		{"aa", "ba", []string{"ab", "abc", "b"}},
+		{"abd", "", []string{"b", "ba", "c"}},
+		{"d", "", nil},
+	}
+
+	for _, c := range cases {
+		var keys []string
+		for key, val := range trie.Range(c.start, c.end) {
+			if got, _ := trie.Get(key); got != val {
+				t.Errorf("value mismatch for %s", key)
+			}
+			keys = append(keys, key)
+		}
+		if !slices.Equal(keys, c.expected) {
+			t.Errorf("Range(%q, %q): expected %v, got %v", c.start, c.end, c.expected, keys)
+		}
+	}
+
+	// early termination
+	for key := range trie.Range("", "") {
+		if key != "a" {
+			t.Errorf("unexpected key %s", key)
+		}
+		break
+	}
+}
+
+func TestTrie_Keys(t *testing.T) {
+	trie := newTestTrie(t, "b", "a", "ab")
+	if err := trie.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := slices.Collect(trie.Keys())
+	if !slices.Equal(keys, []string{"ab", "b"}) {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestTrie_LongestPrefixOf(t *testing.T) {
+	trie := newTestTrie(t, "config", "config.db", "config.db.pool")
+
+	cases := []struct {
+		key, expected string
+		found         bool
+	}{
+		{"config.db.pool.size", "config.db.pool", true},
+		{"config.db.p", "config.db", true},
+		{"config.d", "config", true},
+		{"config", "config", true},
+		{"conf", "", false},
+		{"other", "", false},
+		{"", "", false},
+	}
+
+	for _, c := range cases {
+		key, val, found := trie.LongestPrefixOf(c.key)
+		if key != c.expected || found != c.found {
+			t.Errorf("LongestPrefixOf(%q): expected %q, got %q", c.key, c.expected, key)
+		}
+		if found {
+			if expected, _ := trie.Get(key); val != expected {
+				t.Errorf("LongestPrefixOf(%q): value mismatch", c.key)
+			}
+		}
+	}
+
+	if _, _, found := new(Trie[int]).LongestPrefixOf("key"); found {
+		t.Error("expected nothing found in nil root trie")
+	}
+}
+
+func TestTrie_CountWithPrefix(t *testing.T) {
+	trie := newTestTrie(t, "tab", "table", "tables", "tac", "apple")
+
+	if trie.Len() != 5 {
+		t.Errorf("expected 5 keys, got %d", trie.Len())
+	}
+	if n := trie.CountWithPrefix("tab"); n != 3 {
+		t.Errorf("expected 3 keys with prefix tab, got %d", n)
+	}
+	if n := trie.CountWithPrefix("x"); n != 0 {
+		t.Errorf("expected 0 keys with prefix x, got %d", n)
+	}
+
+	// failed puts and deletes keep the counts
+	if err := trie.Put("table", 0); !errors.Is(err, ErrKeyExists) {
+		t.Error("expected ErrKeyExists")
+	}
+	if err := trie.Delete("ta"); !errors.Is(err, ErrKeyNotFound) {
+		t.Error("expected ErrKeyNotFound")
+	}
+	if n := trie.CountWithPrefix("ta"); n != 4 {
+		t.Errorf("expected 4 keys with prefix ta, got %d", n)
+	}
+
+	if err := trie.Delete("table"); err != nil {
+		t.Fatal(err)
+	}
+	if n := trie.CountWithPrefix("tab"); n != 2 {
+		t.Errorf("expected 2 keys with prefix tab, got %d", n)
+	}
+	if trie.Len() != 4 {
+		t.Errorf("expected 4 keys, got %d", trie.Len())
+	}
+}
+
+func TestTrie_Compression(t *testing.T) {
+	trie := newTestTrie(t, "romane", "romanus", "romulus", "rubens")
+
+	// "r" splits into "om" and "ubens", "om" into "an" and "ulus"
+	r := trie.root.child('r')
+	if r == nil || r.prefix != "r" || r.numChilds() != 2 {
+		t.Fatal("expected edge r with two children")
+	}
+	if om := r.child('o'); om == nil || om.prefix != "om" {
+		t.Fatal("expected compressed edge om")
+	}
+
+	for _, key := range []string{"romane", "romanus", "romulus"} {
+		if err := trie.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the branch is released and "r" is merged with "ubens" again
+	r = trie.root.child('r')
+	if r == nil || r.prefix != "rubens" || r.numChilds() != 0 {
+		t.Fatalf("expected a single leaf rubens, got %q", r.prefix)
+	}
+	if val, ok := trie.Get("rubens"); !ok || val != 3 {
+		t.Error("expected rubens to be kept")
+	}
+
+	if err := trie.Delete("rubens"); err != nil {
+		t.Fatal(err)
+	}
+	if trie.root.numChilds() != 0 || trie.Len() != 0 {
+		t.Error("expected empty trie")
+	}
+}
+
+func TestTrie_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	trie := NewTrie[int]()
+	expected := make(map[string]int)
+
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("%x", rnd.Intn(2000))
+		if _, ok := expected[key]; ok && rnd.Intn(2) == 0 {
+			if err := trie.Delete(key); err != nil {
+				t.Fatal(err)
+			}
+			delete(expected, key)
+			continue
+		}
+
+		err := trie.Put(key, i)
+		if _, ok := expected[key]; ok {
+			if !errors.Is(err, ErrKeyExists) {
+				t.Fatal("expected ErrKeyExists")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected[key] = i
+	}
+
+	if trie.Len() != len(expected) {
+		t.Fatalf("expected %d keys, got %d", len(expected), trie.Len())
+	}
+	for key, val := range expected {
+		if got, ok := trie.Get(key); !ok || got != val {
+			t.Fatalf("expected %s = %d, got %d", key, val, got)
+		}
+	}
+
+	keys := slices.Collect(trie.Keys())
+	if !slices.IsSorted(keys) || len(keys) != len(expected) {
+		t.Error("expected all keys in sorted order")
+	}
+}
+
+// mapTrie is the former layout of Trie, one map per key byte,
+// kept as a baseline for the benchmarks
+type mapTrie struct {
+	val    int
+	hasVal bool
+	childs map[byte]*mapTrie
+}
+
+func (t *mapTrie) put(key string, val int) {
+	cur := t
+	for i := 0; i < len(key); i++ {
+		if cur.childs == nil {
+			cur.childs = make(map[byte]*mapTrie)
+		}
+		next, ok := cur.childs[key[i]]
+		if !ok {
+			next = &mapTrie{}
+			cur.childs[key[i]] = next
+		}
+		cur = next
+	}
+	cur.val, cur.hasVal = val, true
+}
+
+func (t *mapTrie) get(key string) (int, bool) {
+	cur := t
+	for i := 0; i < len(key); i++ {
+		next, ok := cur.childs[key[i]]
+		if !ok {
+			return 0, false
+		}
+		cur = next
+	}
+	return cur.val, cur.hasVal
+}
+
+func benchmarkKeys(n int) []string {
+	rnd := rand.New(rand.NewSource(1))
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("catalog.table_%d.column_%d", rnd.Intn(n), i)
+	}
+	return keys
+}
+
+// heapBytesPerKey reports the heap growth caused by build divided by n
+func heapBytesPerKey(b *testing.B, n int, build func() any) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	v := build()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(v)
+
+	b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/float64(n), "heap-bytes/key")
+}
+
+func BenchmarkTrie_Put(b *testing.B) {
+	keys := benchmarkKeys(10000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie := NewTrie[int]()
+		for j, key := range keys {
+			_ = trie.Put(key, j)
+		}
+	}
+	heapBytesPerKey(b, len(keys), func() any {
+		trie := NewTrie[int]()
+		for j, key := range keys {
+			_ = trie.Put(key, j)
+		}
+		return trie
+	})
+}
+
+func BenchmarkMapTrie_Put(b *testing.B) {
+	keys := benchmarkKeys(10000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trie := &mapTrie{}
+		for j, key := range keys {
+			trie.put(key, j)
+		}
+	}
+	heapBytesPerKey(b, len(keys), func() any {
+		trie := &mapTrie{}
+		for j, key := range keys {
+			trie.put(key, j)
+		}
+		return trie
+	})
+}
+
+func BenchmarkTrie_Get(b *testing.B) {
+	keys := benchmarkKeys(10000)
+	trie := NewTrie[int]()
+	for j, key := range keys {
+		_ = trie.Put(key, j)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = trie.Get(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkMapTrie_Get(b *testing.B) {
+	keys := benchmarkKeys(10000)
+	trie := &mapTrie{}
+	for j, key := range keys {
+		trie.put(key, j)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = trie.get(keys[i%len(keys)])
+	}
+}
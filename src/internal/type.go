@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TypeID 列的 SQL 类型
+type TypeID uint8
+
+const (
+	TypeInvalid TypeID = iota
+	TypeBoolean
+	TypeTinyInt
+	TypeSmallInt
+	TypeInteger
+	TypeBigInt
+	TypeDouble
+	TypeDecimal
+	TypeVarchar
+	TypeChar
+	TypeDate
+	TypeTimestamp
+	TypeBlob
+)
+
+const (
+	// MaxDecimalPrecision DECIMAL 以 int64 保存未缩放的值，最多 18 位十进制数字
+	MaxDecimalPrecision = 18
+	// MaxVarcharLength VARCHAR 和 CHAR 的最大字节数
+	MaxVarcharLength = 65535
+)
+
+var typeNames = [...]string{
+	TypeInvalid:   "INVALID",
+	TypeBoolean:   "BOOLEAN",
+	TypeTinyInt:   "TINYINT",
+	TypeSmallInt:  "SMALLINT",
+	TypeInteger:   "INTEGER",
+	TypeBigInt:    "BIGINT",
+	TypeDouble:    "DOUBLE",
+	TypeDecimal:   "DECIMAL",
+	TypeVarchar:   "VARCHAR",
+	TypeChar:      "CHAR",
+	TypeDate:      "DATE",
+	TypeTimestamp: "TIMESTAMP",
+	TypeBlob:      "BLOB",
+}
+
+func (id TypeID) String() string {
+	if int(id) < len(typeNames) {
+		return typeNames[id]
+	}
+
+	return fmt.Sprintf("TypeID(%d)", id)
+}
+
+// IsInteger TINYINT 到 BIGINT
+func (id TypeID) IsInteger() bool {
+	return id >= TypeTinyInt && id <= TypeBigInt
+}
+
+// IsNumeric 整数、DOUBLE 和 DECIMAL
+func (id TypeID) IsNumeric() bool {
+	return id.IsInteger() || id == TypeDouble || id == TypeDecimal
+}
+
+// IsString VARCHAR 和 CHAR
+func (id TypeID) IsString() bool {
+	return id == TypeVarchar || id == TypeChar
+}
+
+// Type 带参数的列类型
+// VARCHAR(n) 和 CHAR(n) 的 Length 为最大字节数，DECIMAL(p, s) 的 Length 为精度 p，Scale 为小数位数 s
+type Type struct {
+	ID     TypeID
+	Length int
+	Scale  int
+}
+
+func BooleanType() Type   { return Type{ID: TypeBoolean} }
+func TinyIntType() Type   { return Type{ID: TypeTinyInt} }
+func SmallIntType() Type  { return Type{ID: TypeSmallInt} }
+func IntegerType() Type   { return Type{ID: TypeInteger} }
+func BigIntType() Type    { return Type{ID: TypeBigInt} }
+func DoubleType() Type    { return Type{ID: TypeDouble} }
+func DateType() Type      { return Type{ID: TypeDate} }
+func TimestampType() Type { return Type{ID: TypeTimestamp} }
+func BlobType() Type      { return Type{ID: TypeBlob} }
+
+func DecimalType(precision, scale int) Type {
+	return Type{ID: TypeDecimal, Length: precision, Scale: scale}
+}
+
+func VarcharType(n int) Type {
+	return Type{ID: TypeVarchar, Length: n}
+}
+
+func CharType(n int) Type {
+	return Type{ID: TypeChar, Length: n}
+}
+
+// Validate 检查类型参数是否合法
+func (t Type) Validate() error {
+	switch t.ID {
+	case TypeBoolean, TypeTinyInt, TypeSmallInt, TypeInteger, TypeBigInt,
+		TypeDouble, TypeDate, TypeTimestamp, TypeBlob:
+		if t.Length != 0 || t.Scale != 0 {
+			return fmt.Errorf("%w: %s takes no parameters", ErrInvalidType, t.ID)
+		}
+	case TypeDecimal:
+		if t.Length < 1 || t.Length > MaxDecimalPrecision || t.Scale < 0 || t.Scale > t.Length {
+			return fmt.Errorf("%w: DECIMAL(%d, %d)", ErrInvalidType, t.Length, t.Scale)
+		}
+	case TypeVarchar, TypeChar:
+		if t.Length < 1 || t.Length > MaxVarcharLength || t.Scale != 0 {
+			return fmt.Errorf("%w: %s(%d)", ErrInvalidType, t.ID, t.Length)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidType, t.ID)
+	}
+
+	return nil
+}
+
+// FixedSize 返回定长类型编码后的字节数，变长类型 VARCHAR 和 BLOB 返回 false
+func (t Type) FixedSize() (int, bool) {
+	switch t.ID {
+	case TypeBoolean, TypeTinyInt:
+		return 1, true
+	case TypeSmallInt:
+		return 2, true
+	case TypeInteger, TypeDate:
+		return 4, true
+	case TypeBigInt, TypeDouble, TypeDecimal, TypeTimestamp:
+		return 8, true
+	case TypeChar:
+		return t.Length, true
+	}
+
+	return 0, false
+}
+
+func (t Type) String() string {
+	switch t.ID {
+	case TypeDecimal:
+		return fmt.Sprintf("DECIMAL(%d, %d)", t.Length, t.Scale)
+	case TypeVarchar, TypeChar:
+		return fmt.Sprintf("%s(%d)", t.ID, t.Length)
+	}
+
+	return t.ID.String()
+}
+
+// ParseType 解析 String 输出的类型名，如 "VARCHAR(20)"、"decimal(10,2)"
+// INT、BOOL、FLOAT 等常见别名也可以识别
+func ParseType(s string) (Type, error) {
+	name, args := strings.ToUpper(strings.TrimSpace(s)), ""
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		if !strings.HasSuffix(name, ")") {
+			return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, s)
+		}
+		name, args = strings.TrimSpace(name[:i]), name[i+1:len(name)-1]
+	}
+
+	var params []int
+	if args != "" {
+		for _, arg := range strings.Split(args, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil {
+				return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, s)
+			}
+			params = append(params, n)
+		}
+	}
+
+	var t Type
+	switch name {
+	case "BOOLEAN", "BOOL":
+		t = BooleanType()
+	case "TINYINT":
+		t = TinyIntType()
+	case "SMALLINT":
+		t = SmallIntType()
+	case "INTEGER", "INT":
+		t = IntegerType()
+	case "BIGINT":
+		t = BigIntType()
+	case "DOUBLE", "FLOAT", "REAL":
+		t = DoubleType()
+	case "DATE":
+		t = DateType()
+	case "TIMESTAMP":
+		t = TimestampType()
+	case "BLOB":
+		t = BlobType()
+	case "DECIMAL", "NUMERIC":
+		// 省略小数位数时为 0
+		if len(params) < 1 || len(params) > 2 {
+			return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, s)
+		}
+		t = DecimalType(params[0], 0)
+		if len(params) == 2 {
+			t.Scale = params[1]
+		}
+		params = nil
+	case "VARCHAR", "CHAR":
+		if len(params) != 1 {
+			return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, s)
+		}
+		t = VarcharType(params[0])
+		if name == "CHAR" {
+			t = CharType(params[0])
+		}
+		params = nil
+	default:
+		return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, s)
+	}
+
+	if len(params) != 0 {
+		return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, s)
+	}
+	if err := t.Validate(); err != nil {
+		return Type{}, err
+	}
+
+	return t, nil
+}
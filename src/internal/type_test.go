@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected Type
+	}{
+		{"BOOLEAN", BooleanType()},
+		{"bool", BooleanType()},
+		{"int", IntegerType()},
+		{"BIGINT", BigIntType()},
+		{"float", DoubleType()},
+		{"decimal(10,2)", DecimalType(10, 2)},
+		{"NUMERIC(5)", DecimalType(5, 0)},
+		{" VARCHAR( 20 ) ", VarcharType(20)},
+		{"char(3)", CharType(3)},
+		{"TIMESTAMP", TimestampType()},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			typ, err := ParseType(c.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typ != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, typ)
+			}
+
+			// String output parses back to the same type
+			if again, err := ParseType(typ.String()); err != nil || again != typ {
+				t.Errorf("expected %v to round trip, got %v", typ, again)
+			}
+		})
+	}
+
+	for _, in := range []string{"", "TEXT", "VARCHAR", "VARCHAR(0)", "VARCHAR(a)", "INT(3)", "DECIMAL(19, 2)", "DECIMAL(4, 5)", "CHAR(2"} {
+		t.Run("invalid "+in, func(t *testing.T) {
+			if _, err := ParseType(in); !errors.Is(err, ErrInvalidType) {
+				t.Errorf("expected ErrInvalidType, got %v", err)
+			}
+		})
+	}
+}
+
+func TestType_FixedSize(t *testing.T) {
+	cases := []struct {
+		typ   Type
+		size  int
+		fixed bool
+	}{
+		{BooleanType(), 1, true},
+		{SmallIntType(), 2, true},
+		{DateType(), 4, true},
+		{DecimalType(10, 2), 8, true},
+		{CharType(12), 12, true},
+		{VarcharType(12), 0, false},
+		{BlobType(), 0, false},
+	}
+	for _, c := range cases {
+		size, fixed := c.typ.FixedSize()
+		if size != c.size || fixed != c.fixed {
+			t.Errorf("%v: expected (%d, %v), got (%d, %v)", c.typ, c.size, c.fixed, size, fixed)
+		}
+	}
+}
@@ -0,0 +1,760 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DateFormat DATE 的文本格式
+	DateFormat = "2006-01-02"
+	// TimestampFormat TIMESTAMP 的文本格式，精度为微秒，时区为 UTC
+	TimestampFormat = "2006-01-02 15:04:05.999999"
+
+	microsPerDay = 24 * 60 * 60 * 1000 * 1000
+)
+
+// pow10 10 的 0 到 18 次方，DECIMAL 缩放用
+var pow10 = func() [MaxDecimalPrecision + 1]int64 {
+	var p [MaxDecimalPrecision + 1]int64
+	p[0] = 1
+	for i := 1; i < len(p); i++ {
+		p[i] = p[i-1] * 10
+	}
+	return p
+}()
+
+// Value 一个带类型的 SQL 值，可以为 NULL
+// 零值为 INVALID 类型，不能参与任何运算
+type Value struct {
+	typ  TypeID
+	null bool
+
+	// i 保存 BOOLEAN、整数、DECIMAL 未缩放的值、DATE 距 1970-01-01 的天数和 TIMESTAMP 的 Unix 微秒数
+	i int64
+	f float64
+	// s 保存 VARCHAR、CHAR 和 BLOB 的内容，CHAR 去掉了末尾的空格
+	s string
+	// scale DECIMAL 的小数位数
+	scale int
+}
+
+// NewNull 返回指定类型的 NULL
+func NewNull(id TypeID) Value {
+	return Value{typ: id, null: true}
+}
+
+func NewBoolean(b bool) Value {
+	v := Value{typ: TypeBoolean}
+	if b {
+		v.i = 1
+	}
+	return v
+}
+
+func NewTinyInt(i int8) Value   { return Value{typ: TypeTinyInt, i: int64(i)} }
+func NewSmallInt(i int16) Value { return Value{typ: TypeSmallInt, i: int64(i)} }
+func NewInteger(i int32) Value  { return Value{typ: TypeInteger, i: int64(i)} }
+func NewBigInt(i int64) Value   { return Value{typ: TypeBigInt, i: i} }
+func NewDouble(f float64) Value { return Value{typ: TypeDouble, f: f} }
+
+// NewDecimal 返回 unscaled * 10^-scale
+// scale 超出 [0, MaxDecimalPrecision] 时按四舍五入调整到范围内，结果超过 MaxDecimalPrecision 位数字时返回 ErrOverflow
+func NewDecimal(unscaled int64, scale int) (Value, error) {
+	if scale < 0 || scale > MaxDecimalPrecision {
+		// |unscaled| < 10^19，指数再大结果也只会是 0 或者溢出
+		r := new(big.Rat).SetInt64(unscaled)
+		e := min(max(scale, -40), 40)
+		exp := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(max(e, -e))), nil)
+		if scale > 0 {
+			r.Quo(r, new(big.Rat).SetInt(exp))
+		} else {
+			r.Mul(r, new(big.Rat).SetInt(exp))
+		}
+
+		return ratToDecimal(r, min(max(scale, 0), MaxDecimalPrecision))
+	}
+
+	if absInt64(unscaled) >= uint64(pow10[MaxDecimalPrecision]) {
+		return Value{}, fmt.Errorf("%w: DECIMAL out of range", ErrOverflow)
+	}
+
+	return Value{typ: TypeDecimal, i: unscaled, scale: scale}, nil
+}
+
+// ParseDecimal 解析 "-123.45" 形式的十进制数
+func ParseDecimal(s string) (Value, error) {
+	s = strings.TrimSpace(s)
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return Value{}, fmt.Errorf("%w: %q is not a decimal", ErrInvalidCast, s)
+	}
+
+	scale := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		scale = len(s) - i - 1
+	}
+	if scale > MaxDecimalPrecision {
+		return Value{}, fmt.Errorf("%w: %q has too many digits", ErrOverflow, s)
+	}
+
+	return ratToDecimal(r, scale)
+}
+
+func NewVarchar(s string) Value { return Value{typ: TypeVarchar, s: s} }
+
+func NewChar(s string) Value {
+	return Value{typ: TypeChar, s: strings.TrimRight(s, " ")}
+}
+
+// NewDate 取 t 在其所在时区的日期
+func NewDate(t time.Time) Value {
+	y, m, d := t.Date()
+	days := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+	return Value{typ: TypeDate, i: days}
+}
+
+// NewTimestamp 精度截断到微秒
+func NewTimestamp(t time.Time) Value {
+	return Value{typ: TypeTimestamp, i: t.UnixMicro()}
+}
+
+func NewBlob(b []byte) Value {
+	return Value{typ: TypeBlob, s: string(b)}
+}
+
+// TypeID 值的类型
+func (v Value) TypeID() TypeID {
+	return v.typ
+}
+
+func (v Value) IsNull() bool {
+	return v.null
+}
+
+// Bool 仅对 BOOLEAN 有意义
+func (v Value) Bool() bool {
+	return v.i != 0
+}
+
+// Int 返回整数值，DECIMAL 和 DOUBLE 向零截断
+func (v Value) Int() int64 {
+	switch v.typ {
+	case TypeDouble:
+		return int64(v.f)
+	case TypeDecimal:
+		return v.i / pow10[v.scale]
+	}
+
+	return v.i
+}
+
+// Float 返回数值类型的浮点值
+func (v Value) Float() float64 {
+	switch v.typ {
+	case TypeDouble:
+		return v.f
+	case TypeDecimal:
+		return float64(v.i) / float64(pow10[v.scale])
+	}
+
+	return float64(v.i)
+}
+
+// Decimal 返回 DECIMAL 未缩放的值和小数位数，整数的小数位数为 0
+func (v Value) Decimal() (int64, int) {
+	return v.i, v.scale
+}
+
+// Str 返回 VARCHAR、CHAR 和 BLOB 的内容
+func (v Value) Str() string {
+	return v.s
+}
+
+func (v Value) Bytes() []byte {
+	return []byte(v.s)
+}
+
+// Time 返回 DATE 和 TIMESTAMP 对应的 UTC 时间
+func (v Value) Time() time.Time {
+	if v.typ == TypeDate {
+		return time.UnixMicro(v.i * microsPerDay).UTC()
+	}
+
+	return time.UnixMicro(v.i).UTC()
+}
+
+func (v Value) String() string {
+	if v.null {
+		return "NULL"
+	}
+
+	switch v.typ {
+	case TypeBoolean:
+		return strconv.FormatBool(v.Bool())
+	case TypeTinyInt, TypeSmallInt, TypeInteger, TypeBigInt:
+		return strconv.FormatInt(v.i, 10)
+	case TypeDouble:
+		return strconv.FormatFloat(v.f, 'g', -1, 64)
+	case TypeDecimal:
+		return formatDecimal(v.i, v.scale)
+	case TypeVarchar, TypeChar:
+		return v.s
+	case TypeDate:
+		return v.Time().Format(DateFormat)
+	case TypeTimestamp:
+		return v.Time().Format(TimestampFormat)
+	case TypeBlob:
+		return "0x" + hex.EncodeToString([]byte(v.s))
+	}
+
+	return v.typ.String()
+}
+
+func formatDecimal(unscaled int64, scale int) string {
+	s := strconv.FormatUint(absInt64(unscaled), 10)
+	if scale > 0 {
+		if len(s) <= scale {
+			s = strings.Repeat("0", scale-len(s)+1) + s
+		}
+		s = s[:len(s)-scale] + "." + s[len(s)-scale:]
+	}
+	if unscaled < 0 {
+		s = "-" + s
+	}
+
+	return s
+}
+
+func absInt64(i int64) uint64 {
+	if i < 0 {
+		return uint64(-i)
+	}
+	return uint64(i)
+}
+
+// class 可以互相比较的类型归为一类
+func (id TypeID) class() int {
+	switch {
+	case id.IsNumeric():
+		return 1
+	case id.IsString():
+		return 2
+	case id == TypeDate || id == TypeTimestamp:
+		return 3
+	}
+
+	return 4 + int(id)
+}
+
+// Compare 比较两个值，返回 -1、0 或 1
+// 数值类型之间、字符串类型之间、DATE 和 TIMESTAMP 之间可以比较，否则返回 ErrTypeMismatch
+// NULL 等于 NULL 且小于任何非 NULL 值，便于排序和索引
+func (v Value) Compare(o Value) (int, error) {
+	if v.typ == TypeInvalid || o.typ == TypeInvalid || v.typ.class() != o.typ.class() {
+		return 0, fmt.Errorf("%w: cannot compare %s with %s", ErrTypeMismatch, v.typ, o.typ)
+	}
+
+	switch {
+	case v.null && o.null:
+		return 0, nil
+	case v.null:
+		return -1, nil
+	case o.null:
+		return 1, nil
+	}
+
+	switch {
+	case v.typ == TypeDouble || o.typ == TypeDouble:
+		return compareOrdered(v.Float(), o.Float()), nil
+	case v.typ == TypeDecimal || o.typ == TypeDecimal:
+		a, b := v.bigDecimal(), o.bigDecimal()
+		return a.Cmp(b), nil
+	case v.typ.IsString(), v.typ == TypeBlob:
+		return strings.Compare(v.s, o.s), nil
+	case v.typ == TypeDate || v.typ == TypeTimestamp:
+		return compareOrdered(v.micros(), o.micros()), nil
+	}
+
+	return compareOrdered(v.i, o.i), nil
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// bigDecimal 数值的精确有理数表示，DOUBLE 除外
+func (v Value) bigDecimal() *big.Rat {
+	if v.typ == TypeDecimal {
+		return big.NewRat(v.i, pow10[v.scale])
+	}
+
+	return new(big.Rat).SetInt64(v.i)
+}
+
+// micros DATE 和 TIMESTAMP 统一为微秒
+func (v Value) micros() int64 {
+	if v.typ == TypeDate {
+		return v.i * microsPerDay
+	}
+
+	return v.i
+}
+
+// arithmeticType 二元算术运算的结果类型：
+// 有 DOUBLE 则为 DOUBLE，否则有 DECIMAL 则为 DECIMAL，否则为更宽的整数类型
+func arithmeticType(a, b TypeID) (TypeID, error) {
+	if !a.IsNumeric() || !b.IsNumeric() {
+		return TypeInvalid, fmt.Errorf("%w: arithmetic on %s and %s", ErrTypeMismatch, a, b)
+	}
+
+	switch {
+	case a == TypeDouble || b == TypeDouble:
+		return TypeDouble, nil
+	case a == TypeDecimal || b == TypeDecimal:
+		return TypeDecimal, nil
+	}
+
+	return max(a, b), nil
+}
+
+func (v Value) Add(o Value) (Value, error)      { return v.arithmetic(o, '+') }
+func (v Value) Subtract(o Value) (Value, error) { return v.arithmetic(o, '-') }
+func (v Value) Multiply(o Value) (Value, error) { return v.arithmetic(o, '*') }
+
+// Divide 整数相除向零截断，除数为 0 时返回 ErrDivisionByZero
+func (v Value) Divide(o Value) (Value, error) { return v.arithmetic(o, '/') }
+func (v Value) Modulo(o Value) (Value, error) { return v.arithmetic(o, '%') }
+
+// arithmetic 任一操作数为 NULL 时结果为 NULL，溢出时返回 ErrOverflow
+func (v Value) arithmetic(o Value, op byte) (Value, error) {
+	typ, err := arithmeticType(v.typ, o.typ)
+	if err != nil {
+		return Value{}, err
+	}
+	if v.null || o.null {
+		return NewNull(typ), nil
+	}
+
+	switch typ {
+	case TypeDouble:
+		return doubleArithmetic(v.Float(), o.Float(), op)
+	case TypeDecimal:
+		return decimalArithmetic(v, o, op)
+	}
+
+	a, b := big.NewInt(v.i), big.NewInt(o.i)
+	switch op {
+	case '+':
+		a.Add(a, b)
+	case '-':
+		a.Sub(a, b)
+	case '*':
+		a.Mul(a, b)
+	case '/', '%':
+		if b.Sign() == 0 {
+			return Value{}, ErrDivisionByZero
+		}
+		if op == '/' {
+			a.Quo(a, b)
+		} else {
+			a.Rem(a, b)
+		}
+	}
+
+	if !a.IsInt64() {
+		return Value{}, fmt.Errorf("%w: %s out of range", ErrOverflow, typ)
+	}
+	result := Value{typ: typ, i: a.Int64()}
+	if err := result.checkIntRange(typ); err != nil {
+		return Value{}, err
+	}
+
+	return result, nil
+}
+
+func doubleArithmetic(a, b float64, op byte) (Value, error) {
+	var f float64
+	switch op {
+	case '+':
+		f = a + b
+	case '-':
+		f = a - b
+	case '*':
+		f = a * b
+	case '/', '%':
+		if b == 0 {
+			return Value{}, ErrDivisionByZero
+		}
+		if op == '/' {
+			f = a / b
+		} else {
+			f = math.Mod(a, b)
+		}
+	}
+
+	if math.IsInf(f, 0) {
+		return Value{}, fmt.Errorf("%w: DOUBLE out of range", ErrOverflow)
+	}
+
+	return NewDouble(f), nil
+}
+
+// decimalArithmetic 加减和取模的小数位数取两者中较大的，乘法为两者之和，
+// 除法保留被除数的小数位数且至少 4 位
+func decimalArithmetic(v, o Value, op byte) (Value, error) {
+	scale := max(v.scale, o.scale)
+	switch op {
+	case '*':
+		scale = min(v.scale+o.scale, MaxDecimalPrecision)
+	case '/':
+		scale = min(max(v.scale, 4), MaxDecimalPrecision)
+	}
+
+	a, b := v.bigDecimal(), o.bigDecimal()
+	r := new(big.Rat)
+	switch op {
+	case '+':
+		r.Add(a, b)
+	case '-':
+		r.Sub(a, b)
+	case '*':
+		r.Mul(a, b)
+	case '/', '%':
+		if b.Sign() == 0 {
+			return Value{}, ErrDivisionByZero
+		}
+		if op == '/' {
+			r.Quo(a, b)
+		} else {
+			// a - b * trunc(a / b)
+			q := new(big.Rat).Quo(a, b)
+			trunc := new(big.Int).Quo(q.Num(), q.Denom())
+			r.Sub(a, q.Mul(b, q.SetInt(trunc)))
+		}
+	}
+
+	return ratToDecimal(r, scale)
+}
+
+// ratToDecimal 按四舍五入缩放到 scale 位小数
+func ratToDecimal(r *big.Rat, scale int) (Value, error) {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt64(pow10[scale]))
+	num, denom := scaled.Num(), scaled.Denom()
+
+	// 远离零方向舍入：(2 * |num| + denom) / (2 * denom)
+	n := new(big.Int).Abs(num)
+	n.Lsh(n, 1).Add(n, denom)
+	n.Quo(n, new(big.Int).Lsh(denom, 1))
+	if num.Sign() < 0 {
+		n.Neg(n)
+	}
+
+	if !n.IsInt64() {
+		return Value{}, fmt.Errorf("%w: DECIMAL out of range", ErrOverflow)
+	}
+
+	return NewDecimal(n.Int64(), scale)
+}
+
+// checkIntRange 检查整数值能否放入 id
+func (v Value) checkIntRange(id TypeID) error {
+	var lo, hi int64
+	switch id {
+	case TypeTinyInt:
+		lo, hi = math.MinInt8, math.MaxInt8
+	case TypeSmallInt:
+		lo, hi = math.MinInt16, math.MaxInt16
+	case TypeInteger:
+		lo, hi = math.MinInt32, math.MaxInt32
+	default:
+		return nil
+	}
+
+	if v.i < lo || v.i > hi {
+		return fmt.Errorf("%w: %d out of range for %s", ErrOverflow, v.i, id)
+	}
+
+	return nil
+}
+
+// CastAs 将值转换为类型 t，NULL 转换为 t 的 NULL
+// 超出整数范围或 DECIMAL 精度时返回 ErrOverflow，字符串超长时返回 ErrValueTooLong，
+// 不支持的转换返回 ErrInvalidCast
+func (v Value) CastAs(t Type) (Value, error) {
+	if err := t.Validate(); err != nil {
+		return Value{}, err
+	}
+	if v.typ == TypeInvalid {
+		return Value{}, fmt.Errorf("%w: invalid value", ErrInvalidCast)
+	}
+	if v.null {
+		return NewNull(t.ID), nil
+	}
+
+	var (
+		result Value
+		err    error
+	)
+	switch t.ID {
+	case TypeBoolean:
+		result, err = v.castBoolean()
+	case TypeTinyInt, TypeSmallInt, TypeInteger, TypeBigInt:
+		result, err = v.castInteger(t.ID)
+	case TypeDouble:
+		result, err = v.castDouble()
+	case TypeDecimal:
+		result, err = v.castDecimal(t)
+	case TypeVarchar, TypeChar:
+		result, err = v.castString(t)
+	case TypeDate, TypeTimestamp:
+		result, err = v.castTime(t.ID)
+	case TypeBlob:
+		if v.typ != TypeBlob && !v.typ.IsString() {
+			return Value{}, v.invalidCast(t)
+		}
+		result = NewBlob([]byte(v.s))
+	}
+	if err != nil {
+		return Value{}, err
+	}
+
+	return result, nil
+}
+
+func (v Value) invalidCast(t Type) error {
+	return fmt.Errorf("%w: %s to %s", ErrInvalidCast, v.typ, t)
+}
+
+func (v Value) castBoolean() (Value, error) {
+	switch {
+	case v.typ == TypeBoolean:
+		return v, nil
+	case v.typ.IsInteger():
+		return NewBoolean(v.i != 0), nil
+	case v.typ.IsString():
+		b, err := strconv.ParseBool(strings.TrimSpace(v.s))
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: %q is not a boolean", ErrInvalidCast, v.s)
+		}
+		return NewBoolean(b), nil
+	}
+
+	return Value{}, v.invalidCast(BooleanType())
+}
+
+func (v Value) castInteger(id TypeID) (Value, error) {
+	result := Value{typ: id}
+	switch {
+	case v.typ == TypeBoolean || v.typ.IsInteger():
+		result.i = v.i
+	case v.typ == TypeDouble:
+		f := math.Round(v.f)
+		if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return Value{}, fmt.Errorf("%w: %v out of range for %s", ErrOverflow, v.f, id)
+		}
+		result.i = int64(f)
+	case v.typ == TypeDecimal:
+		d, err := ratToDecimal(v.bigDecimal(), 0)
+		if err != nil {
+			return Value{}, err
+		}
+		result.i = d.i
+	case v.typ.IsString():
+		i, err := strconv.ParseInt(strings.TrimSpace(v.s), 10, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: %q is not an integer", ErrInvalidCast, v.s)
+		}
+		result.i = i
+	default:
+		return Value{}, v.invalidCast(Type{ID: id})
+	}
+
+	if err := result.checkIntRange(id); err != nil {
+		return Value{}, err
+	}
+
+	return result, nil
+}
+
+func (v Value) castDouble() (Value, error) {
+	switch {
+	case v.typ.IsNumeric():
+		return NewDouble(v.Float()), nil
+	case v.typ.IsString():
+		f, err := strconv.ParseFloat(strings.TrimSpace(v.s), 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: %q is not a number", ErrInvalidCast, v.s)
+		}
+		return NewDouble(f), nil
+	}
+
+	return Value{}, v.invalidCast(DoubleType())
+}
+
+// castDecimal 按 t 的小数位数四舍五入，并检查整数部分不超过精度
+func (v Value) castDecimal(t Type) (Value, error) {
+	var r *big.Rat
+	switch {
+	case v.typ == TypeDouble:
+		if math.IsNaN(v.f) || math.IsInf(v.f, 0) {
+			return Value{}, fmt.Errorf("%w: %v out of range for %s", ErrOverflow, v.f, t)
+		}
+		r = new(big.Rat).SetFloat64(v.f)
+	case v.typ.IsNumeric():
+		r = v.bigDecimal()
+	case v.typ.IsString():
+		d, err := ParseDecimal(v.s)
+		if err != nil {
+			return Value{}, err
+		}
+		r = d.bigDecimal()
+	default:
+		return Value{}, v.invalidCast(t)
+	}
+
+	result, err := ratToDecimal(r, t.Scale)
+	if err != nil {
+		return Value{}, err
+	}
+	if absInt64(result.i) >= uint64(pow10[t.Length]) {
+		return Value{}, fmt.Errorf("%w: %s out of range for %s", ErrOverflow, v, t)
+	}
+
+	return result, nil
+}
+
+func (v Value) castString(t Type) (Value, error) {
+	s := v.String()
+	if v.typ == TypeBlob {
+		s = v.s
+	}
+
+	if t.ID == TypeChar {
+		s = strings.TrimRight(s, " ")
+	}
+	if len(s) > t.Length {
+		return Value{}, fmt.Errorf("%w: %d bytes for %s", ErrValueTooLong, len(s), t)
+	}
+
+	return Value{typ: t.ID, s: s}, nil
+}
+
+func (v Value) castTime(id TypeID) (Value, error) {
+	var micros int64
+	switch {
+	case v.typ == TypeDate || v.typ == TypeTimestamp:
+		micros = v.micros()
+	case v.typ.IsString():
+		t, err := parseTime(strings.TrimSpace(v.s))
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: %q is not a %s", ErrInvalidCast, v.s, id)
+		}
+		micros = t.UnixMicro()
+	default:
+		return Value{}, v.invalidCast(Type{ID: id})
+	}
+
+	if id == TypeDate {
+		// 向下取整到当天零点
+		days := micros / microsPerDay
+		if micros < 0 && micros%microsPerDay != 0 {
+			days--
+		}
+		return Value{typ: TypeDate, i: days}, nil
+	}
+
+	return Value{typ: TypeTimestamp, i: micros}, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	var err error
+	for _, layout := range []string{TimestampFormat, time.RFC3339Nano, DateFormat} {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// AppendBinary 将值转换为类型 t 后追加其二进制编码，NULL 返回 ErrNullValue
+// 定长类型编码为 t.FixedSize() 个字节，整数为小端序，CHAR 用空格补齐，
+// VARCHAR 和 BLOB 为原始字节，长度由调用方保存
+func (v Value) AppendBinary(buf []byte, t Type) ([]byte, error) {
+	if v.null {
+		return nil, ErrNullValue
+	}
+	v, err := v.CastAs(t)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.ID {
+	case TypeBoolean, TypeTinyInt:
+		return append(buf, byte(v.i)), nil
+	case TypeSmallInt:
+		return binary.LittleEndian.AppendUint16(buf, uint16(v.i)), nil
+	case TypeInteger, TypeDate:
+		return binary.LittleEndian.AppendUint32(buf, uint32(v.i)), nil
+	case TypeBigInt, TypeDecimal, TypeTimestamp:
+		return binary.LittleEndian.AppendUint64(buf, uint64(v.i)), nil
+	case TypeDouble:
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.f)), nil
+	case TypeChar:
+		buf = append(buf, v.s...)
+		return append(buf, bytes.Repeat([]byte{' '}, t.Length-len(v.s))...), nil
+	}
+
+	return append(buf, v.s...), nil
+}
+
+// DecodeValue 解码 AppendBinary 生成的类型为 t 的值
+func DecodeValue(t Type, data []byte) (Value, error) {
+	if size, ok := t.FixedSize(); ok && len(data) != size {
+		return Value{}, fmt.Errorf("%w: %d bytes for %s", ErrInvalidCast, len(data), t)
+	}
+
+	v := Value{typ: t.ID}
+	switch t.ID {
+	case TypeBoolean:
+		if data[0] != 0 {
+			v.i = 1
+		}
+	case TypeTinyInt:
+		v.i = int64(int8(data[0]))
+	case TypeSmallInt:
+		v.i = int64(int16(binary.LittleEndian.Uint16(data)))
+	case TypeInteger, TypeDate:
+		v.i = int64(int32(binary.LittleEndian.Uint32(data)))
+	case TypeBigInt, TypeTimestamp:
+		v.i = int64(binary.LittleEndian.Uint64(data))
+	case TypeDecimal:
+		v.i = int64(binary.LittleEndian.Uint64(data))
+		v.scale = t.Scale
+	case TypeDouble:
+		v.f = math.Float64frombits(binary.LittleEndian.Uint64(data))
+	case TypeChar:
+		v.s = strings.TrimRight(string(data), " ")
+	case TypeVarchar, TypeBlob:
+		v.s = string(data)
+	default:
+		return Value{}, fmt.Errorf("%w: %s", ErrInvalidType, t.ID)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,264 @@
+package internal
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestValue_Compare(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		a, b     Value
+		expected int
+	}{
+		{"integers", NewInteger(1), NewBigInt(2), -1},
+		{"integer and double", NewTinyInt(3), NewDouble(2.5), 1},
+		{"decimal and integer", mustDecimal(300, 2), NewSmallInt(3), 0},
+		{"decimals", mustDecimal(-15, 1), mustDecimal(-149, 2), -1},
+		{"strings", NewVarchar("abc"), NewChar("abd  "), -1},
+		{"char padding", NewChar("ab "), NewVarchar("ab"), 0},
+		{"date and timestamp", NewDate(day), NewTimestamp(day.Add(time.Second)), -1},
+		{"booleans", NewBoolean(true), NewBoolean(false), 1},
+		{"null first", NewNull(TypeInteger), NewInteger(math.MinInt32), -1},
+		{"nulls", NewNull(TypeInteger), NewNull(TypeDouble), 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.a.Compare(c.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.expected {
+				t.Errorf("expected %d, got %d", c.expected, got)
+			}
+		})
+	}
+
+	if _, err := NewInteger(1).Compare(NewVarchar("1")); !errors.Is(err, ErrTypeMismatch) {
+		t.Error("expected ErrTypeMismatch")
+	}
+	if _, err := (Value{}).Compare(Value{}); !errors.Is(err, ErrTypeMismatch) {
+		t.Error("expected ErrTypeMismatch for invalid values")
+	}
+}
+
+func TestValue_Arithmetic(t *testing.T) {
+	cases := []struct {
+		name     string
+		op       func(Value, Value) (Value, error)
+		a, b     Value
+		expected string
+		typ      TypeID
+	}{
+		{"add integers", Value.Add, NewSmallInt(300), NewInteger(5), "305", TypeInteger},
+		{"subtract", Value.Subtract, NewBigInt(5), NewTinyInt(7), "-2", TypeBigInt},
+		{"divide truncates", Value.Divide, NewInteger(-7), NewInteger(2), "-3", TypeInteger},
+		{"modulo", Value.Modulo, NewInteger(-7), NewInteger(2), "-1", TypeInteger},
+		{"double", Value.Multiply, NewDouble(1.5), NewInteger(3), "4.5", TypeDouble},
+		{"decimal add", Value.Add, mustDecimal(150, 2), mustDecimal(25, 1), "4.00", TypeDecimal},
+		{"decimal multiply", Value.Multiply, mustDecimal(15, 1), mustDecimal(15, 1), "2.25", TypeDecimal},
+		{"decimal divide", Value.Divide, mustDecimal(10, 0), NewInteger(3), "3.3333", TypeDecimal},
+		{"decimal modulo", Value.Modulo, mustDecimal(75, 1), NewInteger(2), "1.5", TypeDecimal},
+		{"null", Value.Add, NewNull(TypeInteger), NewDouble(1), "NULL", TypeDouble},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := c.op(c.a, c.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.String() != c.expected || v.TypeID() != c.typ {
+				t.Errorf("expected %s %s, got %s %s", c.typ, c.expected, v.TypeID(), v)
+			}
+		})
+	}
+
+	errCases := []struct {
+		name string
+		err  error
+		op   func(Value, Value) (Value, error)
+		a, b Value
+	}{
+		{"tinyint overflow", ErrOverflow, Value.Add, NewTinyInt(100), NewTinyInt(100)},
+		{"bigint overflow", ErrOverflow, Value.Multiply, NewBigInt(math.MaxInt64), NewBigInt(2)},
+		{"division by zero", ErrDivisionByZero, Value.Divide, NewInteger(1), NewInteger(0)},
+		{"double division by zero", ErrDivisionByZero, Value.Divide, NewDouble(1), NewDouble(0)},
+		{"decimal modulo by zero", ErrDivisionByZero, Value.Modulo, mustDecimal(1, 1), NewInteger(0)},
+		{"string", ErrTypeMismatch, Value.Add, NewVarchar("1"), NewInteger(1)},
+	}
+	for _, c := range errCases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := c.op(c.a, c.b); !errors.Is(err, c.err) {
+				t.Errorf("expected %v, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestValue_CastAs(t *testing.T) {
+	cases := []struct {
+		name     string
+		v        Value
+		typ      Type
+		expected string
+	}{
+		{"double to integer rounds", NewDouble(2.5), IntegerType(), "3"},
+		{"decimal to bigint", mustDecimal(-1250, 3), BigIntType(), "-1"},
+		{"integer to decimal", NewInteger(42), DecimalType(5, 2), "42.00"},
+		{"decimal rescale", mustDecimal(12345, 3), DecimalType(5, 2), "12.35"},
+		{"string to decimal", NewVarchar(" -0.5 "), DecimalType(3, 1), "-0.5"},
+		{"string to boolean", NewVarchar("true"), BooleanType(), "true"},
+		{"boolean to integer", NewBoolean(true), TinyIntType(), "1"},
+		{"integer to varchar", NewBigInt(-12), VarcharType(3), "-12"},
+		{"varchar to char", NewVarchar("ab  "), CharType(2), "ab"},
+		{"string to date", NewVarchar("2024-02-29"), DateType(), "2024-02-29"},
+		{"string to timestamp", NewVarchar("2024-02-29 10:11:12.5"), TimestampType(), "2024-02-29 10:11:12.5"},
+		{"timestamp to date", NewTimestamp(time.Date(1969, 12, 31, 23, 0, 0, 0, time.UTC)), DateType(), "1969-12-31"},
+		{"string to blob", NewVarchar("hi"), BlobType(), "0x6869"},
+		{"null", NewNull(TypeVarchar), IntegerType(), "NULL"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := c.v.CastAs(c.typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.String() != c.expected || v.TypeID() != c.typ.ID {
+				t.Errorf("expected %s %s, got %s %s", c.typ, c.expected, v.TypeID(), v)
+			}
+		})
+	}
+
+	errCases := []struct {
+		name string
+		err  error
+		v    Value
+		typ  Type
+	}{
+		{"tinyint range", ErrOverflow, NewInteger(200), TinyIntType()},
+		{"decimal precision", ErrOverflow, mustDecimal(12345, 2), DecimalType(4, 2)},
+		{"varchar length", ErrValueTooLong, NewVarchar("abcd"), VarcharType(3)},
+		{"not a number", ErrInvalidCast, NewVarchar("abc"), IntegerType()},
+		{"date to integer", ErrInvalidCast, NewDate(time.Now()), IntegerType()},
+		{"integer to blob", ErrInvalidCast, NewInteger(1), BlobType()},
+		{"invalid type", ErrInvalidType, NewInteger(1), VarcharType(0)},
+	}
+	for _, c := range errCases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := c.v.CastAs(c.typ); !errors.Is(err, c.err) {
+				t.Errorf("expected %v, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	for in, expected := range map[string]string{"1.50": "1.50", "-0.05": "-0.05", "+7": "7", ".5": "0.5"} {
+		v, err := ParseDecimal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.String() != expected {
+			t.Errorf("%s: expected %s, got %s", in, expected, v)
+		}
+	}
+
+	for _, in := range []string{"", "1/2", "1e3", "abc"} {
+		if _, err := ParseDecimal(in); !errors.Is(err, ErrInvalidCast) {
+			t.Errorf("%q: expected ErrInvalidCast, got %v", in, err)
+		}
+	}
+}
+
+// mustDecimal 返回 NewDecimal 的结果，只用于合法的常量
+func mustDecimal(unscaled int64, scale int) Value {
+	v, err := NewDecimal(unscaled, scale)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestNewDecimal(t *testing.T) {
+	cases := []struct {
+		unscaled int64
+		scale    int
+		expected string
+	}{
+		{12345, 2, "123.45"},
+		{15, -2, "1500"},
+		{-15, 19, "-0.000000000000000002"},
+		{1, 1000, "0.000000000000000000"},
+	}
+	for _, c := range cases {
+		v, err := NewDecimal(c.unscaled, c.scale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.String() != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, v)
+		}
+		// scale 调整到范围内后，转换不会越界
+		_, _ = v.Int(), v.Float()
+	}
+
+	// 超过 18 位数字的值放不进 DECIMAL
+	for _, c := range []struct {
+		unscaled int64
+		scale    int
+	}{{1, -1000}, {-1, math.MinInt}, {math.MaxInt64, 2}, {-1e18, 0}, {1e17, -1}} {
+		if _, err := NewDecimal(c.unscaled, c.scale); !errors.Is(err, ErrOverflow) {
+			t.Errorf("NewDecimal(%d, %d): expected ErrOverflow, got %v", c.unscaled, c.scale, err)
+		}
+	}
+}
+
+func TestValue_Binary(t *testing.T) {
+	ts := time.Date(2024, 2, 29, 10, 11, 12, 123456000, time.UTC)
+	cases := []struct {
+		v   Value
+		typ Type
+	}{
+		{NewBoolean(true), BooleanType()},
+		{NewTinyInt(-5), TinyIntType()},
+		{NewSmallInt(-300), SmallIntType()},
+		{NewInteger(math.MinInt32), IntegerType()},
+		{NewBigInt(math.MaxInt64), BigIntType()},
+		{NewDouble(-0.125), DoubleType()},
+		{mustDecimal(-12345, 2), DecimalType(10, 2)},
+		{NewVarchar("héllo"), VarcharType(10)},
+		{NewChar("ab"), CharType(5)},
+		{NewDate(ts), DateType()},
+		{NewTimestamp(ts), TimestampType()},
+		{NewBlob([]byte{0, 1, 255}), BlobType()},
+	}
+	for _, c := range cases {
+		t.Run(c.typ.String(), func(t *testing.T) {
+			data, err := c.v.AppendBinary(nil, c.typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size, ok := c.typ.FixedSize(); ok && len(data) != size {
+				t.Errorf("expected %d bytes, got %d", size, len(data))
+			}
+
+			v, err := DecodeValue(c.typ, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cmp, err := v.Compare(c.v); err != nil || cmp != 0 || v.String() != c.v.String() {
+				t.Errorf("expected %v, got %v", c.v, v)
+			}
+		})
+	}
+
+	if _, err := NewNull(TypeInteger).AppendBinary(nil, IntegerType()); !errors.Is(err, ErrNullValue) {
+		t.Error("expected ErrNullValue")
+	}
+	if _, err := DecodeValue(IntegerType(), []byte{1, 2}); err == nil {
+		t.Error("expected error for short data")
+	}
+}
@@ -0,0 +1,282 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+const (
+	// warmupMagic 预热文件的魔数 "GDBW"
+	warmupMagic = 0x47444257
+	// warmupVersion 预热文件格式版本
+	warmupVersion = 1
+)
+
+// WarmupEntry 一个驻留页面及其 LRU-K 访问历史
+// Ages 表示每次访问距离保存时刻的逻辑时钟间隔，从旧到新
+type WarmupEntry struct {
+	PageID int
+	Ages   []int
+}
+
+// WarmupState 返回当前驻留在缓冲池中的页面，按页面 id 排序
+func (m *BufferPoolManager) WarmupState() []WarmupEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := make([]WarmupEntry, 0, len(m.PageTable))
+	for pageID, p := range m.PageTable {
+		entries = append(entries, WarmupEntry{
+			PageID: pageID,
+			Ages:   m.Replacer.ages(p.frameID),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].PageID < entries[j].PageID
+	})
+
+	return entries
+}
+
+// SaveWarmup 将驻留页面列表写入 path，先写临时文件再重命名，避免留下写了一半的文件
+func (m *BufferPoolManager) SaveWarmup(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to create warmup file: %v", err)
+	}
+
+	w := bufio.NewWriter(f)
+	if err := writeWarmup(w, m.WarmupState()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write warmup file: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write warmup file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close warmup file: %v", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// LoadWarmup 读取 SaveWarmup 写入的页面列表
+func LoadWarmup(path string) ([]WarmupEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := readWarmup(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read warmup file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// StartWarmup 在后台按页面 id 顺序预加载 path 中记录的页面，并恢复它们的访问历史
+// 预热只使用空闲的 frame，不会驱逐正常流量加载的页面，也不会 pin 住页面
+// 返回的 channel 在预热结束后收到一个结果并关闭，文件不存在时结果为 nil
+func (m *BufferPoolManager) StartWarmup(ctx context.Context, path string) <-chan error {
+	done := make(chan error, 1)
+
+	go func() {
+		defer close(done)
+
+		entries, err := LoadWarmup(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+			done <- err
+			return
+		}
+
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				done <- err
+				return
+			}
+
+			loaded, err := m.preloadPage(entry)
+			if err != nil {
+				done <- err
+				return
+			}
+			if !loaded {
+				break
+			}
+		}
+
+		done <- nil
+	}()
+
+	return done
+}
+
+// preloadPage 把页面加载到一个空闲 frame，没有空闲 frame 时返回 false
+// 读盘时不持有 m.mu，避免阻塞 FetchPage，读完后重新检查 PageTable 和 freeList；
+// 已经不存在的页面直接跳过，其他读取错误返回给调用方
+func (m *BufferPoolManager) preloadPage(entry WarmupEntry) (bool, error) {
+	m.mu.Lock()
+	_, loaded := m.PageTable[entry.PageID]
+	free := len(m.freeList)
+	if !loaded && free > 0 {
+		m.preloading[entry.PageID] = false
+	}
+	m.mu.Unlock()
+
+	// 已经被正常流量加载
+	if loaded {
+		return true, nil
+	}
+	if free == 0 {
+		return false, nil
+	}
+
+	data := make([]byte, DefaultPageSize)
+	readErr := m.DiskManager.ReadPage(entry.PageID, data)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	written := m.preloading[entry.PageID]
+	delete(m.preloading, entry.PageID)
+	if errors.Is(readErr, ErrPageNotAllocated) {
+		return true, nil
+	}
+	if readErr != nil {
+		return false, readErr
+	}
+
+	// 读盘期间页面可能被正常流量加载，空闲 frame 也可能被用完
+	if _, ok := m.PageTable[entry.PageID]; ok {
+		return true, nil
+	}
+	// 读盘期间页面被加载、修改并写回，读到的内容不一定是最新的
+	if written {
+		return true, nil
+	}
+	n := len(m.freeList)
+	if n == 0 {
+		return false, nil
+	}
+	frameID := m.freeList[n-1]
+
+	if err := m.Replacer.restore(frameID, entry.Ages, true); err != nil {
+		return false, err
+	}
+
+	p := &Page{
+		PageID:  entry.PageID,
+		Data:    data,
+		frameID: frameID,
+	}
+	m.freeList = m.freeList[:n-1]
+	m.PageTable[entry.PageID] = p
+	m.frames[frameID] = p
+
+	return true, nil
+}
+
+func writeWarmup(w io.Writer, entries []WarmupEntry) error {
+	header := []any{uint32(warmupMagic), uint16(warmupVersion), uint32(len(entries))}
+	for _, v := range header {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := binary.Write(w, binary.LittleEndian, int64(entry.PageID)); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, uint16(len(entry.Ages))); err != nil {
+			return err
+		}
+		for _, age := range entry.Ages {
+			if err := binary.Write(w, binary.LittleEndian, int64(age)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func readWarmup(r io.Reader) ([]WarmupEntry, error) {
+	var (
+		magic   uint32
+		version uint16
+		count   uint32
+	)
+	for _, v := range []any{&magic, &version, &count} {
+		if err := readWarmupField(r, v); err != nil {
+			return nil, err
+		}
+	}
+	if magic != warmupMagic {
+		return nil, ErrInvalidWarmupFile
+	}
+	if version != warmupVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidWarmupFile, version)
+	}
+
+	// count 和 n 来自文件，不能据此预先分配，文件被截断时它们可能远大于实际的内容
+	var entries []WarmupEntry
+	for i := uint32(0); i < count; i++ {
+		var (
+			pageID int64
+			n      uint16
+		)
+		if err := readWarmupField(r, &pageID); err != nil {
+			return nil, err
+		}
+		if pageID < 0 {
+			return nil, fmt.Errorf("%w: page id %d out of range", ErrInvalidWarmupFile, pageID)
+		}
+		if err := readWarmupField(r, &n); err != nil {
+			return nil, err
+		}
+
+		entry := WarmupEntry{PageID: int(pageID)}
+		for j := uint16(0); j < n; j++ {
+			var age int64
+			if err := readWarmupField(r, &age); err != nil {
+				return nil, err
+			}
+			if age < 0 {
+				return nil, fmt.Errorf("%w: negative access age %d", ErrInvalidWarmupFile, age)
+			}
+			entry.Ages = append(entry.Ages, int(age))
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].PageID < entries[j].PageID
+	})
+
+	return entries, nil
+}
+
+// readWarmupField 读取一个定长字段，文件在字段中间结束时返回 ErrInvalidWarmupFile
+func readWarmupField(r io.Reader, v any) error {
+	err := binary.Read(r, binary.LittleEndian, v)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated", ErrInvalidWarmupFile)
+	}
+
+	return err
+}
@@ -0,0 +1,264 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBufferPool_Warmup(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 2, PageSize, 2)
+		if err := <-bm.StartWarmup(context.Background(), filepath.Join(t.TempDir(), "missing")); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "warmup")
+		if err := os.WriteFile(path, []byte("not a warmup file"), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadWarmup(path); !errors.Is(err, ErrInvalidWarmupFile) {
+			t.Errorf("expected ErrInvalidWarmupFile, got %v", err)
+		}
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeWarmup(&buf, []WarmupEntry{{PageID: 1, Ages: []int{3, 1}}}); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+
+		// 记录数远大于文件中实际的记录
+		huge := slices.Clone(data)
+		binary.LittleEndian.PutUint32(huge[6:], math.MaxUint32)
+		// 页面 id 为负数
+		negative := slices.Clone(data)
+		binary.LittleEndian.PutUint64(negative[10:], math.MaxUint64)
+
+		for name, b := range map[string][]byte{
+			"huge count":    huge,
+			"negative page": negative,
+			"cut in ages":   data[:len(data)-4],
+			"cut in header": data[:5],
+			"empty":         nil,
+		} {
+			if _, err := readWarmup(bytes.NewReader(b)); !errors.Is(err, ErrInvalidWarmupFile) {
+				t.Errorf("%s: expected ErrInvalidWarmupFile, got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("restore across restart", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		path := filepath.Join(t.TempDir(), "warmup")
+
+		bm := NewBufferPoolManager(dm, 3, PageSize, 2)
+		bm.WarmupFile = path
+
+		// 页面 2 访问两次，页面 5 访问一次
+		for _, pageID := range []int{5, 2, 2} {
+			if _, err := bm.FetchPage(pageID); err != nil {
+				t.Fatal(err)
+			}
+			if err := bm.UnpinPage(pageID, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := bm.ShutDown(); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := LoadWarmup(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 || entries[0].PageID != 2 || entries[1].PageID != 5 {
+			t.Fatalf("expected pages 2 and 5 in order, got %v", entries)
+		}
+		if len(entries[0].Ages) != 2 || len(entries[1].Ages) != 1 {
+			t.Fatalf("unexpected history: %v", entries)
+		}
+
+		restarted := NewBufferPoolManager(dm, 3, PageSize, 2)
+		defer restarted.AssertNoPinLeaks(t)
+		if err := <-restarted.StartWarmup(context.Background(), path); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, pageID := range []int{2, 5} {
+			if _, ok := restarted.PageTable[pageID]; !ok {
+				t.Errorf("expected page %d to be preloaded", pageID)
+			}
+		}
+
+		// 预加载的页面命中缓冲池
+		if _, err := restarted.FetchPage(2); err != nil {
+			t.Fatal(err)
+		}
+		if err := restarted.UnpinPage(2, false); err != nil {
+			t.Fatal(err)
+		}
+		if s := restarted.Stats(); s.Hits != 1 || s.Misses != 0 {
+			t.Errorf("expected 1 hit and no miss, got %d and %d", s.Hits, s.Misses)
+		}
+
+		// 页面 5 只有一次访问，距离为 +inf，应先被驱逐
+		if _, err := restarted.FetchPage(0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := restarted.FetchPage(1); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := restarted.PageTable[5]; ok {
+			t.Error("expected page 5 to be evicted first")
+		}
+		for _, pageID := range []int{0, 1} {
+			if err := restarted.UnpinPage(pageID, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("skip unreadable pages", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		path := filepath.Join(t.TempDir(), "warmup")
+
+		bm := NewBufferPoolManager(dm, 3, PageSize, 2)
+		for pageID := 0; pageID < 2; pageID++ {
+			if _, err := bm.FetchPage(pageID); err != nil {
+				t.Fatal(err)
+			}
+			if err := bm.UnpinPage(pageID, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		entries := append(bm.WarmupState(), WarmupEntry{PageID: 1 << 20, Ages: []int{1}})
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeWarmup(f, entries); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		restarted := NewBufferPoolManager(dm, 3, PageSize, 2)
+		if err := <-restarted.StartWarmup(context.Background(), path); err != nil {
+			t.Fatal(err)
+		}
+		if len(restarted.PageTable) != 2 {
+			t.Errorf("expected pages 0 and 1 to be preloaded, got %d pages", len(restarted.PageTable))
+		}
+		if _, ok := restarted.PageTable[1<<20]; ok {
+			t.Error("expected the missing page to be skipped")
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		path := filepath.Join(t.TempDir(), "warmup")
+
+		bm := NewBufferPoolManager(dm, 3, PageSize, 2)
+		if _, err := bm.FetchPage(0); err != nil {
+			t.Fatal(err)
+		}
+		if err := bm.UnpinPage(0, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := bm.SaveWarmup(path); err != nil {
+			t.Fatal(err)
+		}
+
+		// 文件被关闭后读取失败，这不是页面不存在，错误应返回给调用方
+		if err := dm.DBFile.Close(); err != nil {
+			t.Fatal(err)
+		}
+		restarted := NewBufferPoolManager(dm, 3, PageSize, 2)
+		if err := <-restarted.StartWarmup(context.Background(), path); err == nil {
+			t.Error("expected the read error to be reported")
+		}
+		if len(restarted.PageTable) != 0 {
+			t.Errorf("expected no pages to be preloaded, got %d", len(restarted.PageTable))
+		}
+	})
+
+	t.Run("write back during read", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		bm := NewBufferPoolManager(dm, 3, PageSize, 2)
+		defer bm.AssertNoPinLeaks(t)
+
+		// 模拟预热正在读取页面 0 和 1 时，页面 0 被加载、修改并写回
+		bm.preloading[0], bm.preloading[1] = false, false
+		p, err := bm.FetchPage(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Data[0] = 1
+		if err := bm.UnpinPage(0, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := bm.FlushAllPages(); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bm.preloading[0] || bm.preloading[1] {
+			t.Errorf("expected only page 0 to be marked as written, got %v", bm.preloading)
+		}
+	})
+
+	t.Run("only free frames", func(t *testing.T) {
+		dm := setupDiskManager(t)
+		defer cleanupDiskManager(dm)
+
+		path := filepath.Join(t.TempDir(), "warmup")
+
+		bm := NewBufferPoolManager(dm, 3, PageSize, 2)
+		for pageID := 0; pageID < 3; pageID++ {
+			if _, err := bm.FetchPage(pageID); err != nil {
+				t.Fatal(err)
+			}
+			if err := bm.UnpinPage(pageID, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := bm.SaveWarmup(path); err != nil {
+			t.Fatal(err)
+		}
+
+		restarted := NewBufferPoolManager(dm, 1, PageSize, 2)
+		if _, err := restarted.FetchPage(7); err != nil {
+			t.Fatal(err)
+		}
+		defer restarted.UnpinPage(7, false)
+
+		if err := <-restarted.StartWarmup(context.Background(), path); err != nil {
+			t.Fatal(err)
+		}
+		if len(restarted.PageTable) != 1 {
+			t.Errorf("expected warmup not to evict, got %d pages", len(restarted.PageTable))
+		}
+	})
+}